@@ -12,18 +12,21 @@ import (
 
 var DB *gorm.DB
 
-func InitDB() {
+// DSN 環境変数からデータベースの接続文字列を組み立てます（LISTEN用の専用接続でも使う）
+func DSN() string {
 	host := os.Getenv("DB_HOST")
 	port := os.Getenv("DB_PORT")
 	user := os.Getenv("DB_USER")
 	password := os.Getenv("DB_PASSWORD")
 	dbname := os.Getenv("DB_NAME")
 
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Tokyo",
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=Asia/Tokyo",
 		host, port, user, password, dbname)
+}
 
+func InitDB() {
 	var err error
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	DB, err = gorm.Open(postgres.Open(DSN()), &gorm.Config{})
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -37,10 +40,91 @@ func InitDB() {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
-	// マイグレーション実行
-	if err := DB.AutoMigrate(&models.User{}, &models.Todo{}, &models.RefreshToken{}); err != nil {
+	if err := Migrate(); err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
 
 	log.Println("Database connection established successfully")
 }
+
+// Migrate DBのテーブル・インデックス・トリガーを作成・更新します（テストのDBでも使う）
+func Migrate() error {
+	// todoの変更番号のシーケンスはカラムのデフォルト値で使うため、テーブルより先に作成する
+	if err := DB.Exec(`CREATE SEQUENCE IF NOT EXISTS todo_change_seq`).Error; err != nil {
+		return fmt.Errorf("change sequence: %w", err)
+	}
+
+	// マイグレーション実行
+	if err := DB.AutoMigrate(&models.User{}, &models.Project{}, &models.Label{}, &models.Todo{}, &models.TodoRevision{}, &models.Share{}, &models.Comment{}, &models.CommentRevision{}, &models.Attachment{}, &models.ImportJob{}, &models.ImportIssue{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.TodoEvent{}, &models.TodoTombstone{}, &models.RefreshToken{}); err != nil {
+		return err
+	}
+
+	if err := migrateSearch(); err != nil {
+		return fmt.Errorf("search index: %w", err)
+	}
+
+	if err := migrateSync(); err != nil {
+		return fmt.Errorf("sync triggers: %w", err)
+	}
+
+	// 以前のバージョンが保存していたwebhookの応答の本文（内部のサービスの応答を含みうる）を削除する
+	if err := DB.Exec(`ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body`).Error; err != nil {
+		return fmt.Errorf("webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+// migrateSearch 全文検索用のtsvectorカラムとインデックスを作成
+// search_vectorはtitleから自動生成されるため、アプリケーションから書き込む必要はありません
+func migrateSearch() error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE todos ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (to_tsvector('english', coalesce(title, ''))) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_todos_search_vector ON todos USING GIN (search_vector)`,
+		// 日本語など語幹処理が効かないテキスト向けのトライグラムインデックス
+		`CREATE INDEX IF NOT EXISTS idx_todos_title_trgm ON todos USING GIN (title gin_trgm_ops)`,
+	}
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateSync 差分同期用に、todoの変更番号（change_seq）と変更したトランザクションのID（change_xid）を記録するトリガーを作成
+// どの経路で更新されても採番し直されるよう、アプリケーションではなくトリガーで設定します
+// change_xidは、変更番号が小さいのに後からコミットされた変更を同期で取りこぼさないために使います（todo_eventsのイベントIDも同様）
+func migrateSync() error {
+	statements := []string{
+		`ALTER TABLE todos ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id()`,
+		`CREATE INDEX IF NOT EXISTS idx_todos_change_xid ON todos (change_xid)`,
+		`ALTER TABLE todo_tombstones ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id()`,
+		// リアルタイム配信の再送でも、後からコミットされたイベントを取りこぼさないよう記録したトランザクションを残す
+		`ALTER TABLE todo_events ADD COLUMN IF NOT EXISTS change_xid xid8 NOT NULL DEFAULT pg_current_xact_id()`,
+		`CREATE INDEX IF NOT EXISTS idx_todo_events_change_xid ON todo_events (change_xid)`,
+		`CREATE OR REPLACE FUNCTION set_todo_change_seq() RETURNS trigger AS $$
+		BEGIN
+			NEW.change_seq := nextval('todo_change_seq');
+			NEW.change_xid := pg_current_xact_id();
+			RETURN NEW;
+		END
+		$$ LANGUAGE plpgsql`,
+		`CREATE OR REPLACE TRIGGER todos_change_seq BEFORE INSERT OR UPDATE ON todos
+			FOR EACH ROW EXECUTE FUNCTION set_todo_change_seq()`,
+		// client_idの重複防止は持ち主ではなく作成したユーザーごとに行う（以前のインデックスは持ち主ごと）
+		`DROP INDEX IF EXISTS idx_todos_user_client_id`,
+		`UPDATE todos SET client_user_id = COALESCE(
+			(SELECT actor_id FROM todo_revisions WHERE todo_id = todos.id AND action = 'create' ORDER BY id LIMIT 1),
+			user_id)
+			WHERE client_id IS NOT NULL AND client_user_id IS NULL`,
+	}
+	for _, stmt := range statements {
+		if err := DB.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
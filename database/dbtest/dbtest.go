@@ -0,0 +1,99 @@
+// Package dbtest DBを使うテストのためのヘルパー
+//
+// TEST_DATABASE_DSN（例: "host=localhost port=5432 user=postgres password=postgres dbname=todo_test sslmode=disable"）に
+// テスト用のPostgresを指定すると実行します。指定がなければテストはスキップされます
+package dbtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"sync"
+	"testing"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/models"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// migrateLockKey パッケージごとのテストが並行してマイグレーションしないよう取るアドバイザリロックのキー
+const migrateLockKey = 7301002
+
+var (
+	openOnce sync.Once
+	testDB   *gorm.DB
+	openErr  error
+)
+
+// open テスト用のDBに接続し、最初の1回だけマイグレーションします
+func open(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		return nil, err
+	}
+	// database.Migrateはdatabase.DBを使うため、ロックを取った接続に差し替えて実行する
+	err = db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrateLockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrateLockKey)
+
+		prev := database.DB
+		database.DB = conn
+		defer func() { database.DB = prev }()
+		return database.Migrate()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Open テストごとのトランザクションを開始してdatabase.DBに設定し、返します
+// テストが終わるとロールバックしてdatabase.DBを元に戻すため、テストで作成したデータは残りません
+// database.DBを差し替えるので、Openを使うテストはt.Parallelにしないでください
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	openOnce.Do(func() {
+		testDB, openErr = open(dsn)
+	})
+	if openErr != nil {
+		t.Fatalf("Failed to open test database: %v", openErr)
+	}
+
+	tx := testDB.Begin()
+	if tx.Error != nil {
+		t.Fatalf("Failed to begin transaction: %v", tx.Error)
+	}
+	prev := database.DB
+	database.DB = tx
+	t.Cleanup(func() {
+		database.DB = prev
+		tx.Rollback()
+	})
+	return tx
+}
+
+// Create レコードを作成します（失敗したらテストを終了する）
+func Create(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("Failed to create %T: %v", value, err)
+	}
+}
+
+// CreateUser 重複しないメールアドレスのユーザーを作成します
+func CreateUser(t *testing.T, db *gorm.DB) models.User {
+	t.Helper()
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	user := models.User{Email: "test-" + hex.EncodeToString(suffix) + "@example.com", PasswordHash: "x"}
+	Create(t, db, &user)
+	return user
+}
@@ -0,0 +1,54 @@
+package grpcserver
+
+import (
+	"log"
+	"net"
+	"os"
+
+	"go-gin-todo-api/handlers"
+	"go-gin-todo-api/middleware"
+	todov1 "go-gin-todo-api/proto/todo/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// NewServer AuthServiceとTodoServiceを登録したgRPCサーバーを作成します
+// AuthServiceのRegister・Login・Refresh・Logout以外はJWTトークンの検証が必要です
+func NewServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			statusUnaryInterceptor,
+			middleware.AuthUnaryInterceptor(handlers.GRPCPublicMethods),
+		),
+		grpc.ChainStreamInterceptor(
+			statusStreamInterceptor,
+			middleware.AuthStreamInterceptor(handlers.GRPCPublicMethods),
+		),
+	)
+	todov1.RegisterAuthServiceServer(server, handlers.NewAuthServiceServer())
+	todov1.RegisterTodoServiceServer(server, handlers.NewTodoServiceServer())
+	// grpcurlなどでサービスの一覧を取得できるようにする
+	reflection.Register(server)
+	return server
+}
+
+// Start GRPC_PORT（デフォルト9090）でgRPCサーバーを起動します
+func Start() {
+	port := os.Getenv("GRPC_PORT")
+	if port == "" {
+		port = "9090"
+	}
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		log.Fatalf("Failed to listen for gRPC: %v", err)
+	}
+
+	server := NewServer()
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			log.Printf("gRPC server stopped: %v", err)
+		}
+	}()
+
+	log.Printf("gRPC server started (port: %s)", port)
+}
@@ -0,0 +1,71 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go-gin-todo-api/utils"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain ErrorInfoのdomain
+const errorDomain = "go-gin-todo-api"
+
+// httpStatusCodes RESTのステータスコードに対応するgRPCのステータスコード
+var httpStatusCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.AlreadyExists,
+	http.StatusGone:                  codes.FailedPrecondition,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// toStatusError ハンドラーのエラーをgRPCのステータスに変換します
+// APIErrorはそのステータスコードから、DBのエラーはutils.HandleDBErrorの結果から変換し、
+// RESTのエラーコード（not_foundなど）をErrorInfoのreasonに入れます
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	apiErr := utils.ToAPIError(err)
+	code, ok := httpStatusCodes[apiErr.Status]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, apiErr.Message)
+	info := &errdetails.ErrorInfo{Reason: string(apiErr.Code), Domain: errorDomain}
+	if withDetails, err := st.WithDetails(info); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// statusUnaryInterceptor unaryのハンドラーが返したエラーをgRPCのステータスに変換します
+func statusUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	resp, err := handler(ctx, req)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+	return resp, nil
+}
+
+// statusStreamInterceptor streamのハンドラーが返したエラーをgRPCのステータスに変換します
+func statusStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return toStatusError(handler(srv, ss))
+}
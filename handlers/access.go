@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// requiredRoleMessage 権限が足りない場合のエラーメッセージ
+func requiredRoleMessage(required services.Role) string {
+	return fmt.Sprintf("%s role is required", required)
+}
+
+// checkTodoRole ユーザーがtodoに対してrequired以上の権限を持つか確認します
+// 閲覧もできない場合はtodoの存在を明かさないよう404を返します
+func checkTodoRole(tx *gorm.DB, userID uint, todo *models.Todo, required services.Role) error {
+	role, err := services.TodoRole(tx, userID, todo)
+	if err != nil {
+		return err
+	}
+	if role == services.RoleNone {
+		return utils.NewNotFoundError("Todo not found")
+	}
+	if !role.Allows(required) {
+		return utils.NewForbiddenError(requiredRoleMessage(required))
+	}
+	return nil
+}
+
+// authorizeTodo todoを取得し、required以上の権限があるか確認します
+func authorizeTodo(tx *gorm.DB, userID uint, id uint, required services.Role) (*models.Todo, error) {
+	var todo models.Todo
+	if err := tx.First(&todo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Todo not found")
+		}
+		return nil, err
+	}
+	if err := checkTodoRole(tx, userID, &todo, required); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// authorizeProject プロジェクトを取得し、required以上の権限があるか確認します
+func authorizeProject(tx *gorm.DB, userID uint, id uint, required services.Role) (*models.Project, error) {
+	var project models.Project
+	if err := tx.First(&project, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Project not found")
+		}
+		return nil, err
+	}
+
+	role, err := services.ProjectRole(tx, userID, &project)
+	if err != nil {
+		return nil, err
+	}
+	if role == services.RoleNone {
+		return nil, utils.NewNotFoundError("Project not found")
+	}
+	if !role.Allows(required) {
+		return nil, utils.NewForbiddenError(requiredRoleMessage(required))
+	}
+	return &project, nil
+}
+
+// findAuthorizedTodo URLのtodoを取得してrequired以上の権限があるか確認し、なければエラーレスポンスを返します
+func findAuthorizedTodo(c *gin.Context, userID uint, required services.Role) (*models.Todo, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid todo ID")
+		return nil, false
+	}
+
+	todo, err := authorizeTodo(database.DB, userID, uint(id), required)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return nil, false
+	}
+	return todo, true
+}
+
+// findAuthorizedProject URLのプロジェクトを取得してrequired以上の権限があるか確認し、なければエラーレスポンスを返します
+func findAuthorizedProject(c *gin.Context, userID uint, required services.Role) (*models.Project, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid project ID")
+		return nil, false
+	}
+
+	project, err := authorizeProject(database.DB, userID, uint(id), required)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return nil, false
+	}
+	return project, true
+}
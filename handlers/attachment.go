@@ -0,0 +1,324 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/storage"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sniffLength Content-Typeの判定に使う先頭のバイト数
+const sniffLength = 512
+
+// maxFilenameLength 保存するファイル名の最大文字数
+const maxFilenameLength = 255
+
+// inlineContentTypes ブラウザでそのまま表示してよいContent-Type（それ以外はダウンロードさせる）
+var inlineContentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// envMegabytes 環境変数をMB単位のサイズとして読み込みます
+func envMegabytes(name string, defaultMB int64) int64 {
+	mb := defaultMB
+	if mbStr := os.Getenv(name); mbStr != "" {
+		if parsed, err := strconv.ParseInt(mbStr, 10, 64); err == nil && parsed > 0 {
+			mb = parsed
+		}
+	}
+	return mb << 20
+}
+
+// attachmentMaxSize 1ファイルの最大サイズ
+func attachmentMaxSize() int64 {
+	return envMegabytes("ATTACHMENT_MAX_SIZE_MB", 25)
+}
+
+// attachmentQuota ユーザーごとの添付ファイルの合計サイズの上限
+func attachmentQuota() int64 {
+	return envMegabytes("ATTACHMENT_QUOTA_MB", 500)
+}
+
+// attachmentUsage ユーザーがアップロードした添付ファイルの合計サイズを返します
+func attachmentUsage(tx *gorm.DB, userID uint) (int64, error) {
+	var usage int64
+	err := tx.Model(&models.Attachment{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&usage).Error
+	return usage, err
+}
+
+// checkAttachmentQuota sizeバイトを追加しても容量の上限を超えないか確認します
+func checkAttachmentQuota(tx *gorm.DB, userID uint, size int64) error {
+	usage, err := attachmentUsage(tx, userID)
+	if err != nil {
+		return err
+	}
+	if usage+size > attachmentQuota() {
+		return utils.NewQuotaExceededError(fmt.Sprintf("Attachment quota exceeded (%d of %d bytes used)", usage, attachmentQuota()))
+	}
+	return nil
+}
+
+// sanitizeFilename アップロードされたファイル名からパスと制御文字を取り除きます
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength])
+	}
+	return name
+}
+
+// newStorageKey 添付ファイルを保存するキーを作成します
+func newStorageKey(todoID uint) (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("todos/%d/%s", todoID, hex.EncodeToString(bytes)), nil
+}
+
+// findAttachment URLの添付ファイルを取得します
+func findAttachment(c *gin.Context, todoID uint) (*models.Attachment, bool) {
+	id, err := strconv.ParseUint(c.Param("attachment_id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid attachment ID")
+		return nil, false
+	}
+
+	var attachment models.Attachment
+	if err := database.DB.Where("id = ? AND todo_id = ?", id, todoID).First(&attachment).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Attachment not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	return &attachment, true
+}
+
+// GetAttachments todoの添付ファイル一覧を取得（閲覧権限があれば参照可）
+func GetAttachments(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := database.DB.Where("todo_id = ?", todo.ID).Order("id ASC").Find(&attachments).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// UploadAttachment todoにファイルを添付（multipart/form-dataのfileフィールド、編集権限が必要）
+func UploadAttachment(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleEditor)
+	if !ok {
+		return
+	}
+
+	// multipartのヘッダー分の余裕を持たせてリクエスト全体のサイズを制限する
+	maxSize := attachmentMaxSize()
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			utils.RespondAPIError(c, utils.NewPayloadTooLargeError(fmt.Sprintf("File must not exceed %d bytes", maxSize)))
+		} else {
+			utils.RespondBadRequest(c, "file is required")
+		}
+		return
+	}
+	if header.Size > maxSize {
+		utils.RespondAPIError(c, utils.NewPayloadTooLargeError(fmt.Sprintf("File must not exceed %d bytes", maxSize)))
+		return
+	}
+
+	// アップロード前に容量を確認し、超える場合はファイルを保存しない
+	if err := checkAttachmentQuota(database.DB, userID.(uint), header.Size); err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		utils.RespondBadRequest(c, "Failed to read file")
+		return
+	}
+	defer file.Close()
+
+	// 申告されたContent-Typeは信用せず、ファイルの先頭から判定する
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		utils.RespondBadRequest(c, "Failed to read file")
+		return
+	}
+	contentType := http.DetectContentType(head[:n])
+
+	key, err := newStorageKey(todo.ID)
+	if err != nil {
+		utils.RespondInternalError(c, "Failed to store file")
+		return
+	}
+
+	hash := sha256.New()
+	body := io.TeeReader(io.MultiReader(bytes.NewReader(head[:n]), file), hash)
+	if err := storage.Files.Put(c.Request.Context(), key, body, header.Size, contentType); err != nil {
+		utils.RespondInternalError(c, "Failed to store file")
+		return
+	}
+
+	attachment := models.Attachment{
+		TodoID:      todo.ID,
+		UserID:      userID.(uint),
+		Filename:    sanitizeFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		Checksum:    hex.EncodeToString(hash.Sum(nil)),
+		StorageKey:  key,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 同じユーザーの同時アップロードで上限を超えないよう、ユーザー単位でロックして確認し直す
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.User{}, userID).Error; err != nil {
+			return err
+		}
+		if err := checkAttachmentQuota(tx, userID.(uint), attachment.Size); err != nil {
+			return err
+		}
+		return tx.Create(&attachment).Error
+	})
+	if err != nil {
+		storage.DeleteObjects(c.Request.Context(), []string{key})
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// DownloadAttachment 添付ファイルをダウンロード（Rangeリクエスト・条件付きGETに対応）
+func DownloadAttachment(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	attachment, ok := findAttachment(c, todo.ID)
+	if !ok {
+		return
+	}
+
+	object, err := storage.Files.Open(c.Request.Context(), attachment.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.RespondNotFound(c, "Attachment file not found")
+		} else {
+			utils.RespondInternalError(c, "Failed to read file")
+		}
+		return
+	}
+	defer object.Close()
+
+	disposition := "attachment"
+	if inlineContentTypes[attachment.ContentType] {
+		disposition = "inline"
+	}
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("ETag", "\""+attachment.Checksum+"\"")
+
+	// Range / If-Range / If-None-Matchの処理はhttp.ServeContentに任せる
+	http.ServeContent(c.Writer, c.Request, attachment.Filename, attachment.CreatedAt, object)
+}
+
+// DeleteAttachment 添付ファイルを削除（編集権限が必要）
+func DeleteAttachment(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleEditor)
+	if !ok {
+		return
+	}
+
+	attachment, ok := findAttachment(c, todo.ID)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(attachment).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	storage.DeleteObjects(c.Request.Context(), []string{attachment.StorageKey})
+
+	c.Status(http.StatusNoContent)
+}
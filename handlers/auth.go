@@ -6,9 +6,11 @@ import (
 
 	"go-gin-todo-api/database"
 	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
 	"go-gin-todo-api/utils"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // RegisterRequest ユーザー登録リクエスト
@@ -28,19 +30,33 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
-// Register ユーザー登録ハンドラー
-func Register(c *gin.Context) {
-	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.RespondBadRequest(c, err.Error())
-		return
-	}
+// RegisterResponse ユーザー登録のレスポンス
+type RegisterResponse struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
 
+// TokenResponse ログイン・トークン更新のレスポンス
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+}
+
+// LoginEvent user.loginのwebhookで送る内容
+type LoginEvent struct {
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+// registerUser ユーザーを作成します（REST・gRPCで共通）
+func registerUser(req RegisterRequest) (*models.User, error) {
 	// パスワードをハッシュ化
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
-		utils.RespondInternalError(c, "Failed to hash password")
-		return
+		return nil, utils.NewInternalError("Failed to hash password")
 	}
 
 	// ユーザーを作成
@@ -51,138 +67,174 @@ func Register(c *gin.Context) {
 
 	if err := database.DB.Create(&user).Error; err != nil {
 		statusCode, message := utils.HandleDBError(err)
-		if statusCode == 409 {
-			utils.RespondConflict(c, "Email already exists")
-		} else {
-			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		if statusCode == http.StatusConflict {
+			return nil, utils.NewConflictError("Email already exists")
 		}
-		return
+		return nil, &utils.APIError{Status: statusCode, Code: utils.ErrorCodeInternal, Message: message}
 	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"id":    user.ID,
-		"email": user.Email,
-	})
+	return &user, nil
 }
 
-// Login ログインハンドラー
-func Login(c *gin.Context) {
-	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.RespondBadRequest(c, err.Error())
-		return
+// newTokenPair アクセストークンと新しいリフレッシュトークンを生成します
+// リフレッシュトークンはハッシュ化したものを返すので、呼び出し側でDBに保存してください
+func newTokenPair(userID uint) (*TokenResponse, *models.RefreshToken, error) {
+	// アクセストークンを生成
+	accessToken, err := utils.GenerateAccessToken(userID)
+	if err != nil {
+		return nil, nil, utils.NewInternalError("Failed to generate access token")
+	}
+
+	// リフレッシュトークンを生成
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, nil, utils.NewInternalError("Failed to generate refresh token")
 	}
 
+	tokens := &TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+	}
+	model := &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: utils.HashRefreshToken(refreshToken),
+		ExpiresAt: time.Now().Add(utils.GetRefreshTokenTTL()),
+	}
+	return tokens, model, nil
+}
+
+// loginUser メールアドレスとパスワードを検証し、トークンを発行します（REST・gRPCで共通）
+// ipとuserAgentはuser.loginのwebhookで通知します
+func loginUser(req LoginRequest, ip, userAgent string) (*TokenResponse, error) {
 	// ユーザーを検索
 	var user models.User
 	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		utils.RespondUnauthorized(c, "Invalid email or password")
-		return
+		return nil, utils.NewUnauthorizedError("Invalid email or password")
 	}
 
 	// パスワードを検証
 	if !utils.ComparePassword(user.PasswordHash, req.Password) {
-		utils.RespondUnauthorized(c, "Invalid email or password")
-		return
+		return nil, utils.NewUnauthorizedError("Invalid email or password")
 	}
 
-	// アクセストークンを生成
-	accessToken, err := utils.GenerateAccessToken(user.ID)
+	tokens, refreshTokenModel, err := newTokenPair(user.ID)
 	if err != nil {
-		utils.RespondInternalError(c, "Failed to generate access token")
-		return
+		return nil, err
 	}
 
-	// リフレッシュトークンを生成
-	refreshToken, err := utils.GenerateRefreshToken()
+	// ログインの通知はリフレッシュトークンの保存と同じトランザクションでキューに追加する
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(refreshTokenModel).Error; err != nil {
+			return err
+		}
+		return services.EnqueueWebhookEvent(tx, user.ID, models.WebhookEventUserLogin, LoginEvent{
+			UserID:    user.ID,
+			Email:     user.Email,
+			IP:        ip,
+			UserAgent: userAgent,
+		})
+	})
 	if err != nil {
-		utils.RespondInternalError(c, "Failed to generate refresh token")
-		return
+		statusCode, message := utils.HandleDBError(err)
+		return nil, &utils.APIError{Status: statusCode, Code: utils.ErrorCodeInternal, Message: message}
 	}
+	return tokens, nil
+}
 
-	// リフレッシュトークンをハッシュ化してDBに保存
-	tokenHash := utils.HashRefreshToken(refreshToken)
-	expiresAt := time.Now().Add(utils.GetRefreshTokenTTL())
+// refreshTokens リフレッシュトークンを検証し、ローテーションした新しいトークンを発行します（REST・gRPCで共通）
+func refreshTokens(token string) (*TokenResponse, error) {
+	// DBでトークンを検索
+	var refreshToken models.RefreshToken
+	if err := database.DB.Where("token_hash = ?", utils.HashRefreshToken(token)).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		First(&refreshToken).Error; err != nil {
+		return nil, utils.NewUnauthorizedError("Invalid or expired refresh token")
+	}
 
-	refreshTokenModel := models.RefreshToken{
-		UserID:    user.ID,
-		TokenHash: tokenHash,
-		ExpiresAt: expiresAt,
+	// ローテーション: 新しいリフレッシュトークンを生成
+	tokens, newRefreshTokenModel, err := newTokenPair(refreshToken.UserID)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := database.DB.Create(&refreshTokenModel).Error; err != nil {
+	// 古いトークンをrevoke
+	database.DB.Model(&refreshToken).Update("revoked_at", time.Now())
+
+	// 新しいリフレッシュトークンをDBに保存
+	if err := database.DB.Create(newRefreshTokenModel).Error; err != nil {
 		statusCode, message := utils.HandleDBError(err)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
-		return
+		return nil, &utils.APIError{Status: statusCode, Code: utils.ErrorCodeInternal, Message: message}
 	}
+	return tokens, nil
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": refreshToken,
-		"token_type":    "Bearer",
-	})
+// revokeRefreshToken リフレッシュトークンを無効にします（REST・gRPCで共通）
+func revokeRefreshToken(token string) error {
+	result := database.DB.Model(&models.RefreshToken{}).
+		Where("token_hash = ?", utils.HashRefreshToken(token)).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+
+	if result.Error != nil {
+		statusCode, message := utils.HandleDBError(result.Error)
+		return &utils.APIError{Status: statusCode, Code: utils.ErrorCodeInternal, Message: message}
+	}
+	if result.RowsAffected == 0 {
+		return utils.NewNotFoundError("Token not found or already revoked")
+	}
+	return nil
 }
 
-// Refresh リフレッシュトークンハンドラー
-func Refresh(c *gin.Context) {
-	var req RefreshRequest
+// Register ユーザー登録ハンドラー
+func Register(c *gin.Context) {
+	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.RespondBadRequest(c, err.Error())
 		return
 	}
 
-	// リフレッシュトークンをハッシュ化
-	tokenHash := utils.HashRefreshToken(req.RefreshToken)
-
-	// DBでトークンを検索
-	var refreshToken models.RefreshToken
-	if err := database.DB.Where("token_hash = ?", tokenHash).
-		Where("revoked_at IS NULL").
-		Where("expires_at > ?", time.Now()).
-		First(&refreshToken).Error; err != nil {
-		utils.RespondUnauthorized(c, "Invalid or expired refresh token")
+	user, err := registerUser(req)
+	if err != nil {
+		utils.RespondAPIError(c, err)
 		return
 	}
 
-	// 新しいアクセストークンを生成
-	accessToken, err := utils.GenerateAccessToken(refreshToken.UserID)
-	if err != nil {
-		utils.RespondInternalError(c, "Failed to generate access token")
+	c.JSON(http.StatusCreated, RegisterResponse{ID: user.ID, Email: user.Email})
+}
+
+// Login ログインハンドラー
+func Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
 		return
 	}
 
-	// ローテーション: 新しいリフレッシュトークンを生成
-	newRefreshToken, err := utils.GenerateRefreshToken()
+	tokens, err := loginUser(req, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
-		utils.RespondInternalError(c, "Failed to generate refresh token")
+		utils.RespondAPIError(c, err)
 		return
 	}
 
-	// 古いトークンをrevoke
-	now := time.Now()
-	database.DB.Model(&refreshToken).Update("revoked_at", now)
-
-	// 新しいリフレッシュトークンをDBに保存
-	newTokenHash := utils.HashRefreshToken(newRefreshToken)
-	newExpiresAt := time.Now().Add(utils.GetRefreshTokenTTL())
+	c.JSON(http.StatusOK, tokens)
+}
 
-	newRefreshTokenModel := models.RefreshToken{
-		UserID:    refreshToken.UserID,
-		TokenHash: newTokenHash,
-		ExpiresAt: newExpiresAt,
+// Refresh リフレッシュトークンハンドラー
+func Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
 	}
 
-	if err := database.DB.Create(&newRefreshTokenModel).Error; err != nil {
-		statusCode, message := utils.HandleDBError(err)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+	tokens, err := refreshTokens(req.RefreshToken)
+	if err != nil {
+		utils.RespondAPIError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token":  accessToken,
-		"refresh_token": newRefreshToken,
-		"token_type":    "Bearer",
-	})
+	c.JSON(http.StatusOK, tokens)
 }
 
 // Logout ログアウトハンドラー
@@ -193,23 +245,8 @@ func Logout(c *gin.Context) {
 		return
 	}
 
-	// リフレッシュトークンをハッシュ化
-	tokenHash := utils.HashRefreshToken(req.RefreshToken)
-
-	// トークンをrevoke
-	result := database.DB.Model(&models.RefreshToken{}).
-		Where("token_hash = ?", tokenHash).
-		Where("revoked_at IS NULL").
-		Update("revoked_at", time.Now())
-
-	if result.Error != nil {
-		statusCode, message := utils.HandleDBError(result.Error)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
-		return
-	}
-
-	if result.RowsAffected == 0 {
-		utils.RespondNotFound(c, "Token not found or already revoked")
+	if err := revokeRefreshToken(req.RefreshToken); err != nil {
+		utils.RespondAPIError(c, err)
 		return
 	}
 
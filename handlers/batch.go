@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// maxBatchOperations 1回のバッチで実行できる操作の上限
+const maxBatchOperations = 100
+
+// バッチの実行モード
+const (
+	batchModeAllOrNothing = "all_or_nothing"
+	batchModeBestEffort   = "best_effort"
+)
+
+// 各操作の結果
+const (
+	batchStatusSucceeded  = "succeeded"
+	batchStatusFailed     = "failed"
+	batchStatusRolledBack = "rolled_back" // all_or_nothingで他の操作が失敗したため取り消された
+	batchStatusSkipped    = "skipped"     // all_or_nothingで前の操作が失敗したため実行されなかった
+)
+
+// BatchOperation バッチ内の1操作
+// opはcreate / update / delete。dataにはPOST /todos・PATCH /todos/:idと同じ内容を指定します
+// if_matchはIf-Matchヘッダーと同じく、todoのETagが一致する場合だけ更新・削除します
+type BatchOperation struct {
+	Op       string          `json:"op" binding:"required,oneof=create update delete"`
+	ID       uint            `json:"id"`
+	Children string          `json:"children"`
+	IfMatch  string          `json:"if_match"`
+	ClientID string          `json:"client_id"`
+	Data     json.RawMessage `json:"data"`
+}
+
+// BatchRequest バッチ操作リクエスト
+type BatchRequest struct {
+	Mode       string           `json:"mode" binding:"omitempty,oneof=all_or_nothing best_effort"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,dive"`
+}
+
+// BatchOperationResult 操作ごとの結果
+type BatchOperationResult struct {
+	Index    int                `json:"index"`
+	Op       string             `json:"op"`
+	ClientID string             `json:"client_id,omitempty"`
+	Status   string             `json:"status"`
+	Todo     *models.Todo       `json:"todo,omitempty"`
+	Error    *utils.ErrorDetail `json:"error,omitempty"`
+}
+
+// BatchResponse バッチ操作レスポンス
+type BatchResponse struct {
+	Mode      string                 `json:"mode"`
+	Committed bool                   `json:"committed"`
+	Results   []BatchOperationResult `json:"results"`
+}
+
+// errBatchAborted all_or_nothingで操作が失敗し、バッチ全体を取り消すことを示します
+var errBatchAborted = fmt.Errorf("batch aborted")
+
+// decodeBatchData 操作のdataをリクエスト構造体に読み込み、通常のエンドポイントと同じ検証を行います
+func decodeBatchData(data json.RawMessage, req interface{}) error {
+	if len(data) == 0 {
+		return utils.NewBadRequestError("data is required")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(req); err != nil {
+		return utils.NewBadRequestError(err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return utils.NewBadRequestError(err.Error())
+	}
+	return nil
+}
+
+// runBatchOperation 1つの操作を実行します
+func runBatchOperation(tx *gorm.DB, userID uint, op BatchOperation) (*models.Todo, error) {
+	switch op.Op {
+	case "create":
+		var req CreateTodoRequest
+		if err := decodeBatchData(op.Data, &req); err != nil {
+			return nil, err
+		}
+		return createTodo(tx, userID, req)
+	case "update":
+		if op.ID == 0 {
+			return nil, utils.NewBadRequestError("id is required")
+		}
+		var req UpdateTodoRequest
+		if err := decodeBatchData(op.Data, &req); err != nil {
+			return nil, err
+		}
+		return updateTodo(tx, userID, op.ID, req, op.IfMatch)
+	case "delete":
+		if op.ID == 0 {
+			return nil, utils.NewBadRequestError("id is required")
+		}
+		children := op.Children
+		if children == "" {
+			children = "cascade"
+		}
+		return nil, deleteTodo(tx, userID, op.ID, children, op.IfMatch)
+	default:
+		return nil, utils.NewBadRequestError(fmt.Sprintf("Invalid op: %s", op.Op))
+	}
+}
+
+// BatchTodos 複数のtodoの作成・更新・削除を1つのトランザクションで実行
+// mode=all_or_nothing（デフォルト）は1つでも失敗するとすべて取り消し、best_effortは失敗した操作だけを取り消します
+func BatchTodos(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = batchModeAllOrNothing
+	}
+	if len(req.Operations) > maxBatchOperations {
+		utils.RespondBadRequest(c, fmt.Sprintf("operations must not exceed %d", maxBatchOperations))
+		return
+	}
+
+	results := make([]BatchOperationResult, len(req.Operations))
+	for i, op := range req.Operations {
+		results[i] = BatchOperationResult{Index: i, Op: op.Op, ClientID: op.ClientID, Status: batchStatusSkipped}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, op := range req.Operations {
+			// 各操作はセーブポイント内で実行し、失敗した操作の変更だけを巻き戻せるようにする
+			var todo *models.Todo
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				var err error
+				todo, err = runBatchOperation(sp, userID.(uint), op)
+				return err
+			})
+			if err != nil {
+				detail := utils.ToAPIError(err).Detail()
+				results[i].Status = batchStatusFailed
+				results[i].Error = &detail
+				if req.Mode == batchModeAllOrNothing {
+					return errBatchAborted
+				}
+				continue
+			}
+			results[i].Status = batchStatusSucceeded
+			results[i].Todo = todo
+		}
+		return nil
+	})
+	if err != nil && err != errBatchAborted {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	committed := err == nil
+	if !committed {
+		for i := range results {
+			if results[i].Status == batchStatusSucceeded {
+				results[i].Status = batchStatusRolledBack
+				results[i].Todo = nil
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, BatchResponse{
+		Mode:      req.Mode,
+		Committed: committed,
+		Results:   results,
+	})
+}
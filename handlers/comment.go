@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// CommentRequest コメント作成・編集リクエスト（本文は最大10000文字）
+type CommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+// CommentResponse コメントレスポンス
+type CommentResponse struct {
+	ID        uint          `json:"id"`
+	TodoID    uint          `json:"todo_id"`
+	Body      string        `json:"body"`
+	Author    UserSummary   `json:"author"`
+	Mentions  []UserSummary `json:"mentions"`
+	EditedAt  *time.Time    `json:"edited_at"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// CommentListResponse コメント一覧レスポンス
+type CommentListResponse struct {
+	Items      []CommentResponse `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+}
+
+// commentSort コメント一覧の並び順（古い順）
+const commentSort = "id"
+
+// newCommentResponse コメントをレスポンスに変換します（UserとMentionsをPreloadしておく）
+func newCommentResponse(comment *models.Comment) CommentResponse {
+	resp := CommentResponse{
+		ID:        comment.ID,
+		TodoID:    comment.TodoID,
+		Body:      comment.Body,
+		Author:    UserSummary{ID: comment.UserID},
+		Mentions:  make([]UserSummary, len(comment.Mentions)),
+		EditedAt:  comment.EditedAt,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+	if comment.User != nil {
+		resp.Author.Email = comment.User.Email
+	}
+	for i, user := range comment.Mentions {
+		resp.Mentions[i] = UserSummary{ID: user.ID, Email: user.Email}
+	}
+	return resp
+}
+
+// normalizeCommentBody 本文の前後の空白を取り除き、空でないことを確認します
+func normalizeCommentBody(body string) (string, error) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", utils.NewBadRequestError("body must not be empty")
+	}
+	return body, nil
+}
+
+// resolveMentions 本文の@メールアドレスを、todoを閲覧できるユーザーに解決します
+// 存在しないユーザーやアクセス権のないユーザー、投稿者自身は無視します
+func resolveMentions(tx *gorm.DB, todo *models.Todo, authorID uint, body string) ([]models.User, error) {
+	emails := utils.ParseMentions(body)
+	if len(emails) == 0 {
+		return []models.User{}, nil
+	}
+
+	var users []models.User
+	if err := tx.Where("email IN ? AND id <> ?", emails, authorID).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	mentions := make([]models.User, 0, len(users))
+	for _, user := range users {
+		role, err := services.TodoRole(tx, user.ID, todo)
+		if err != nil {
+			return nil, err
+		}
+		if role.Allows(services.RoleViewer) {
+			mentions = append(mentions, user)
+		}
+	}
+	return mentions, nil
+}
+
+// loadComment コメントを投稿者・メンションと一緒に取得します
+func loadComment(tx *gorm.DB, todoID, commentID uint) (*models.Comment, error) {
+	var comment models.Comment
+	if err := tx.Preload("User").Preload("Mentions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("users.id")
+	}).Where("id = ? AND todo_id = ?", commentID, todoID).First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Comment not found")
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// parseCommentID URLのコメントIDを解釈します
+func parseCommentID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid comment ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// GetComments todoのコメントを古い順に取得（閲覧権限があれば参照可）
+func GetComments(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Where("todo_id = ?", todo.ID)
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := utils.DecodeCursor(cursorStr)
+		if err != nil || cursor.Sort != commentSort {
+			utils.RespondBadRequest(c, "Invalid cursor")
+			return
+		}
+		query = query.Where("id > ?", cursor.ID)
+	}
+
+	// 次ページの有無を判定するため1件多く取得
+	var comments []models.Comment
+	if err := query.Preload("User").Preload("Mentions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("users.id")
+	}).Order("id ASC").Limit(limit + 1).Find(&comments).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	var next *string
+	if len(comments) > limit {
+		comments = comments[:limit]
+		last := comments[limit-1]
+		cursor := utils.EncodeCursor(utils.Cursor{
+			Sort:  commentSort,
+			Value: strconv.FormatUint(uint64(last.ID), 10),
+			ID:    last.ID,
+		})
+		next = &cursor
+	}
+
+	resp := CommentListResponse{Items: make([]CommentResponse, len(comments)), NextCursor: next}
+	for i := range comments {
+		resp.Items[i] = newCommentResponse(&comments[i])
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateComment todoにコメントを投稿（編集権限が必要）
+func CreateComment(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var comment *models.Comment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		body, err := normalizeCommentBody(req.Body)
+		if err != nil {
+			return err
+		}
+		mentions, err := resolveMentions(tx, todo, userID.(uint), body)
+		if err != nil {
+			return err
+		}
+
+		created := models.Comment{TodoID: todo.ID, UserID: userID.(uint), Body: body}
+		if err := tx.Create(&created).Error; err != nil {
+			return err
+		}
+		if len(mentions) > 0 {
+			if err := tx.Model(&created).Omit("Mentions.*").Association("Mentions").Append(mentions); err != nil {
+				return err
+			}
+		}
+
+		comment, err = loadComment(tx, todo.ID, created.ID)
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCommentResponse(comment))
+}
+
+// UpdateComment コメントを編集（投稿者のみ）。編集前の本文は編集履歴に残ります
+func UpdateComment(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+
+	var req CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var comment *models.Comment
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		comment, err = loadComment(tx, todo.ID, commentID)
+		if err != nil {
+			return err
+		}
+		if comment.UserID != userID.(uint) {
+			return utils.NewForbiddenError("Only the author can edit this comment")
+		}
+
+		body, err := normalizeCommentBody(req.Body)
+		if err != nil {
+			return err
+		}
+		if body == comment.Body {
+			return nil
+		}
+
+		if err := tx.Create(&models.CommentRevision{CommentID: comment.ID, Body: comment.Body}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(comment).Updates(map[string]interface{}{
+			"body":      body,
+			"edited_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		mentions, err := resolveMentions(tx, todo, userID.(uint), body)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(comment).Omit("Mentions.*").Association("Mentions").Replace(mentions); err != nil {
+			return err
+		}
+
+		comment, err = loadComment(tx, todo.ID, comment.ID)
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newCommentResponse(comment))
+}
+
+// DeleteComment コメントを削除（投稿者、またはtodoのowner権限を持つユーザー）
+func DeleteComment(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		comment, err := loadComment(tx, todo.ID, commentID)
+		if err != nil {
+			return err
+		}
+		if comment.UserID != userID.(uint) {
+			if err := checkTodoRole(tx, userID.(uint), todo, services.RoleOwner); err != nil {
+				return err
+			}
+		}
+		// メンション・編集履歴は外部キーで削除される
+		return tx.Delete(comment).Error
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCommentHistory コメントの編集履歴（編集前の本文）を新しい順に取得
+func GetCommentHistory(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	commentID, ok := parseCommentID(c)
+	if !ok {
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	comment, err := loadComment(database.DB, todo.ID, commentID)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	var revisions []models.CommentRevision
+	if err := database.DB.Where("comment_id = ?", comment.ID).Order("id DESC").Find(&revisions).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
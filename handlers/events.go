@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/realtime"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// sseHeartbeatInterval プロキシに切断されないよう、イベントがなくてもコメント行を送る間隔
+	sseHeartbeatInterval = 25 * time.Second
+	// sseRetryMillis 切断されたときにクライアントが再接続するまでの待ち時間
+	sseRetryMillis = 3000
+	// sseReplayLimit 再接続時に再送するイベントの最大件数（これを超える場合はresetを送る）
+	sseReplayLimit = 1000
+)
+
+// writeSSE イベントを1件書き込みます（idが空なら省略）
+func writeSSE(w io.Writer, id string, event, data string) error {
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
+
+// replayTodoEvents Last-Event-IDより後のイベントを再送し、送ったイベントのIDを返します
+// 保持期間を過ぎて削除されたイベントがある、件数が多すぎる、または以前のバージョンのidで位置がわからない場合は、一覧を取り直すようresetを送ります
+func replayTodoEvents(w io.Writer, userID uint, since utils.EventCursor) (map[uint]bool, error) {
+	var bounds struct {
+		MinID *uint
+		MaxID *uint
+	}
+	var latest utils.EventCursor
+	var events []models.TodoEvent
+	// 位置と再送するイベントが食い違わないよう、同じスナップショットで読む
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if latest.XMin, err = services.SnapshotXMin(tx); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TodoEvent{}).Select("MIN(id) AS min_id, MAX(id) AS max_id").Scan(&bounds).Error; err != nil {
+			return err
+		}
+		if bounds.MaxID != nil {
+			latest.ID = *bounds.MaxID
+		}
+		if since.XMin == 0 || bounds.MinID == nil || *bounds.MinID > since.ID+1 {
+			return nil
+		}
+		return tx.Where("user_id = ?", userID).Scopes(services.TodoEventsAfter(since)).
+			Order("id ASC").Limit(sseReplayLimit + 1).Find(&events).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	if since.XMin == 0 || bounds.MinID == nil || *bounds.MinID > since.ID+1 || len(events) > sseReplayLimit {
+		// resetのIDを最新の位置にして、一覧を取り直したあとの再接続ではそこから再送させる
+		return nil, writeSSE(w, latest.String(), "reset", `{"reason":"replay_unavailable"}`)
+	}
+
+	sent := make(map[uint]bool, len(events))
+	for _, event := range events {
+		cursor := utils.EventCursor{ID: max(event.ID, since.ID), XMin: since.XMin}
+		if err := writeSSE(w, cursor.String(), event.Event, event.Data); err != nil {
+			return nil, err
+		}
+		sent[event.ID] = true
+	}
+	return sent, nil
+}
+
+// StreamEvents 自分が閲覧できるtodoの作成・更新・削除をServer-Sent Eventsで配信
+// Last-Event-ID（ヘッダーまたは?last_event_id）を指定すると、保持期間内のイベントを再送してから続きを配信します
+func StreamEvents(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	lastEventIDStr := c.GetHeader("Last-Event-ID")
+	if lastEventIDStr == "" {
+		lastEventIDStr = c.Query("last_event_id")
+	}
+	var since utils.EventCursor
+	if lastEventIDStr != "" {
+		var err error
+		if since, err = utils.ParseEventCursor(lastEventIDStr); err != nil {
+			utils.RespondBadRequest(c, "Invalid Last-Event-ID")
+			return
+		}
+	}
+
+	// 再送中に記録されたイベントを取りこぼさないよう、再送より先に購読を始める
+	sub := realtime.DefaultHub.Subscribe(userID.(uint))
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // nginxのバッファリングを無効にする
+	c.Status(http.StatusOK)
+
+	w := c.Writer
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis); err != nil {
+		return
+	}
+	var replayed map[uint]bool
+	if lastEventIDStr != "" {
+		var err error
+		if replayed, err = replayTodoEvents(w, userID.(uint), since); err != nil {
+			return
+		}
+	}
+	w.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// 送信が追いつかずに購読が打ち切られた。クライアントはLast-Event-IDで再接続して続きを受け取る
+				return
+			}
+			if replayed[event.ID] {
+				continue
+			}
+			if err := writeSSE(w, event.Cursor, event.Event, event.Data); err != nil {
+				return
+			}
+			w.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
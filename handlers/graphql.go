@@ -0,0 +1,540 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// GraphQLRequest GraphQLのリクエスト
+type GraphQLRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlContextKey GraphQLのリゾルバーに渡すcontextのキー
+type graphqlContextKey int
+
+const (
+	graphqlUserIDKey graphqlContextKey = iota
+	graphqlLoadersKey
+)
+
+// graphqlError RESTと同じエラーコード・ステータスをGraphQLのエラーのextensionsに含めるためのエラー
+type graphqlError struct {
+	*utils.APIError
+}
+
+// Extensions エラーのextensionsを返します
+func (e graphqlError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code":   string(e.Code),
+		"status": e.Status,
+	}
+	if len(e.Details) > 0 {
+		extensions["details"] = e.Details
+	}
+	return extensions
+}
+
+// toGraphQLError エラーをAPIErrorに変換してGraphQLのエラーにします（DBエラーの内容は返さない）
+func toGraphQLError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return graphqlError{utils.ToAPIError(err)}
+}
+
+// graphqlResolver リゾルバーが返したエラーをGraphQLのエラーに変換します
+func graphqlResolver(fn graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		result, err := fn(p)
+		if err != nil {
+			return nil, toGraphQLError(err)
+		}
+		return result, nil
+	}
+}
+
+// graphqlThunk ローダーのthunkが返したエラーをGraphQLのエラーに変換します
+func graphqlThunk(thunk func() (interface{}, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		result, err := thunk()
+		if err != nil {
+			return nil, toGraphQLError(err)
+		}
+		return result, nil
+	}
+}
+
+func graphqlUserID(p graphql.ResolveParams) uint {
+	return p.Context.Value(graphqlUserIDKey).(uint)
+}
+
+func graphqlLoadersFrom(p graphql.ResolveParams) *graphqlLoaders {
+	return p.Context.Value(graphqlLoadersKey).(*graphqlLoaders)
+}
+
+// graphqlTodo リゾルバーのsource（*models.Todoまたはmodels.Todo）をtodoにします
+func graphqlTodo(p graphql.ResolveParams) *models.Todo {
+	switch todo := p.Source.(type) {
+	case *models.Todo:
+		return todo
+	case models.Todo:
+		return &todo
+	}
+	return nil
+}
+
+// newGraphQLListParams todosの引数をクエリパラメータの文字列に変換します
+func newGraphQLListParams(args map[string]interface{}) queryParams {
+	params := make(queryParams, len(args))
+	for key, value := range args {
+		switch v := value.(type) {
+		case string:
+			params[key] = v
+		case bool:
+			params[key] = strconv.FormatBool(v)
+		case int:
+			params[key] = strconv.Itoa(v)
+		}
+	}
+	return params
+}
+
+// decodeGraphQLInput 入力オブジェクトをRESTと同じリクエスト構造体に読み込み、同じ検証を行います
+// GraphQLのnullは未指定と区別できないため、clearに指定したフィールドをnullとして扱います
+func decodeGraphQLInput(input map[string]interface{}, req interface{}) error {
+	fields := make(map[string]interface{}, len(input))
+	for key, value := range input {
+		if key != "clear" {
+			fields[key] = value
+		}
+	}
+	if clear, ok := input["clear"].([]interface{}); ok {
+		for _, name := range clear {
+			fields[name.(string)] = nil
+		}
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return utils.NewBadRequestError(err.Error())
+	}
+	return decodeBatchData(data, req)
+}
+
+// todoListArgs todosの引数（GET /todosのクエリパラメータと同じ）
+var todoListArgs = graphql.FieldConfigArgument{
+	"completed":        &graphql.ArgumentConfig{Type: graphql.Boolean},
+	"project_id":       &graphql.ArgumentConfig{Type: graphql.String, Description: "プロジェクトのID（noneでプロジェクト未所属）"},
+	"parent_id":        &graphql.ArgumentConfig{Type: graphql.String, Description: "親のtodoのID（noneでサブタスク以外）"},
+	"label":            &graphql.ArgumentConfig{Type: graphql.String, Description: "ラベル名（カンマ区切り）"},
+	"label_mode":       &graphql.ArgumentConfig{Type: graphql.String, Description: "any（デフォルト）またはall"},
+	"created_after":    &graphql.ArgumentConfig{Type: graphql.String},
+	"created_before":   &graphql.ArgumentConfig{Type: graphql.String},
+	"updated_after":    &graphql.ArgumentConfig{Type: graphql.String},
+	"updated_before":   &graphql.ArgumentConfig{Type: graphql.String},
+	"due_after":        &graphql.ArgumentConfig{Type: graphql.String},
+	"due_before":       &graphql.ArgumentConfig{Type: graphql.String},
+	"include_archived": &graphql.ArgumentConfig{Type: graphql.Boolean},
+	"sort":             &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "created_at"},
+	"limit":            &graphql.ArgumentConfig{Type: graphql.Int},
+	"cursor":           &graphql.ArgumentConfig{Type: graphql.String},
+}
+
+// resolveTodos 自分のtodoと共有されたtodoの一覧を取得します（GetTodosと同じ）
+func resolveTodos(p graphql.ResolveParams) (interface{}, error) {
+	return listTodos(database.DB.Scopes(services.VisibleTodos(graphqlUserID(p))), newGraphQLListParams(p.Args))
+}
+
+// newGraphQLSchema GraphQLのスキーマを作成します
+// フィールド名・引数名はRESTのJSON・クエリパラメータと同じsnake_caseです
+func newGraphQLSchema() (graphql.Schema, error) {
+	labelType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Label",
+		Fields: graphql.Fields{
+			"id":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"color": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		},
+	})
+
+	projectType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Project",
+		Fields: graphql.Fields{
+			"id":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"user_id":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"name":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"archived_at": &graphql.Field{Type: graphql.DateTime},
+			"created_at":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"updated_at":  &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		},
+	})
+
+	var todoType *graphql.Object
+	todoType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Todo",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":           &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"user_id":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"title":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"completed":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+				"project_id":   &graphql.Field{Type: graphql.Int},
+				"parent_id":    &graphql.Field{Type: graphql.Int},
+				"start_at":     &graphql.Field{Type: graphql.DateTime},
+				"due_at":       &graphql.Field{Type: graphql.DateTime},
+				"rrule":        &graphql.Field{Type: graphql.String},
+				"series_id":    &graphql.Field{Type: graphql.Int},
+				"priority":     &graphql.Field{Type: graphql.String},
+				"completed_at": &graphql.Field{Type: graphql.DateTime},
+				"created_at":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"updated_at":   &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+				"version":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+				"etag":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"labels": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(labelType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return graphqlThunk(graphqlLoadersFrom(p).labels.load(graphqlTodo(p).ID)), nil
+					},
+				},
+				"project": &graphql.Field{
+					Type:        projectType,
+					Description: "所属するプロジェクト（閲覧できない場合はnull）",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						todo := graphqlTodo(p)
+						if todo.ProjectID == nil {
+							return nil, nil
+						}
+						thunk := graphqlLoadersFrom(p).projects.load(*todo.ProjectID)
+						return graphqlThunk(func() (interface{}, error) {
+							project, err := thunk()
+							if err != nil || project.(*models.Project) == nil {
+								return nil, err
+							}
+							return project, nil
+						}), nil
+					},
+				},
+				"parent": &graphql.Field{
+					Type:        todoType,
+					Description: "親のtodo（閲覧できない場合はnull）",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						todo := graphqlTodo(p)
+						if todo.ParentID == nil {
+							return nil, nil
+						}
+						thunk := graphqlLoadersFrom(p).todos.load(*todo.ParentID)
+						return graphqlThunk(func() (interface{}, error) {
+							parent, err := thunk()
+							if err != nil || parent.(*models.Todo) == nil {
+								return nil, err
+							}
+							return parent, nil
+						}), nil
+					},
+				},
+				"subtasks": &graphql.Field{
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType))),
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return graphqlThunk(graphqlLoadersFrom(p).subtasks.load(graphqlTodo(p).ID)), nil
+					},
+				},
+			}
+		}),
+	})
+
+	todoConnectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "TodoConnection",
+		Fields: graphql.Fields{
+			"items":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(todoType)))},
+			"next_cursor": &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":                   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+			"email":                &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"timezone":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"cascade_completion":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"auto_complete_parent": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"created_at":           &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+			"todos": &graphql.Field{
+				Type:        graphql.NewNonNull(todoConnectionType),
+				Description: "自分のtodoと共有されたtodoの一覧（Query.todosと同じ）",
+				Args:        todoListArgs,
+				Resolve:     graphqlResolver(resolveTodos),
+			},
+		},
+	})
+
+	todoFieldEnum := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "TodoClearableField",
+		Description: "updateTodoでnullにできるフィールド",
+		Values: graphql.EnumValueConfigMap{
+			"project_id": &graphql.EnumValueConfig{Value: "project_id"},
+			"parent_id":  &graphql.EnumValueConfig{Value: "parent_id"},
+			"start_at":   &graphql.EnumValueConfig{Value: "start_at"},
+			"due_at":     &graphql.EnumValueConfig{Value: "due_at"},
+			"rrule":      &graphql.EnumValueConfig{Value: "rrule"},
+			"priority":   &graphql.EnumValueConfig{Value: "priority"},
+		},
+	})
+
+	createTodoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateTodoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":      &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"project_id": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"parent_id":  &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"start_at":   &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"due_at":     &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"rrule":      &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"priority":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"label_ids":  &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+		},
+	})
+
+	updateTodoInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateTodoInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"title":            &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"completed":        &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+			"project_id":       &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"parent_id":        &graphql.InputObjectFieldConfig{Type: graphql.Int},
+			"start_at":         &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"due_at":           &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+			"rrule":            &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"priority":         &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"label_ids":        &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+			"add_label_ids":    &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+			"remove_label_ids": &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+			"clear": &graphql.InputObjectFieldConfig{
+				Type:        graphql.NewList(graphql.NewNonNull(todoFieldEnum)),
+				Description: "nullにするフィールド（PATCH /todos/:idでnullを指定するのと同じ）",
+			},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: graphqlResolver(func(p graphql.ResolveParams) (interface{}, error) {
+					var user models.User
+					if err := database.DB.First(&user, graphqlUserID(p)).Error; err != nil {
+						if errors.Is(err, gorm.ErrRecordNotFound) {
+							return nil, utils.NewNotFoundError("User not found")
+						}
+						return nil, err
+					}
+					return &user, nil
+				}),
+			},
+			"todos": &graphql.Field{
+				Type:    graphql.NewNonNull(todoConnectionType),
+				Args:    todoListArgs,
+				Resolve: graphqlResolver(resolveTodos),
+			},
+			"todo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: graphqlResolver(func(p graphql.ResolveParams) (interface{}, error) {
+					return authorizeTodo(database.DB, graphqlUserID(p), uint(p.Args["id"].(int)), services.RoleViewer)
+				}),
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createTodoInput)},
+				},
+				Resolve: graphqlResolver(func(p graphql.ResolveParams) (interface{}, error) {
+					var req CreateTodoRequest
+					if err := decodeGraphQLInput(p.Args["input"].(map[string]interface{}), &req); err != nil {
+						return nil, err
+					}
+					var todo *models.Todo
+					err := database.DB.Transaction(func(tx *gorm.DB) error {
+						var err error
+						todo, err = createTodo(tx, graphqlUserID(p), req)
+						return err
+					})
+					return todo, err
+				}),
+			},
+			"updateTodo": &graphql.Field{
+				Type: graphql.NewNonNull(todoType),
+				Args: graphql.FieldConfigArgument{
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"input":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateTodoInput)},
+					"if_match": &graphql.ArgumentConfig{Type: graphql.String, Description: "If-Matchヘッダーと同じ（ETagが一致する場合だけ更新）"},
+				},
+				Resolve: graphqlResolver(func(p graphql.ResolveParams) (interface{}, error) {
+					var req UpdateTodoRequest
+					if err := decodeGraphQLInput(p.Args["input"].(map[string]interface{}), &req); err != nil {
+						return nil, err
+					}
+					ifMatch, _ := p.Args["if_match"].(string)
+					var todo *models.Todo
+					err := database.DB.Transaction(func(tx *gorm.DB) error {
+						var err error
+						todo, err = updateTodo(tx, graphqlUserID(p), uint(p.Args["id"].(int)), req, ifMatch)
+						return err
+					})
+					return todo, err
+				}),
+			},
+			"deleteTodo": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Boolean),
+				Description: "todoをゴミ箱に移動します（DELETE /todos/:idと同じ）",
+				Args: graphql.FieldConfigArgument{
+					"id":       &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+					"children": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "cascade"},
+					"if_match": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: graphqlResolver(func(p graphql.ResolveParams) (interface{}, error) {
+					ifMatch, _ := p.Args["if_match"].(string)
+					err := database.DB.Transaction(func(tx *gorm.DB) error {
+						return deleteTodo(tx, graphqlUserID(p), uint(p.Args["id"].(int)), p.Args["children"].(string), ifMatch)
+					})
+					return err == nil, err
+				}),
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// graphqlSchema 起動時に作成するGraphQLのスキーマ
+var graphqlSchema = func() graphql.Schema {
+	schema, err := newGraphQLSchema()
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}()
+
+// findGraphQLOperation 実行する操作を返します（operationNameが空なら唯一の操作）
+func findGraphQLOperation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			if found != nil {
+				return nil
+			}
+			found = operation
+		} else if operation.Name != nil && operation.Name.Value == name {
+			return operation
+		}
+	}
+	return found
+}
+
+// formatGraphQLError APIErrorをGraphQLのエラーの形式にします
+func formatGraphQLError(err error) gqlerrors.FormattedError {
+	apiErr := graphqlError{utils.ToAPIError(err)}
+	return gqlerrors.FormattedError{
+		Message:    apiErr.Message,
+		Locations:  []location.SourceLocation{},
+		Extensions: apiErr.Extensions(),
+	}
+}
+
+// respondGraphQLErrors 実行前に弾いたリクエストのエラーをGraphQLの形式で返します
+// 構文・スキーマの検証エラーにもinvalid_requestのエラーコードを付けます
+func respondGraphQLErrors(c *gin.Context, errs ...gqlerrors.FormattedError) {
+	for i := range errs {
+		if errs[i].Extensions == nil {
+			errs[i].Extensions = graphqlError{utils.NewBadRequestError(errs[i].Message)}.Extensions()
+		}
+	}
+	c.JSON(http.StatusBadRequest, &graphql.Result{Errors: errs})
+}
+
+// GraphQL /graphqlでクエリ・ミューテーションを実行
+// 実行前に構文・スキーマを検証し、深さと複雑さが上限を超えるクエリは実行しません
+// 実行中のエラーは200でdataと一緒に返し、extensionsにRESTと同じエラーコード（code）とステータス（status）を含めます
+func GraphQL(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req GraphQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondGraphQLErrors(c, formatGraphQLError(utils.NewBadRequestError(err.Error())))
+		return
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"}),
+	})
+	if err != nil {
+		respondGraphQLErrors(c, gqlerrors.FormatError(err))
+		return
+	}
+	if validation := graphql.ValidateDocument(&graphqlSchema, doc, nil); !validation.IsValid {
+		respondGraphQLErrors(c, validation.Errors...)
+		return
+	}
+	operation := findGraphQLOperation(doc, req.OperationName)
+	if operation == nil {
+		respondGraphQLErrors(c, formatGraphQLError(utils.NewBadRequestError("Unknown or ambiguous operation")))
+		return
+	}
+	if err := checkGraphQLLimits(doc, operation, req.Variables); err != nil {
+		respondGraphQLErrors(c, formatGraphQLError(err))
+		return
+	}
+
+	ctx := context.WithValue(c.Request.Context(), graphqlUserIDKey, userID.(uint))
+	ctx = context.WithValue(ctx, graphqlLoadersKey, newGraphQLLoaders(userID.(uint)))
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        graphqlSchema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       ctx,
+	})
+	// ローダーのthunkから返したエラーは、graphql-goがextensionsを引き継がないため内部エラーとして扱う
+	for i := range result.Errors {
+		if result.Errors[i].Extensions == nil {
+			result.Errors[i].Extensions = graphqlError{&utils.APIError{Status: http.StatusInternalServerError, Code: utils.ErrorCodeInternal}}.Extensions()
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"go-gin-todo-api/utils"
+)
+
+const (
+	// maxGraphQLDepth クエリのフィールドの入れ子の深さの上限
+	maxGraphQLDepth = 8
+	// maxGraphQLComplexity クエリの複雑さ（取得しうるフィールドの数の見積もり）の上限
+	maxGraphQLComplexity = 5000
+)
+
+// graphqlListSizes リストを返すフィールドの、複雑さを見積もる際の件数
+// limit引数を持つフィールドは引数の値（省略時はデフォルトの件数）を使います
+var graphqlListSizes = map[string]int{
+	"labels":   10,
+	"subtasks": 20,
+}
+
+// graphqlCost クエリの深さと複雑さを求めます
+type graphqlCost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+}
+
+// checkGraphQLLimits 実行する操作の深さと複雑さが上限を超えていないか検証します
+// イントロスペクション（__で始まるフィールド）は数えません
+func checkGraphQLLimits(doc *ast.Document, operation *ast.OperationDefinition, variables map[string]interface{}) error {
+	cost := graphqlCost{fragments: make(map[string]*ast.FragmentDefinition), variables: variables}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			cost.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	complexity, depth := cost.selectionSet(operation.SelectionSet, 0)
+	if depth > maxGraphQLDepth {
+		return utils.NewBadRequestError(fmt.Sprintf("Query depth %d exceeds the limit of %d", depth, maxGraphQLDepth))
+	}
+	if complexity > maxGraphQLComplexity {
+		return utils.NewBadRequestError(fmt.Sprintf("Query complexity %d exceeds the limit of %d", complexity, maxGraphQLComplexity))
+	}
+	return nil
+}
+
+// selectionSet 選択されたフィールドの複雑さと、最も深いフィールドの深さを返します
+func (g graphqlCost) selectionSet(set *ast.SelectionSet, depth int) (int, int) {
+	if set == nil {
+		return 0, depth
+	}
+	complexity, maxDepth := 0, depth
+	for _, selection := range set.Selections {
+		var c, d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			c, d = g.selectionSet(s.SelectionSet, depth+1)
+			c = 1 + g.listSize(s)*c
+		case *ast.InlineFragment:
+			c, d = g.selectionSet(s.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			// フラグメントの循環は実行前の検証で弾かれている
+			if fragment, ok := g.fragments[s.Name.Value]; ok {
+				c, d = g.selectionSet(fragment.SelectionSet, depth)
+			}
+		}
+		complexity += c
+		maxDepth = max(maxDepth, d)
+	}
+	return complexity, maxDepth
+}
+
+// listSize フィールドが返すリストの件数の見積もりを返します（リストでなければ1）
+func (g graphqlCost) listSize(field *ast.Field) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil && n > 0 {
+				return n
+			}
+		case *ast.Variable:
+			switch n := g.variables[value.Name.Value].(type) {
+			case float64:
+				if n > 0 {
+					return int(n)
+				}
+			case int:
+				if n > 0 {
+					return n
+				}
+			}
+		}
+		return utils.DefaultPageLimit
+	}
+	if field.Name.Value == "todos" {
+		return utils.DefaultPageLimit
+	}
+	if size, ok := graphqlListSizes[field.Name.Value]; ok {
+		return size
+	}
+	return 1
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"sync"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+)
+
+// batchLoader 1回のGraphQLリクエストの中で要求されたキーをまとめ、1回のクエリで読み込むローダー（dataloader）
+// loadは値の代わりにthunkを返し、GraphQLの実行がthunkを呼び出す時点で、それまでに要求されたキーをまとめて読み込みます
+type batchLoader[K comparable, V any] struct {
+	mu      sync.Mutex
+	fetch   func(keys []K) (map[K]V, error)
+	pending []K
+	cache   map[K]V
+	errs    map[K]error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{fetch: fetch, cache: make(map[K]V), errs: make(map[K]error)}
+}
+
+// load キーの読み込みを予約し、値を返すthunkを返します
+func (l *batchLoader[K, V]) load(key K) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.cache[key]; !ok {
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.flushLocked()
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		return l.cache[key], nil
+	}
+}
+
+// flushLocked 予約されたキーのうち、まだ読み込んでいないものをまとめて読み込みます
+func (l *batchLoader[K, V]) flushLocked() {
+	keys := make([]K, 0, len(l.pending))
+	seen := make(map[K]bool, len(l.pending))
+	for _, key := range l.pending {
+		if _, ok := l.cache[key]; !ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+
+	values, err := l.fetch(keys)
+	for _, key := range keys {
+		// 見つからなかったキーもゼロ値で記録し、読み込み直さないようにする
+		l.cache[key] = values[key]
+		if err != nil {
+			l.errs[key] = err
+		}
+	}
+}
+
+// graphqlLoaders GraphQLのリクエストごとのローダー
+// todoの関連はtodoごとに読み込むとN+1クエリになるため、一覧の全件分をまとめて読み込みます
+type graphqlLoaders struct {
+	labels   *batchLoader[uint, []models.Label]
+	projects *batchLoader[uint, *models.Project]
+	todos    *batchLoader[uint, *models.Todo]
+	subtasks *batchLoader[uint, []models.Todo]
+}
+
+// newGraphQLLoaders ユーザーが閲覧できるものだけを読み込むローダーを作成します
+func newGraphQLLoaders(userID uint) *graphqlLoaders {
+	return &graphqlLoaders{
+		labels: newBatchLoader(func(todoIDs []uint) (map[uint][]models.Label, error) {
+			var rows []struct {
+				TodoID uint
+				models.Label
+			}
+			if err := database.DB.Table("labels").
+				Select("todo_labels.todo_id, labels.*").
+				Joins("JOIN todo_labels ON todo_labels.label_id = labels.id").
+				Where("todo_labels.todo_id IN ?", todoIDs).
+				Order("labels.name ASC").
+				Scan(&rows).Error; err != nil {
+				return nil, err
+			}
+			labels := make(map[uint][]models.Label, len(todoIDs))
+			for _, id := range todoIDs {
+				labels[id] = []models.Label{}
+			}
+			for _, row := range rows {
+				labels[row.TodoID] = append(labels[row.TodoID], row.Label)
+			}
+			return labels, nil
+		}),
+		projects: newBatchLoader(func(ids []uint) (map[uint]*models.Project, error) {
+			var projects []models.Project
+			if err := database.DB.Scopes(services.VisibleProjects(userID)).Where("id IN ?", ids).Find(&projects).Error; err != nil {
+				return nil, err
+			}
+			byID := make(map[uint]*models.Project, len(projects))
+			for i := range projects {
+				byID[projects[i].ID] = &projects[i]
+			}
+			return byID, nil
+		}),
+		todos: newBatchLoader(func(ids []uint) (map[uint]*models.Todo, error) {
+			var todos []models.Todo
+			if err := database.DB.Scopes(services.VisibleTodos(userID)).Where("id IN ?", ids).Find(&todos).Error; err != nil {
+				return nil, err
+			}
+			byID := make(map[uint]*models.Todo, len(todos))
+			for i := range todos {
+				byID[todos[i].ID] = &todos[i]
+			}
+			return byID, nil
+		}),
+		subtasks: newBatchLoader(func(parentIDs []uint) (map[uint][]models.Todo, error) {
+			var todos []models.Todo
+			if err := database.DB.Scopes(services.VisibleTodos(userID)).Where("parent_id IN ?", parentIDs).
+				Order("created_at ASC, id ASC").Find(&todos).Error; err != nil {
+				return nil, err
+			}
+			children := make(map[uint][]models.Todo, len(parentIDs))
+			for _, id := range parentIDs {
+				children[id] = []models.Todo{}
+			}
+			for _, todo := range todos {
+				children[*todo.ParentID] = append(children[*todo.ParentID], todo)
+			}
+			return children, nil
+		}),
+	}
+}
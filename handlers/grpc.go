@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	todov1 "go-gin-todo-api/proto/todo/v1"
+	"go-gin-todo-api/realtime"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+)
+
+// GRPCPublicMethods 認証なしで呼び出せるgRPCのメソッド
+var GRPCPublicMethods = map[string]bool{
+	todov1.AuthService_Register_FullMethodName: true,
+	todov1.AuthService_Login_FullMethodName:    true,
+	todov1.AuthService_Refresh_FullMethodName:  true,
+	todov1.AuthService_Logout_FullMethodName:   true,
+}
+
+// grpcUserID 認証のインターセプターがcontextに設定したuser_idを返します
+func grpcUserID(ctx context.Context) (uint, error) {
+	userID, ok := middleware.GRPCUserID(ctx)
+	if !ok {
+		return 0, utils.NewUnauthorizedError("Unauthorized")
+	}
+	return userID, nil
+}
+
+// validateGRPCRequest RESTと同じbindingタグで検証します
+func validateGRPCRequest(req interface{}) error {
+	if err := binding.Validator.ValidateStruct(req); err != nil {
+		return utils.NewBadRequestError(err.Error())
+	}
+	return nil
+}
+
+func toProtoTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func fromProtoTimestamp(ts *timestamppb.Timestamp) *time.Time {
+	if ts == nil {
+		return nil
+	}
+	t := ts.AsTime()
+	return &t
+}
+
+func toProtoID(id *uint) *uint32 {
+	if id == nil {
+		return nil
+	}
+	v := uint32(*id)
+	return &v
+}
+
+func fromProtoID(id *uint32) *uint {
+	if id == nil {
+		return nil
+	}
+	v := uint(*id)
+	return &v
+}
+
+func fromProtoIDs(ids []uint32) []uint {
+	if ids == nil {
+		return nil
+	}
+	values := make([]uint, len(ids))
+	for i, id := range ids {
+		values[i] = uint(id)
+	}
+	return values
+}
+
+func toProtoUser(user *models.User) *todov1.User {
+	return &todov1.User{
+		Id:        uint32(user.ID),
+		Email:     user.Email,
+		Timezone:  user.Timezone,
+		CreatedAt: toProtoTimestamp(&user.CreatedAt),
+	}
+}
+
+func toProtoTokens(tokens *TokenResponse) *todov1.TokenResponse {
+	return &todov1.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    tokens.TokenType,
+	}
+}
+
+func toProtoTodo(todo *models.Todo) *todov1.Todo {
+	labels := make([]*todov1.Label, len(todo.Labels))
+	for i, label := range todo.Labels {
+		labels[i] = &todov1.Label{Id: uint32(label.ID), Name: label.Name, Color: label.Color}
+	}
+	return &todov1.Todo{
+		Id:          uint32(todo.ID),
+		UserId:      uint32(todo.UserID),
+		Title:       todo.Title,
+		Completed:   todo.Completed,
+		ProjectId:   toProtoID(todo.ProjectID),
+		ParentId:    toProtoID(todo.ParentID),
+		StartAt:     toProtoTimestamp(todo.StartAt),
+		DueAt:       toProtoTimestamp(todo.DueAt),
+		Rrule:       todo.RRule,
+		SeriesId:    toProtoID(todo.SeriesID),
+		Priority:    todo.Priority,
+		CompletedAt: toProtoTimestamp(todo.CompletedAt),
+		CreatedAt:   toProtoTimestamp(&todo.CreatedAt),
+		UpdatedAt:   toProtoTimestamp(&todo.UpdatedAt),
+		Version:     uint32(todo.Version),
+		Etag:        todo.ETag,
+		Labels:      labels,
+	}
+}
+
+// authGRPCServer gRPCのAuthService（POST /auth/*と同じ処理）
+type authGRPCServer struct {
+	todov1.UnimplementedAuthServiceServer
+}
+
+// NewAuthServiceServer gRPCのAuthServiceを作成します
+func NewAuthServiceServer() todov1.AuthServiceServer {
+	return &authGRPCServer{}
+}
+
+func (s *authGRPCServer) Register(ctx context.Context, in *todov1.RegisterRequest) (*todov1.User, error) {
+	req := RegisterRequest{Email: in.GetEmail(), Password: in.GetPassword()}
+	if err := validateGRPCRequest(&req); err != nil {
+		return nil, err
+	}
+	user, err := registerUser(req)
+	if err != nil {
+		return nil, err
+	}
+	return toProtoUser(user), nil
+}
+
+func (s *authGRPCServer) Login(ctx context.Context, in *todov1.LoginRequest) (*todov1.TokenResponse, error) {
+	req := LoginRequest{Email: in.GetEmail(), Password: in.GetPassword()}
+	if err := validateGRPCRequest(&req); err != nil {
+		return nil, err
+	}
+
+	// user.loginのwebhookで送るIPとUser-Agent
+	var ip, userAgent string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if values := metadata.ValueFromIncomingContext(ctx, "user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+
+	tokens, err := loginUser(req, ip, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	return toProtoTokens(tokens), nil
+}
+
+func (s *authGRPCServer) Refresh(ctx context.Context, in *todov1.RefreshRequest) (*todov1.TokenResponse, error) {
+	req := RefreshRequest{RefreshToken: in.GetRefreshToken()}
+	if err := validateGRPCRequest(&req); err != nil {
+		return nil, err
+	}
+	tokens, err := refreshTokens(req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return toProtoTokens(tokens), nil
+}
+
+func (s *authGRPCServer) Logout(ctx context.Context, in *todov1.RefreshRequest) (*emptypb.Empty, error) {
+	req := RefreshRequest{RefreshToken: in.GetRefreshToken()}
+	if err := validateGRPCRequest(&req); err != nil {
+		return nil, err
+	}
+	if err := revokeRefreshToken(req.RefreshToken); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *authGRPCServer) GetMe(ctx context.Context, _ *emptypb.Empty) (*todov1.User, error) {
+	userID, err := grpcUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return toProtoUser(&user), nil
+}
+
+// todoGRPCServer gRPCのTodoService（/todosのRESTハンドラーと同じ処理）
+type todoGRPCServer struct {
+	todov1.UnimplementedTodoServiceServer
+}
+
+// NewTodoServiceServer gRPCのTodoServiceを作成します
+func NewTodoServiceServer() todov1.TodoServiceServer {
+	return &todoGRPCServer{}
+}
+
+// newGRPCListParams ListTodosRequestをGET /todosのクエリパラメータに変換します
+func newGRPCListParams(in *todov1.ListTodosRequest) queryParams {
+	params := queryParams{}
+	if in.Completed != nil {
+		params["completed"] = strconv.FormatBool(in.GetCompleted())
+	}
+	for key, value := range map[string]string{
+		"project_id": in.GetProjectId(),
+		"parent_id":  in.GetParentId(),
+		"label":      in.GetLabel(),
+		"label_mode": in.GetLabelMode(),
+		"sort":       in.GetSort(),
+		"cursor":     in.GetCursor(),
+	} {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	for key, ts := range map[string]*timestamppb.Timestamp{
+		"created_after":  in.GetCreatedAfter(),
+		"created_before": in.GetCreatedBefore(),
+		"updated_after":  in.GetUpdatedAfter(),
+		"updated_before": in.GetUpdatedBefore(),
+		"due_after":      in.GetDueAfter(),
+		"due_before":     in.GetDueBefore(),
+	} {
+		if ts != nil {
+			params[key] = ts.AsTime().Format(time.RFC3339Nano)
+		}
+	}
+	if in.GetIncludeArchived() {
+		params["include_archived"] = "true"
+	}
+	if in.GetLimit() != 0 {
+		params["limit"] = strconv.Itoa(int(in.GetLimit()))
+	}
+	return params
+}
+
+func (s *todoGRPCServer) ListTodos(ctx context.Context, in *todov1.ListTodosRequest) (*todov1.ListTodosResponse, error) {
+	userID, err := grpcUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	page, err := listTodos(database.DB.Scopes(services.VisibleTodos(userID)).Preload("Labels"), newGRPCListParams(in))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &todov1.ListTodosResponse{Items: make([]*todov1.Todo, len(page.Items))}
+	for i := range page.Items {
+		resp.Items[i] = toProtoTodo(&page.Items[i])
+	}
+	if page.NextCursor != nil {
+		resp.NextCursor = *page.NextCursor
+	}
+	return resp, nil
+}
+
+func (s *todoGRPCServer) GetTodo(ctx context.Context, in *todov1.GetTodoRequest) (*todov1.Todo, error) {
+	userID, err := grpcUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	todo, err := authorizeTodo(database.DB, userID, uint(in.GetId()), services.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.DB.Model(todo).Association("Labels").Find(&todo.Labels); err != nil {
+		return nil, err
+	}
+	return toProtoTodo(todo), nil
+}
+
+func (s *todoGRPCServer) CreateTodo(ctx context.Context, in *todov1.CreateTodoRequest) (*todov1.Todo, error) {
+	userID, err := grpcUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	input := in.GetTodo()
+	req := CreateTodoRequest{
+		Title:     input.GetTitle(),
+		ProjectID: fromProtoID(input.ProjectId),
+		ParentID:  fromProtoID(input.ParentId),
+		StartAt:   fromProtoTimestamp(input.GetStartAt()),
+		DueAt:     fromProtoTimestamp(input.GetDueAt()),
+		RRule:     input.Rrule,
+		Priority:  input.Priority,
+		LabelIDs:  fromProtoIDs(input.GetLabelIds()),
+	}
+	if err := validateGRPCRequest(&req); err != nil {
+		return nil, err
+	}
+
+	var todo *models.Todo
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		todo, err = createTodo(tx, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toProtoTodo(todo), nil
+}
+
+// newGRPCUpdateRequest update_maskに指定したフィールドをPATCH /todos/:idのリクエストに変換します
+// optionalのフィールドは、update_maskに含めて値を省略するとnullとして扱います
+func newGRPCUpdateRequest(in *todov1.UpdateTodoRequest) (UpdateTodoRequest, error) {
+	input := in.GetTodo()
+	req := UpdateTodoRequest{
+		AddLabelIDs:    fromProtoIDs(in.GetAddLabelIds()),
+		RemoveLabelIDs: fromProtoIDs(in.GetRemoveLabelIds()),
+	}
+	for _, path := range in.GetUpdateMask().GetPaths() {
+		switch path {
+		case "title":
+			title := input.GetTitle()
+			req.Title = &title
+		case "completed":
+			req.Completed = input.Completed
+		case "project_id":
+			req.ProjectID = utils.Optional[uint]{Set: true, Value: fromProtoID(input.ProjectId)}
+		case "parent_id":
+			req.ParentID = utils.Optional[uint]{Set: true, Value: fromProtoID(input.ParentId)}
+		case "start_at":
+			req.StartAt = utils.Optional[time.Time]{Set: true, Value: fromProtoTimestamp(input.GetStartAt())}
+		case "due_at":
+			req.DueAt = utils.Optional[time.Time]{Set: true, Value: fromProtoTimestamp(input.GetDueAt())}
+		case "rrule":
+			req.RRule = utils.Optional[string]{Set: true, Value: input.Rrule}
+		case "priority":
+			req.Priority = utils.Optional[string]{Set: true, Value: input.Priority}
+		case "label_ids":
+			labelIDs := fromProtoIDs(input.GetLabelIds())
+			if labelIDs == nil {
+				labelIDs = []uint{}
+			}
+			req.LabelIDs = &labelIDs
+		default:
+			return req, utils.NewBadRequestError(fmt.Sprintf("Invalid update_mask path: %s", path))
+		}
+	}
+	return req, validateGRPCRequest(&req)
+}
+
+func (s *todoGRPCServer) UpdateTodo(ctx context.Context, in *todov1.UpdateTodoRequest) (*todov1.Todo, error) {
+	userID, err := grpcUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req, err := newGRPCUpdateRequest(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var todo *models.Todo
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		todo, err = updateTodo(tx, userID, uint(in.GetId()), req, in.GetIfMatch())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toProtoTodo(todo), nil
+}
+
+func (s *todoGRPCServer) DeleteTodo(ctx context.Context, in *todov1.DeleteTodoRequest) (*emptypb.Empty, error) {
+	userID, err := grpcUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	children := in.GetChildren()
+	if children == "" {
+		children = "cascade"
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTodo(tx, userID, uint(in.GetId()), children, in.GetIfMatch())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// WatchTodos GET /eventsと同じイベントを配信します（切断中のイベントは再送しません）
+// アクセストークンの有効期限が来たらUnauthenticatedで終えるので、クライアントは新しいトークンで接続し直します
+func (s *todoGRPCServer) WatchTodos(_ *todov1.WatchTodosRequest, stream grpc.ServerStreamingServer[todov1.TodoEvent]) error {
+	userID, err := grpcUserID(stream.Context())
+	if err != nil {
+		return err
+	}
+	expiresAt, ok := middleware.GRPCTokenExpiresAt(stream.Context())
+	if !ok || expiresAt.IsZero() {
+		return status.Error(codes.Unauthenticated, "Access token has no expiry")
+	}
+	expire := time.NewTimer(time.Until(expiresAt))
+	defer expire.Stop()
+
+	sub := realtime.DefaultHub.Subscribe(userID)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-expire.C:
+			return status.Error(codes.Unauthenticated, "Access token expired")
+		case event, ok := <-sub.C:
+			if !ok {
+				// 送信が追いつかずに購読が打ち切られた。クライアントは再接続して一覧を取り直す
+				return status.Error(codes.Unavailable, "Event stream was closed because the client is too slow")
+			}
+			var todo models.Todo
+			if err := json.Unmarshal([]byte(event.Data), &todo); err != nil {
+				return err
+			}
+			if err := stream.Send(&todov1.TodoEvent{
+				Id:        uint32(event.ID),
+				Event:     event.Event,
+				TodoId:    uint32(event.TodoID),
+				Todo:      toProtoTodo(&todo),
+				CreatedAt: toProtoTimestamp(&event.CreatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
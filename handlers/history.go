@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// RevertTodoRequest リバートリクエスト
+type RevertTodoRequest struct {
+	RevisionID uint `json:"revision_id" binding:"required"`
+}
+
+// TodoHistoryResponse 変更履歴レスポンス
+type TodoHistoryResponse struct {
+	Items      []models.TodoRevision `json:"items"`
+	NextCursor *string               `json:"next_cursor"`
+}
+
+// historySort 変更履歴の並び順（新しい順）
+const historySort = "-id"
+
+// loadTodoLabelIDs todoごとのラベルIDを取得します
+func loadTodoLabelIDs(tx *gorm.DB, todoIDs []uint) (map[uint][]uint, error) {
+	var rows []struct {
+		TodoID  uint
+		LabelID uint
+	}
+	if err := tx.Table("todo_labels").
+		Select("todo_id, label_id").
+		Where("todo_id IN ?", todoIDs).
+		Order("label_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	labelIDs := make(map[uint][]uint, len(todoIDs))
+	for _, row := range rows {
+		labelIDs[row.TodoID] = append(labelIDs[row.TodoID], row.LabelID)
+	}
+	return labelIDs, nil
+}
+
+// snapshotTodo 変更履歴に保存するtodoの状態を作成します
+func snapshotTodo(todo *models.Todo, labelIDs []uint) models.TodoSnapshot {
+	ids := append([]uint{}, labelIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return models.TodoSnapshot{
+		Title:     todo.Title,
+		Completed: todo.Completed,
+		ProjectID: todo.ProjectID,
+		ParentID:  todo.ParentID,
+		StartAt:   todo.StartAt,
+		DueAt:     todo.DueAt,
+		RRule:     todo.RRule,
+		Priority:  todo.Priority,
+		LabelIDs:  ids,
+	}
+}
+
+// loadSnapshot todoの現在の状態をラベルも含めて取得します
+func loadSnapshot(tx *gorm.DB, todo *models.Todo) (models.TodoSnapshot, error) {
+	labelIDs, err := loadTodoLabelIDs(tx, []uint{todo.ID})
+	if err != nil {
+		return models.TodoSnapshot{}, err
+	}
+	return snapshotTodo(todo, labelIDs[todo.ID]), nil
+}
+
+// snapshotFields スナップショットをフィールド名ごとの値に変換します
+func snapshotFields(snapshot *models.TodoSnapshot) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if snapshot == nil {
+		return fields
+	}
+	b, _ := json.Marshal(snapshot)
+	json.Unmarshal(b, &fields)
+	return fields
+}
+
+// diffSnapshots 変更前後の状態から変更のあったフィールドを取り出します（beforeがnilなら作成）
+func diffSnapshots(before *models.TodoSnapshot, after models.TodoSnapshot) models.TodoChanges {
+	from := snapshotFields(before)
+	to := snapshotFields(&after)
+
+	changes := make(models.TodoChanges)
+	for field, value := range to {
+		if prev, ok := from[field]; !ok || !reflect.DeepEqual(prev, value) {
+			changes[field] = models.FieldChange{From: from[field], To: value}
+		}
+	}
+	return changes
+}
+
+// recordRevision 変更前後の状態から変更履歴を記録します（変更がなければ記録しない）
+func recordRevision(tx *gorm.DB, todoID, actorID uint, action string, before *models.TodoSnapshot, after models.TodoSnapshot, revertedFromID *uint) error {
+	changes := diffSnapshots(before, after)
+	if len(changes) == 0 {
+		return nil
+	}
+	return tx.Create(&models.TodoRevision{
+		TodoID:         todoID,
+		ActorID:        actorID,
+		Action:         action,
+		Changes:        changes,
+		Snapshot:       after,
+		RevertedFromID: revertedFromID,
+	}).Error
+}
+
+// recordTrashRevisions ゴミ箱への移動・復元の変更履歴をまとめて記録します
+// 削除の場合のスナップショットは削除前の状態です
+func recordTrashRevisions(tx *gorm.DB, todos []models.Todo, actorID uint, action string, deletedAt time.Time) error {
+	if len(todos) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(todos))
+	for i := range todos {
+		ids[i] = todos[i].ID
+	}
+	labelIDs, err := loadTodoLabelIDs(tx, ids)
+	if err != nil {
+		return err
+	}
+
+	change := models.FieldChange{From: nil, To: deletedAt}
+	if action == models.RevisionActionRestore {
+		change = models.FieldChange{From: deletedAt, To: nil}
+	}
+
+	revisions := make([]models.TodoRevision, len(todos))
+	for i := range todos {
+		revisions[i] = models.TodoRevision{
+			TodoID:   todos[i].ID,
+			ActorID:  actorID,
+			Action:   action,
+			Changes:  models.TodoChanges{"deleted_at": change},
+			Snapshot: snapshotTodo(&todos[i], labelIDs[todos[i].ID]),
+		}
+	}
+	return tx.Create(&revisions).Error
+}
+
+// findTodoIncludingTrash ゴミ箱にあるものも含めてtodoを取得して閲覧権限を確認し、なければエラーレスポンスを返します
+func findTodoIncludingTrash(c *gin.Context, userID uint) (*models.Todo, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid todo ID")
+		return nil, false
+	}
+
+	var todo models.Todo
+	if err := database.DB.Unscoped().First(&todo, id).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Todo not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	if err := checkTodoRole(database.DB, userID, &todo, services.RoleViewer); err != nil {
+		utils.RespondAPIError(c, err)
+		return nil, false
+	}
+
+	return &todo, true
+}
+
+// GetTodoHistory todoの変更履歴を新しい順に取得（ゴミ箱のtodoも可）
+func GetTodoHistory(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	todo, ok := findTodoIncludingTrash(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	query := database.DB.Where("todo_id = ?", todo.ID)
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := utils.DecodeCursor(cursorStr)
+		if err != nil || cursor.Sort != historySort {
+			utils.RespondBadRequest(c, "Invalid cursor")
+			return
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+
+	// 次ページの有無を判定するため1件多く取得
+	var revisions []models.TodoRevision
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&revisions).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	resp := TodoHistoryResponse{Items: revisions}
+	if len(revisions) > limit {
+		resp.Items = revisions[:limit]
+		last := resp.Items[limit-1]
+		next := utils.EncodeCursor(utils.Cursor{
+			Sort:  historySort,
+			Value: strconv.FormatUint(uint64(last.ID), 10),
+			ID:    last.ID,
+		})
+		resp.NextCursor = &next
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// existingLabelIDs 削除済みのラベルを除いたIDを順序を保って返します
+func existingLabelIDs(tx *gorm.DB, ids []uint) ([]uint, error) {
+	result := []uint{}
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var found []uint
+	if err := tx.Model(&models.Label{}).Where("id IN ?", ids).Pluck("id", &found).Error; err != nil {
+		return nil, err
+	}
+	exists := make(map[uint]bool, len(found))
+	for _, id := range found {
+		exists[id] = true
+	}
+	for _, id := range ids {
+		if exists[id] {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+// RevertTodo todoを変更履歴の時点の状態に戻す（リバート自体も変更履歴に記録されます）
+// 変更履歴の時点から削除されたラベルは付け直しません
+func RevertTodo(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid todo ID")
+		return
+	}
+
+	var req RevertTodoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	var todo *models.Todo
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var revision models.TodoRevision
+		if err := tx.Where("id = ? AND todo_id = ?", req.RevisionID, id).First(&revision).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return utils.NewNotFoundError("Revision not found")
+			}
+			return err
+		}
+
+		snapshot := revision.Snapshot
+		labelIDs, err := existingLabelIDs(tx, snapshot.LabelIDs)
+		if err != nil {
+			return err
+		}
+		update := UpdateTodoRequest{
+			Title:     &snapshot.Title,
+			Completed: &snapshot.Completed,
+			ProjectID: utils.Optional[uint]{Set: true, Value: snapshot.ProjectID},
+			ParentID:  utils.Optional[uint]{Set: true, Value: snapshot.ParentID},
+			StartAt:   utils.Optional[time.Time]{Set: true, Value: snapshot.StartAt},
+			DueAt:     utils.Optional[time.Time]{Set: true, Value: snapshot.DueAt},
+			RRule:     utils.Optional[string]{Set: true, Value: snapshot.RRule},
+			Priority:  utils.Optional[string]{Set: true, Value: snapshot.Priority},
+			LabelIDs:  &labelIDs,
+		}
+
+		todo, err = applyTodoUpdate(tx, userID.(uint), uint(id), update, c.GetHeader("If-Match"), &revision.ID)
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.Header("ETag", todo.ETag)
+	c.JSON(http.StatusOK, todo)
+}
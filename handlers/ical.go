@@ -0,0 +1,518 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// icalUIDDomain エクスポートするVTODOのUIDのドメイン部分
+const icalUIDDomain = "go-gin-todo-api"
+
+// maxICSImportSize インポートするiCalendarファイルの最大サイズ
+const maxICSImportSize = 5 << 20
+
+// defaultICalUIDPattern このAPIがエクスポートしたVTODOのUID（todo-<id>@go-gin-todo-api）
+var defaultICalUIDPattern = regexp.MustCompile(`^todo-(\d+)@` + regexp.QuoteMeta(icalUIDDomain) + `$`)
+
+// インポート結果の状態
+const (
+	importStatusCreated = "created"
+	importStatusUpdated = "updated"
+	importStatusSkipped = "skipped"
+	importStatusFailed  = "failed"
+)
+
+// ImportICSResult VTODOごとのインポート結果
+type ImportICSResult struct {
+	Index  int                `json:"index"`
+	Line   int                `json:"line"`
+	UID    string             `json:"uid,omitempty"`
+	Status string             `json:"status"`
+	TodoID *uint              `json:"todo_id,omitempty"`
+	Reason string             `json:"reason,omitempty"`
+	Error  *utils.ErrorDetail `json:"error,omitempty"`
+}
+
+// ImportICSResponse iCalendarインポートのレスポンス
+type ImportICSResponse struct {
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Skipped int               `json:"skipped"`
+	Failed  int               `json:"failed"`
+	Results []ImportICSResult `json:"results"`
+}
+
+// CalendarTokenResponse iCalendar購読URLのレスポンス（トークンは発行時にしか返さない）
+type CalendarTokenResponse struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// todoICalUID todoのVTODOのUIDを返します（インポートしたtodoは元のUIDを維持する）
+func todoICalUID(todo *models.Todo) string {
+	if todo.ICalUID != nil {
+		return *todo.ICalUID
+	}
+	return fmt.Sprintf("todo-%d@%s", todo.ID, icalUIDDomain)
+}
+
+// writeTodosICS todoをVTODOとしてiCalendar形式で書き出します
+func writeTodosICS(w io.Writer, todos []models.Todo, parentUIDs map[uint]string) error {
+	ical := utils.NewICalWriter(w)
+	ical.Line("BEGIN", "VCALENDAR")
+	ical.Line("VERSION", "2.0")
+	ical.Line("PRODID", "-//"+icalUIDDomain+"//Todos//JA")
+	ical.Line("CALSCALE", "GREGORIAN")
+	ical.Text("X-WR-CALNAME", "Todos")
+
+	for i := range todos {
+		todo := &todos[i]
+		ical.Line("BEGIN", "VTODO")
+		ical.Text("UID", todoICalUID(todo))
+		ical.Time("DTSTAMP", todo.UpdatedAt)
+		ical.Time("CREATED", todo.CreatedAt)
+		ical.Time("LAST-MODIFIED", todo.UpdatedAt)
+		ical.Line("SEQUENCE", strconv.FormatUint(uint64(todo.Version-1), 10))
+		ical.Text("SUMMARY", todo.Title)
+		if todo.Completed {
+			ical.Line("STATUS", "COMPLETED")
+		} else {
+			ical.Line("STATUS", "NEEDS-ACTION")
+		}
+		if todo.StartAt != nil {
+			ical.Time("DTSTART", *todo.StartAt)
+		}
+		if todo.DueAt != nil {
+			ical.Time("DUE", *todo.DueAt)
+		}
+		if todo.RRule != nil {
+			// RRULEにはDTSTARTが必要なため、開始日時がなければ期限を起点にする
+			if todo.StartAt == nil && todo.DueAt != nil {
+				ical.Time("DTSTART", *todo.DueAt)
+			}
+			ical.Line("RRULE", *todo.RRule)
+		}
+		if len(todo.Labels) > 0 {
+			names := make([]string, len(todo.Labels))
+			for j, label := range todo.Labels {
+				names[j] = utils.EscapeICalText(label.Name)
+			}
+			ical.Line("CATEGORIES", strings.Join(names, ","))
+		}
+		if todo.ParentID != nil {
+			if uid, ok := parentUIDs[*todo.ParentID]; ok {
+				ical.Text("RELATED-TO", uid)
+			}
+		}
+		ical.Line("END", "VTODO")
+	}
+
+	ical.Line("END", "VCALENDAR")
+	return ical.Err()
+}
+
+// respondTodosICS ユーザーが閲覧できるtodoをiCalendar形式で返します（一覧取得と同じフィルタが使えます）
+func respondTodosICS(c *gin.Context, userID uint) {
+	query, err := applyTodoFilters(database.DB.Scopes(services.VisibleTodos(userID)), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	var todos []models.Todo
+	if err := excludeArchivedProjects(query, c).Preload("Labels").Order("created_at ASC, id ASC").Find(&todos).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	parentIDs := make([]uint, 0)
+	for _, todo := range todos {
+		if todo.ParentID != nil {
+			parentIDs = append(parentIDs, *todo.ParentID)
+		}
+	}
+	parentUIDs := make(map[uint]string, len(parentIDs))
+	if len(parentIDs) > 0 {
+		var parents []models.Todo
+		if err := database.DB.Select("id", "ical_uid").Where("id IN ?", parentIDs).Find(&parents).Error; err != nil {
+			statusCode, message := utils.HandleDBError(err)
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+			return
+		}
+		for i := range parents {
+			parentUIDs[parents[i].ID] = todoICalUID(&parents[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeTodosICS(&buf, todos, parentUIDs); err != nil {
+		utils.RespondInternalError(c, "Failed to write calendar")
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="todos.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// ExportTodosICS 自分のtodoと共有されたtodoをiCalendar（VTODO）形式でエクスポート
+func ExportTodosICS(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	respondTodosICS(c, userID.(uint))
+}
+
+// GetCalendarFeed 購読URLのトークンでユーザーを特定してiCalendarを返す（カレンダーアプリ向け、JWT不要）
+func GetCalendarFeed(c *gin.Context) {
+	var user models.User
+	if err := database.DB.Where("calendar_token_hash = ?", utils.HashRefreshToken(c.Param("token"))).First(&user).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Calendar not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return
+	}
+
+	respondTodosICS(c, user.ID)
+}
+
+// CreateCalendarToken iCalendar購読URLを発行（既存のURLは無効になります）
+func CreateCalendarToken(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	// リフレッシュトークンと同じく256ビットの乱数を使い、DBにはハッシュだけを保存する
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		utils.RespondInternalError(c, "Failed to generate token")
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("calendar_token_hash", utils.HashRefreshToken(token)).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	c.JSON(http.StatusCreated, CalendarTokenResponse{
+		Token: token,
+		URL:   fmt.Sprintf("%s://%s/feeds/%s/todos.ics", scheme, c.Request.Host, token),
+	})
+}
+
+// DeleteCalendarToken iCalendar購読URLを無効化
+func DeleteCalendarToken(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("calendar_token_hash", nil).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// readImportFile multipart/form-dataのfileフィールド、またはリクエスト本文をインポートするファイルとして読み込みます
+func readImportFile(c *gin.Context, maxSize int64) ([]byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSize+1<<20)
+
+	var r io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				return nil, utils.NewPayloadTooLargeError(fmt.Sprintf("File must not exceed %d bytes", maxSize))
+			}
+			return nil, utils.NewBadRequestError("file is required")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, utils.NewBadRequestError("Failed to read file")
+		}
+		defer file.Close()
+		r = file
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, utils.NewPayloadTooLargeError(fmt.Sprintf("File must not exceed %d bytes", maxSize))
+		}
+		return nil, utils.NewBadRequestError("Failed to read file")
+	}
+	if int64(len(data)) > maxSize {
+		return nil, utils.NewPayloadTooLargeError(fmt.Sprintf("File must not exceed %d bytes", maxSize))
+	}
+	if len(data) == 0 {
+		return nil, utils.NewBadRequestError("file is empty")
+	}
+	return data, nil
+}
+
+// icalTodo VTODOから読み取ったtodoの内容
+type icalTodo struct {
+	UID       string
+	Title     string
+	Completed bool
+	Cancelled bool
+	StartAt   *time.Time
+	DueAt     *time.Time
+}
+
+// parseVTodo VTODOのSUMMARY / STATUS / DTSTART / DUEを読み取ります
+// 日付のみの値やタイムゾーンのない時刻はユーザーのタイムゾーンで解釈します
+func parseVTodo(component *utils.ICalComponent, loc *time.Location) (*icalTodo, error) {
+	todo := &icalTodo{}
+	if prop, ok := component.Get("UID"); ok {
+		todo.UID = strings.TrimSpace(utils.UnescapeICalText(prop.Value))
+	}
+
+	prop, ok := component.Get("SUMMARY")
+	if ok {
+		todo.Title = strings.TrimSpace(utils.UnescapeICalText(prop.Value))
+	}
+	if todo.Title == "" {
+		return nil, utils.NewBadRequestError("SUMMARY is required")
+	}
+
+	if prop, ok := component.Get("STATUS"); ok {
+		switch strings.ToUpper(strings.TrimSpace(prop.Value)) {
+		case "COMPLETED":
+			todo.Completed = true
+		case "CANCELLED":
+			todo.Cancelled = true
+		}
+	} else if _, ok := component.Get("COMPLETED"); ok {
+		todo.Completed = true
+	}
+
+	for _, field := range []struct {
+		name string
+		dest **time.Time
+	}{
+		{"DTSTART", &todo.StartAt},
+		{"DUE", &todo.DueAt},
+	} {
+		prop, ok := component.Get(field.name)
+		if !ok {
+			continue
+		}
+		t, err := utils.ParseICalTime(prop, loc)
+		if err != nil {
+			return nil, utils.NewBadRequestError(fmt.Sprintf("%s: %v", field.name, err))
+		}
+		*field.dest = &t
+	}
+	return todo, nil
+}
+
+// findImportTarget UIDに対応する既存のtodoを探します（見つからなければnil）
+// このAPIがエクスポートしたUIDはtodoのID、それ以外はインポート時に保存したUIDで照合します
+func findImportTarget(tx *gorm.DB, userID uint, uid string) (*models.Todo, error) {
+	if uid == "" {
+		return nil, nil
+	}
+
+	if match := defaultICalUIDPattern.FindStringSubmatch(uid); match != nil {
+		id, _ := strconv.ParseUint(match[1], 10, 32)
+		var todo models.Todo
+		err := tx.Unscoped().First(&todo, id).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		if err == nil {
+			role, err := services.TodoRole(tx, userID, &todo)
+			if err != nil {
+				return nil, err
+			}
+			if role != services.RoleNone {
+				return &todo, nil
+			}
+		}
+		// 別のサーバー・ユーザーがエクスポートしたものは新しいtodoとして扱う
+	}
+
+	var todo models.Todo
+	err := tx.Unscoped().Where("user_id = ? AND ical_uid = ?", userID, uid).First(&todo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// importVTodo 1件のVTODOをtodoとして作成・更新します
+func importVTodo(tx *gorm.DB, userID uint, item *icalTodo, result *ImportICSResult) error {
+	existing, err := findImportTarget(tx, userID, item.UID)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil && existing.DeletedAt.Valid {
+		result.Status = importStatusSkipped
+		result.TodoID = &existing.ID
+		result.Reason = "todo is in trash"
+		return nil
+	}
+	if item.Cancelled {
+		result.Status = importStatusSkipped
+		result.Reason = "cancelled todos are not imported"
+		return nil
+	}
+
+	if existing != nil {
+		todo, err := updateTodo(tx, userID, existing.ID, UpdateTodoRequest{
+			Title:     &item.Title,
+			Completed: &item.Completed,
+			StartAt:   utils.Optional[time.Time]{Set: true, Value: item.StartAt},
+			DueAt:     utils.Optional[time.Time]{Set: true, Value: item.DueAt},
+		}, "")
+		if err != nil {
+			return err
+		}
+		result.Status = importStatusUpdated
+		result.TodoID = &todo.ID
+		return nil
+	}
+
+	todo, err := createTodo(tx, userID, CreateTodoRequest{
+		Title:   item.Title,
+		StartAt: item.StartAt,
+		DueAt:   item.DueAt,
+	})
+	if err != nil {
+		return err
+	}
+	if item.UID != "" && !defaultICalUIDPattern.MatchString(item.UID) {
+		// 更新日時やバージョンを変えないようフックを通さずに保存する
+		if err := tx.Model(todo).UpdateColumn("ical_uid", item.UID).Error; err != nil {
+			return err
+		}
+	}
+	if item.Completed {
+		if _, err := updateTodo(tx, userID, todo.ID, UpdateTodoRequest{Completed: &item.Completed}, ""); err != nil {
+			return err
+		}
+	}
+	result.Status = importStatusCreated
+	result.TodoID = &todo.ID
+	return nil
+}
+
+// ImportTodosICS iCalendarファイルのVTODOをtodoとしてインポート
+// UIDが一致するtodoがあれば更新し、なければ作成します。失敗したVTODOだけを取り消し、残りは取り込みます
+func ImportTodosICS(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	data, err := readImportFile(c, maxICSImportSize)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	components, err := utils.ParseICal(bytes.NewReader(data))
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid iCalendar: "+err.Error())
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	loc, err := utils.LoadLocation(user.Timezone)
+	if err != nil {
+		loc, _ = utils.LoadLocation(utils.DefaultTimezone)
+	}
+
+	resp := ImportICSResponse{Results: []ImportICSResult{}}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		index := 0
+		for i := range components {
+			if components[i].Name != "VTODO" {
+				continue
+			}
+			result := ImportICSResult{Index: index, Line: components[i].Line}
+			index++
+
+			// 各VTODOはセーブポイント内で取り込み、失敗したものだけを巻き戻す
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				item, err := parseVTodo(&components[i], loc)
+				if err != nil {
+					return err
+				}
+				result.UID = item.UID
+				return importVTodo(sp, userID.(uint), item, &result)
+			})
+			if err != nil {
+				detail := utils.ToAPIError(err).Detail()
+				result.Status = importStatusFailed
+				result.TodoID = nil
+				result.Error = &detail
+			}
+
+			switch result.Status {
+			case importStatusCreated:
+				resp.Created++
+			case importStatusUpdated:
+				resp.Updated++
+			case importStatusSkipped:
+				resp.Skipped++
+			case importStatusFailed:
+				resp.Failed++
+			}
+			resp.Results = append(resp.Results, result)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
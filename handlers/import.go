@@ -0,0 +1,636 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/importer"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// importBatchSize 1トランザクションで取り込むtodoの件数（進捗はバッチごとに保存される）
+	importBatchSize = 100
+	// importLease ジョブを占有する期間（この間に処理が進まなければ、停止したとみなして他のプロセスが引き継ぐ）
+	importLease = 5 * time.Minute
+	// maxImportCommentLength 取り込むコメントの最大文字数（コメントAPIと同じ）
+	maxImportCommentLength = 10000
+	// importSort インポート一覧の並び順（新しい順）
+	importSort = "-id"
+	// importIssueSort レポートの並び順（古い順）
+	importIssueSort = "id"
+)
+
+// importMaxSize 取り込むファイルの最大サイズ
+func importMaxSize() int64 {
+	return envMegabytes("IMPORT_MAX_SIZE_MB", 20)
+}
+
+// ImportJobListResponse インポートジョブ一覧レスポンス
+type ImportJobListResponse struct {
+	Items      []models.ImportJob `json:"items"`
+	NextCursor *string            `json:"next_cursor"`
+}
+
+// ImportReportResponse 対応付けられなかった項目の一覧レスポンス
+type ImportReportResponse struct {
+	Items      []models.ImportIssue `json:"items"`
+	NextCursor *string              `json:"next_cursor"`
+}
+
+// importFilename アップロードされたファイルの名前を返します（本文で送られた場合は空）
+func importFilename(c *gin.Context) string {
+	if form := c.Request.MultipartForm; form != nil && len(form.File["file"]) > 0 {
+		return sanitizeFilename(form.File["file"][0].Filename)
+	}
+	return ""
+}
+
+// importParam multipartのフィールド、なければクエリパラメータの値を返します
+func importParam(c *gin.Context, name string) string {
+	if value := c.PostForm(name); value != "" {
+		return value
+	}
+	return c.Query(name)
+}
+
+// CreateImportJob TodoistまたはTrelloのエクスポートファイルを受け付け、バックグラウンドで取り込む
+// multipart/form-dataのfile・source（todoist / trello）・format（json / csv、省略時はファイルから判定）・project_nameを指定します
+func CreateImportJob(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	data, err := readImportFile(c, importMaxSize())
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	source := strings.ToLower(importParam(c, "source"))
+	if source != importer.SourceTodoist && source != importer.SourceTrello {
+		utils.RespondBadRequest(c, "source must be todoist or trello")
+		return
+	}
+	filename := importFilename(c)
+	format := strings.ToLower(importParam(c, "format"))
+	if format == "" {
+		format = importer.DetectFormat(filename, data)
+	}
+	if format != importer.FormatJSON && format != importer.FormatCSV {
+		utils.RespondBadRequest(c, "format must be json or csv")
+		return
+	}
+	if filename == "" {
+		filename = source + "." + format
+	}
+	projectName := strings.TrimSpace(importParam(c, "project_name"))
+	if projectName == "" && source == importer.SourceTodoist && format == importer.FormatCSV {
+		// TodoistのCSVはプロジェクトごとのファイルなので、ファイル名をプロジェクト名にする
+		projectName = strings.TrimSuffix(filename, ".csv")
+	}
+
+	var user models.User
+	if err := database.DB.Select("timezone").First(&user, userID).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	loc, err := utils.LoadLocation(user.Timezone)
+	if err != nil {
+		loc, _ = utils.LoadLocation(utils.DefaultTimezone)
+	}
+
+	// 形式の誤りはジョブを作る前に返す
+	plan, err := importer.Parse(source, format, data, importer.Options{ProjectName: projectName, Location: loc})
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	job := models.ImportJob{
+		UserID:      userID.(uint),
+		Source:      source,
+		Format:      format,
+		Filename:    filename,
+		ProjectName: projectName,
+		Timezone:    loc.String(),
+		Payload:     data,
+		Status:      models.ImportStatusPending,
+		Total:       len(plan.Tasks),
+	}
+	if err := database.DB.Create(&job).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/imports/%d", job.ID))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// findImportJob URLの自分のインポートジョブを取得し、なければエラーレスポンスを返します
+func findImportJob(c *gin.Context, userID interface{}) (*models.ImportJob, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid import ID")
+		return nil, false
+	}
+
+	var job models.ImportJob
+	if err := database.DB.Omit("payload").Where("id = ? AND user_id = ?", id, userID).First(&job).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Import not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	return &job, true
+}
+
+// GetImportJobs 自分のインポートジョブ一覧を取得（新しい順）
+func GetImportJobs(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	query := database.DB.Omit("payload").Where("user_id = ?", userID)
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := utils.DecodeCursor(cursorStr)
+		if err != nil || cursor.Sort != importSort {
+			utils.RespondBadRequest(c, "Invalid cursor")
+			return
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+
+	// 次ページの有無を判定するため1件多く取得
+	var jobs []models.ImportJob
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&jobs).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	var next *string
+	if len(jobs) > limit {
+		jobs = jobs[:limit]
+		last := jobs[limit-1]
+		cursor := utils.EncodeCursor(utils.Cursor{
+			Sort:  importSort,
+			Value: strconv.FormatUint(uint64(last.ID), 10),
+			ID:    last.ID,
+		})
+		next = &cursor
+	}
+
+	c.JSON(http.StatusOK, ImportJobListResponse{Items: jobs, NextCursor: next})
+}
+
+// GetImportJob インポートジョブの進捗を取得（processed / totalが処理済み・全体のtodo数）
+func GetImportJob(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	job, ok := findImportJob(c, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// GetImportReport インポートで対応付けられなかった項目の一覧を取得
+func GetImportReport(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	job, ok := findImportJob(c, userID)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Where("job_id = ?", job.ID)
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := utils.DecodeCursor(cursorStr)
+		if err != nil || cursor.Sort != importIssueSort {
+			utils.RespondBadRequest(c, "Invalid cursor")
+			return
+		}
+		query = query.Where("id > ?", cursor.ID)
+	}
+
+	var issues []models.ImportIssue
+	if err := query.Order("id ASC").Limit(limit + 1).Find(&issues).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	var next *string
+	if len(issues) > limit {
+		issues = issues[:limit]
+		last := issues[limit-1]
+		cursor := utils.EncodeCursor(utils.Cursor{
+			Sort:  importIssueSort,
+			Value: strconv.FormatUint(uint64(last.ID), 10),
+			ID:    last.ID,
+		})
+		next = &cursor
+	}
+
+	c.JSON(http.StatusOK, ImportReportResponse{Items: issues, NextCursor: next})
+}
+
+// ResumeImportJob 失敗したインポートジョブを処理済みの位置から再開
+func ResumeImportJob(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	job, ok := findImportJob(c, userID)
+	if !ok {
+		return
+	}
+	if job.Status != models.ImportStatusFailed {
+		utils.RespondConflict(c, "Only failed imports can be resumed")
+		return
+	}
+
+	if err := database.DB.Model(job).Updates(map[string]interface{}{
+		"status":       models.ImportStatusPending,
+		"error":        nil,
+		"locked_until": nil,
+	}).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	job.Status = models.ImportStatusPending
+	job.Error = nil
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// claimImportJob 待機中のジョブ、または占有期間が切れた処理中のジョブを1件占有します（なければnil）
+func claimImportJob() (*models.ImportJob, error) {
+	var job models.ImportJob
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 複数のプロセスで動かしても同じジョブを取り合わないよう、ロック中の行は飛ばす
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND (locked_until IS NULL OR locked_until < ?)",
+				[]string{models.ImportStatusPending, models.ImportStatusRunning}, time.Now()).
+			Order("id ASC").First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		lease := newImportLease()
+		updates := map[string]interface{}{
+			"status":       models.ImportStatusRunning,
+			"locked_until": lease,
+		}
+		if job.StartedAt == nil {
+			now := time.Now()
+			updates["started_at"] = now
+			job.StartedAt = &now
+		}
+		if err := tx.Model(&job).Updates(updates).Error; err != nil {
+			return err
+		}
+		job.Status = models.ImportStatusRunning
+		job.LockedUntil = &lease
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// newImportLease 占有期間の終わりを返します（DBと同じマイクロ秒単位にして、自分の占有かどうかを比較できるようにする）
+func newImportLease() time.Time {
+	return time.Now().Add(importLease).Truncate(time.Microsecond)
+}
+
+// errImportLeaseLost 占有期間が切れ、他のプロセスにジョブが引き継がれた場合のエラー
+var errImportLeaseLost = errors.New("import job was taken over by another worker")
+
+// RunNextImportJob 待機中のインポートジョブを1件取り込みます（処理したジョブがなければfalse）
+// バックグラウンドジョブから繰り返し呼び出されます
+func RunNextImportJob() (bool, error) {
+	job, err := claimImportJob()
+	if err != nil || job == nil {
+		return false, err
+	}
+
+	if err := runImportJob(job); err != nil {
+		if errors.Is(err, errImportLeaseLost) {
+			return true, err
+		}
+		message := err.Error()
+		if updateErr := database.DB.Model(job).Where("locked_until = ?", job.LockedUntil).Updates(map[string]interface{}{
+			"status":       models.ImportStatusFailed,
+			"error":        message,
+			"locked_until": nil,
+		}).Error; updateErr != nil {
+			log.Printf("Failed to mark import job %d as failed: %v", job.ID, updateErr)
+		}
+		return true, fmt.Errorf("import job %d failed: %w", job.ID, err)
+	}
+	return true, nil
+}
+
+// runImportJob ファイルを読み取り直し、処理済みの位置からバッチごとに取り込みます
+func runImportJob(job *models.ImportJob) error {
+	loc, err := utils.LoadLocation(job.Timezone)
+	if err != nil {
+		loc, _ = utils.LoadLocation(utils.DefaultTimezone)
+	}
+	plan, err := importer.Parse(job.Source, job.Format, job.Payload, importer.Options{ProjectName: job.ProjectName, Location: loc})
+	if err != nil {
+		return err
+	}
+
+	projects := make(map[string]importer.Project, len(plan.Projects))
+	for _, project := range plan.Projects {
+		projects[project.Ref] = project
+	}
+
+	// 最初の実行では読み取り時点で対応付けられなかった項目をレポートに記録する
+	if job.State.Todos == nil {
+		job.State = models.ImportState{Projects: map[string]uint{}, Labels: map[string]uint{}, Todos: map[string]uint{}}
+		issues := make([]models.ImportIssue, len(plan.Issues))
+		for i, issue := range plan.Issues {
+			issues[i] = models.ImportIssue{JobID: job.ID, Ref: issue.Ref, Title: issue.Title, Message: issue.Message}
+		}
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if len(issues) > 0 {
+				if err := tx.CreateInBatches(issues, importBatchSize).Error; err != nil {
+					return err
+				}
+			}
+			job.Total = len(plan.Tasks)
+			job.IssueCount = len(issues)
+			return saveImportProgress(tx, job)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for job.Processed < len(plan.Tasks) {
+		end := min(job.Processed+importBatchSize, len(plan.Tasks))
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			var issues []models.ImportIssue
+			for _, task := range plan.Tasks[job.Processed:end] {
+				taskIssues, err := importPlanTask(tx, job, projects, &task)
+				if err != nil {
+					return err
+				}
+				issues = append(issues, taskIssues...)
+			}
+			if len(issues) > 0 {
+				if err := tx.Create(&issues).Error; err != nil {
+					return err
+				}
+			}
+			job.Processed = end
+			job.IssueCount += len(issues)
+			return saveImportProgress(tx, job)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// 取り込み元でアーカイブされていたプロジェクトは、todoを追加し終えてからアーカイブする
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		var archived []uint
+		for _, project := range plan.Projects {
+			if id, ok := job.State.Projects[project.Ref]; ok && project.Archived {
+				archived = append(archived, id)
+			}
+		}
+		if len(archived) > 0 {
+			if err := tx.Model(&models.Project{}).Where("id IN ? AND archived_at IS NULL", archived).
+				Update("archived_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Model(job).Where("locked_until = ?", job.LockedUntil).Updates(map[string]interface{}{
+			"status":       models.ImportStatusCompleted,
+			"payload":      nil,
+			"locked_until": nil,
+			"finished_at":  time.Now(),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errImportLeaseLost
+		}
+		return nil
+	})
+}
+
+// saveImportProgress 進捗と対応表を保存し、占有期間を延長します（他のプロセスに引き継がれていればエラー）
+func saveImportProgress(tx *gorm.DB, job *models.ImportJob) error {
+	lease := newImportLease()
+	result := tx.Model(job).Where("locked_until = ?", job.LockedUntil).Updates(map[string]interface{}{
+		"total":        job.Total,
+		"processed":    job.Processed,
+		"imported":     job.Imported,
+		"issue_count":  job.IssueCount,
+		"state":        job.State,
+		"locked_until": lease,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errImportLeaseLost
+	}
+	job.LockedUntil = &lease
+	return nil
+}
+
+// importProjectID 取り込み元のプロジェクトに対応するプロジェクトを返します（初めてなら作成）
+func importProjectID(tx *gorm.DB, job *models.ImportJob, projects map[string]importer.Project, ref string) (*uint, error) {
+	if id, ok := job.State.Projects[ref]; ok {
+		return &id, nil
+	}
+	source, ok := projects[ref]
+	if !ok {
+		return nil, nil
+	}
+
+	name := strings.TrimSpace(source.Name)
+	if name == "" {
+		name = "Imported"
+	}
+	project := models.Project{UserID: job.UserID, Name: name}
+	if err := tx.Create(&project).Error; err != nil {
+		return nil, err
+	}
+	job.State.Projects[ref] = project.ID
+	return &project.ID, nil
+}
+
+// importLabelIDs ラベル名に対応する自分のラベルを返します（同じ名前のラベルがなければ作成）
+func importLabelIDs(tx *gorm.DB, job *models.ImportJob, names []string) ([]uint, error) {
+	ids := make([]uint, 0, len(names))
+	seen := make(map[uint]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := job.State.Labels[name]
+		if !ok {
+			label := models.Label{UserID: job.UserID, Name: name}
+			if err := tx.Where("user_id = ? AND name = ?", job.UserID, name).FirstOrCreate(&label).Error; err != nil {
+				return nil, err
+			}
+			id = label.ID
+			job.State.Labels[name] = id
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// importPlanTask 1件のtodoを取り込み、対応付けられなかった項目を返します
+// todoの作成に失敗した場合はそのtodoだけを取り消してレポートに記録し、DBエラーの場合はエラーを返します
+func importPlanTask(tx *gorm.DB, job *models.ImportJob, projects map[string]importer.Project, task *importer.Task) ([]models.ImportIssue, error) {
+	var issues []models.ImportIssue
+	addIssue := func(message string) {
+		issues = append(issues, models.ImportIssue{JobID: job.ID, Ref: task.Ref, Title: task.Title, Message: message})
+	}
+
+	projectID, err := importProjectID(tx, job, projects, task.ProjectRef)
+	if err != nil {
+		return nil, err
+	}
+	if projectID == nil && task.ProjectRef != "" {
+		addIssue("project not found; imported without a project")
+	}
+	labelIDs, err := importLabelIDs(tx, job, task.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	req := CreateTodoRequest{
+		Title:     strings.TrimSpace(task.Title),
+		ProjectID: projectID,
+		StartAt:   task.StartAt,
+		DueAt:     task.DueAt,
+		LabelIDs:  labelIDs,
+	}
+	if req.Title == "" {
+		req.Title = "(untitled)"
+		addIssue("empty title replaced with (untitled)")
+	}
+	if task.Priority != "" {
+		priority := task.Priority
+		req.Priority = &priority
+	}
+	if task.ParentRef != "" {
+		if parentID, ok := job.State.Todos[task.ParentRef]; ok {
+			req.ParentID = &parentID
+		} else {
+			addIssue("parent was not imported; imported as a top-level todo")
+		}
+	}
+	if req.StartAt != nil && req.DueAt != nil && req.StartAt.After(*req.DueAt) {
+		req.StartAt = nil
+		addIssue("start date after due date not imported")
+	}
+
+	var todoID uint
+	err = tx.Transaction(func(sp *gorm.DB) error {
+		todo, err := createTodo(sp, job.UserID, req)
+		if err != nil {
+			return err
+		}
+		if task.Completed {
+			completed := true
+			if _, err := updateTodo(sp, job.UserID, todo.ID, UpdateTodoRequest{Completed: &completed}, ""); err != nil {
+				return err
+			}
+		}
+		for _, body := range task.Comments {
+			body = strings.TrimSpace(body)
+			if body == "" {
+				continue
+			}
+			if runes := []rune(body); len(runes) > maxImportCommentLength {
+				body = string(runes[:maxImportCommentLength])
+				addIssue("comment truncated to 10000 characters")
+			}
+			if err := sp.Create(&models.Comment{TodoID: todo.ID, UserID: job.UserID, Body: body}).Error; err != nil {
+				return err
+			}
+		}
+		todoID = todo.ID
+		return nil
+	})
+	if err != nil {
+		apiErr := utils.ToAPIError(err)
+		if apiErr.Status >= http.StatusInternalServerError {
+			return nil, err
+		}
+		// 取り消したtodoの分のレポートは残さない
+		issues = append(issues[:0], models.ImportIssue{JobID: job.ID, Ref: task.Ref, Title: task.Title, Message: "not imported: " + apiErr.Message})
+		return issues, nil
+	}
+
+	job.State.Todos[task.Ref] = todoID
+	job.Imported++
+	return issues, nil
+}
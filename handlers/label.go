@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// CreateLabelRequest ラベル作成リクエスト
+type CreateLabelRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+// UpdateLabelRequest ラベル更新（名前変更・色変更）リクエスト
+type UpdateLabelRequest struct {
+	Name  *string `json:"name"`
+	Color *string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+// MergeLabelRequest ラベル統合リクエスト
+type MergeLabelRequest struct {
+	TargetID uint `json:"target_id" binding:"required"`
+}
+
+// findLabel 自分のラベルを取得し、見つからなければエラーレスポンスを返します
+func findLabel(c *gin.Context, userID interface{}) (*models.Label, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid label ID")
+		return nil, false
+	}
+
+	var label models.Label
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&label).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Label not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	return &label, true
+}
+
+// findOwnedLabels 指定したIDのラベルがすべて自分のものであることを確認して取得します
+func findOwnedLabels(tx *gorm.DB, userID interface{}, ids []uint) ([]models.Label, error) {
+	labels := []models.Label{}
+	if len(ids) == 0 {
+		return labels, nil
+	}
+
+	if err := tx.Where("id IN ? AND user_id = ?", ids, userID).Find(&labels).Error; err != nil {
+		return nil, err
+	}
+
+	unique := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		unique[id] = true
+	}
+	if len(labels) != len(unique) {
+		return nil, errInvalidLabelIDs
+	}
+	return labels, nil
+}
+
+var errInvalidLabelIDs = utils.NewBadRequestError("Invalid label_ids: label not found")
+
+// applyLabelFilter ?label=a,b&label_mode=all|any でラベル名による絞り込みを行います
+func applyLabelFilter(query *gorm.DB, c todoListParams) (*gorm.DB, error) {
+	raw := c.Query("label")
+	if raw == "" {
+		return query, nil
+	}
+
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return query, nil
+	}
+
+	tagged := database.DB.Table("todo_labels").
+		Select("todo_labels.todo_id").
+		Joins("JOIN labels ON labels.id = todo_labels.label_id").
+		Where("labels.name IN ?", names)
+
+	switch c.DefaultQuery("label_mode", "any") {
+	case "any":
+	case "all":
+		// 指定したラベルをすべて持つtodoのみ
+		tagged = tagged.Group("todo_labels.todo_id").Having("COUNT(DISTINCT labels.name) = ?", len(names))
+	default:
+		return nil, fmt.Errorf("Invalid label_mode: must be all or any")
+	}
+
+	return query.Where("id IN (?)", tagged), nil
+}
+
+// GetLabels 自分のラベル一覧を取得
+func GetLabels(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var labels []models.Label
+	if err := database.DB.Where("user_id = ?", userID).Order("name ASC, id ASC").Find(&labels).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, labels)
+}
+
+// CreateLabel ラベルを作成
+func CreateLabel(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req CreateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	label := models.Label{
+		UserID: userID.(uint),
+		Name:   strings.TrimSpace(req.Name),
+		Color:  req.Color,
+	}
+	if label.Name == "" {
+		utils.RespondBadRequest(c, "name must not be empty")
+		return
+	}
+
+	if err := database.DB.Create(&label).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 409 {
+			utils.RespondConflict(c, "Label already exists")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, label)
+}
+
+// UpdateLabel ラベルの名前・色を変更
+// todoはラベルIDで紐づいているため、名前変更はタグ付けされたすべてのtodoに即座に反映されます
+func UpdateLabel(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req UpdateLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	label, ok := findLabel(c, userID)
+	if !ok {
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			utils.RespondBadRequest(c, "name must not be empty")
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Color != nil {
+		updates["color"] = *req.Color
+	}
+
+	if len(updates) == 0 {
+		utils.RespondBadRequest(c, "No fields to update")
+		return
+	}
+
+	if err := database.DB.Model(label).Updates(updates).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 409 {
+			utils.RespondConflict(c, "Label already exists")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return
+	}
+
+	// 更新後のデータを取得
+	database.DB.First(label, label.ID)
+	c.JSON(http.StatusOK, label)
+}
+
+// MergeLabel ラベルを別のラベルに統合
+// 統合元が付いていたtodoには統合先を付け替え、統合元を削除します（すべて1トランザクション）
+func MergeLabel(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req MergeLabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	source, ok := findLabel(c, userID)
+	if !ok {
+		return
+	}
+
+	if source.ID == req.TargetID {
+		utils.RespondBadRequest(c, "Cannot merge a label into itself")
+		return
+	}
+
+	var target models.Label
+	if err := database.DB.Where("id = ? AND user_id = ?", req.TargetID, userID).First(&target).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Target label not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := labeledTodoIDs(tx, source.ID)
+		if err != nil {
+			return err
+		}
+		if err := changeTodos(tx, userID.(uint), ids, func() error {
+			// 両方のラベルが付いているtodoは重複させない
+			if err := tx.Exec(`INSERT INTO todo_labels (todo_id, label_id)
+				SELECT todo_id, ? FROM todo_labels WHERE label_id = ?
+				ON CONFLICT DO NOTHING`, target.ID, source.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("DELETE FROM todo_labels WHERE label_id = ?", source.ID).Error; err != nil {
+				return err
+			}
+			return touchTodos(tx, ids)
+		}); err != nil {
+			return err
+		}
+		return tx.Delete(source).Error
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// labeledTodoIDs ラベルが付いているtodo（ゴミ箱にあるものを除く）のIDを取得します
+func labeledTodoIDs(tx *gorm.DB, labelID uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Model(&models.Todo{}).
+		Where("id IN (?)", tx.Table("todo_labels").Select("todo_id").Where("label_id = ?", labelID)).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// touchTodos ラベルの付け替えで変わったtodoの更新日時を進めます（versionと変更番号も進む）
+func touchTodos(tx *gorm.DB, ids []uint) error {
+	return tx.Model(&models.Todo{}).Where("id IN ?", ids).Update("updated_at", time.Now()).Error
+}
+
+// DeleteLabel ラベルを削除（todoからも外れる）
+func DeleteLabel(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	label, ok := findLabel(c, userID)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := labeledTodoIDs(tx, label.ID)
+		if err != nil {
+			return err
+		}
+		if err := changeTodos(tx, userID.(uint), ids, func() error {
+			if err := tx.Exec("DELETE FROM todo_labels WHERE label_id = ?", label.ID).Error; err != nil {
+				return err
+			}
+			return touchTodos(tx, ids)
+		}); err != nil {
+			return err
+		}
+		return tx.Delete(label).Error
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
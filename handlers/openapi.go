@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	swaggerFiles "github.com/swaggo/files/v2"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/openapi"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// openAPIErrors ステータスコードごとのエラーの説明
+var openAPIErrors = map[int]string{
+	http.StatusBadRequest:          "リクエストが不正",
+	http.StatusUnauthorized:        "認証が必要、または認証に失敗",
+	http.StatusForbidden:           "権限がない",
+	http.StatusNotFound:            "見つからない",
+	http.StatusConflict:            "既に存在する、または現在の状態では実行できない",
+	http.StatusPreconditionFailed:  "If-Matchのバージョンが一致しない",
+	http.StatusInternalServerError: "サーバーのエラー",
+}
+
+// openAPIBuilder 操作の定義を組み立てる補助
+type openAPIBuilder struct {
+	doc *openapi.Document
+}
+
+// response 本文がJSONのレスポンス（vがnilなら本文なし）
+func (b openAPIBuilder) response(description string, v interface{}) *openapi.Response {
+	if v == nil {
+		return &openapi.Response{Description: description}
+	}
+	return &openapi.Response{Description: description, Content: openapi.JSONBody(b.doc.SchemaOf(v))}
+}
+
+// responses 成功時のレスポンスに、statusesのエラーレスポンス（utils.ErrorResponse）を加えます
+func (b openAPIBuilder) responses(success map[int]*openapi.Response, statuses ...int) map[string]*openapi.Response {
+	responses := make(map[string]*openapi.Response, len(success)+len(statuses))
+	for status, response := range success {
+		responses[strconv.Itoa(status)] = response
+	}
+	for _, status := range statuses {
+		responses[strconv.Itoa(status)] = b.response(openAPIErrors[status], utils.ErrorResponse{})
+	}
+	return responses
+}
+
+// body 必須のJSONのリクエスト本文
+func (b openAPIBuilder) body(v interface{}) *openapi.RequestBody {
+	return &openapi.RequestBody{Required: true, Content: openapi.JSONBody(b.doc.SchemaOf(v))}
+}
+
+// todoIDParam /todos/:idのパスパラメータ
+var todoIDParam = &openapi.Parameter{Name: "id", In: "path", Required: true, Schema: &openapi.Schema{Type: "integer", Format: "int64"}}
+
+// todoListParameters GET /todosのクエリパラメータ
+func todoListParameters() []*openapi.Parameter {
+	dateTime := &openapi.Schema{Type: "string", Format: "date-time"}
+	minLimit, maxLimit := 1.0, float64(utils.MaxPageLimit)
+	return []*openapi.Parameter{
+		{Name: "completed", In: "query", Schema: &openapi.Schema{Type: "boolean"}},
+		{Name: "project_id", In: "query", Description: "プロジェクトのID（noneでプロジェクト未所属）", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "parent_id", In: "query", Description: "親のtodoのID（noneでサブタスク以外）", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "label", In: "query", Description: "ラベル名（カンマ区切り）", Schema: &openapi.Schema{Type: "string"}},
+		{Name: "label_mode", In: "query", Schema: &openapi.Schema{Type: "string", Enum: []string{"any", "all"}}},
+		{Name: "created_after", In: "query", Schema: dateTime},
+		{Name: "created_before", In: "query", Schema: dateTime},
+		{Name: "updated_after", In: "query", Schema: dateTime},
+		{Name: "updated_before", In: "query", Schema: dateTime},
+		{Name: "due_after", In: "query", Schema: dateTime},
+		{Name: "due_before", In: "query", Schema: dateTime},
+		{Name: "include_archived", In: "query", Description: "アーカイブしたプロジェクトのtodoも含める", Schema: &openapi.Schema{Type: "boolean"}},
+		{Name: "sort", In: "query", Description: "-を付けると降順", Schema: &openapi.Schema{Type: "string", Enum: []string{
+			"id", "-id", "title", "-title", "completed", "-completed", "created_at", "-created_at", "updated_at", "-updated_at",
+		}}},
+		{Name: "limit", In: "query", Schema: &openapi.Schema{Type: "integer", Minimum: &minLimit, Maximum: &maxLimit}},
+		{Name: "cursor", In: "query", Description: "前のページのnext_cursor", Schema: &openapi.Schema{Type: "string"}},
+	}
+}
+
+// newOpenAPIDocument /auth/*・/me・/todosのOpenAPIドキュメントをリクエスト・レスポンスの型から作成します
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Go Gin Todo API",
+		Version:     "1.0.0",
+		Description: "認証・ユーザー・todoのAPI",
+	})
+	doc.Components.SecuritySchemes["bearerAuth"] = &openapi.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}
+
+	// 独自のJSONの形式を持つ型
+	doc.Define(gorm.DeletedAt{}, &openapi.Schema{Type: "string", Format: "date-time", Nullable: true})
+	doc.Define(utils.Optional[uint]{}, openapi.Nullable(doc.SchemaOf(uint(0))))
+	doc.Define(utils.Optional[string]{}, &openapi.Schema{Type: "string", Nullable: true})
+	doc.Define(utils.Optional[time.Time]{}, &openapi.Schema{Type: "string", Format: "date-time", Nullable: true})
+
+	b := openAPIBuilder{doc: doc}
+	bearer := []map[string][]string{{"bearerAuth": {}}}
+	ifMatch := &openapi.Parameter{Name: "If-Match", In: "header", Description: "ETagが一致する場合だけ実行", Schema: &openapi.Schema{Type: "string"}}
+
+	doc.Add("POST", "/auth/register", &openapi.Operation{
+		OperationID: "register",
+		Summary:     "ユーザー登録",
+		Tags:        []string{"auth"},
+		RequestBody: b.body(RegisterRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusCreated: b.response("登録したユーザー", RegisterResponse{}),
+		}, http.StatusBadRequest, http.StatusConflict, http.StatusInternalServerError),
+	})
+	doc.Add("POST", "/auth/login", &openapi.Operation{
+		OperationID: "login",
+		Summary:     "ログイン（アクセストークンとリフレッシュトークンを発行）",
+		Tags:        []string{"auth"},
+		RequestBody: b.body(LoginRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK: b.response("発行したトークン", TokenResponse{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+	})
+	doc.Add("POST", "/auth/refresh", &openapi.Operation{
+		OperationID: "refreshToken",
+		Summary:     "トークンの更新（リフレッシュトークンはローテーションする）",
+		Tags:        []string{"auth"},
+		RequestBody: b.body(RefreshRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK: b.response("発行したトークン", TokenResponse{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+	})
+	doc.Add("POST", "/auth/logout", &openapi.Operation{
+		OperationID: "logout",
+		Summary:     "ログアウト（リフレッシュトークンを無効化）",
+		Tags:        []string{"auth"},
+		RequestBody: b.body(RefreshRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusNoContent: b.response("無効にした", nil),
+		}, http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+	})
+
+	doc.Add("GET", "/me", &openapi.Operation{
+		OperationID: "getMe",
+		Summary:     "自分の情報を取得",
+		Tags:        []string{"users"},
+		Security:    bearer,
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK: b.response("自分の情報", MeResponse{}),
+		}, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError),
+	})
+	doc.Add("PATCH", "/me", &openapi.Operation{
+		OperationID: "updateMe",
+		Summary:     "ユーザー設定を更新（タイムゾーン・サブタスク完了ルール）",
+		Tags:        []string{"users"},
+		Security:    bearer,
+		RequestBody: b.body(UpdateMeRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK: b.response("更新後の情報", MeResponse{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusInternalServerError),
+	})
+
+	doc.Add("GET", "/todos", &openapi.Operation{
+		OperationID: "listTodos",
+		Summary:     "自分のtodoと共有されたtodoの一覧を取得（キーセットページネーション）",
+		Tags:        []string{"todos"},
+		Security:    bearer,
+		Parameters:  todoListParameters(),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK: b.response("1ページ分のtodo", TodoListResponse{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError),
+	})
+	doc.Add("POST", "/todos", &openapi.Operation{
+		OperationID: "createTodo",
+		Summary:     "todoを作成",
+		Tags:        []string{"todos"},
+		Security:    bearer,
+		RequestBody: b.body(CreateTodoRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusCreated: b.response("作成したtodo", models.Todo{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError),
+	})
+	doc.Add("POST", "/todos/quick", &openapi.Operation{
+		OperationID: "quickAddTodo",
+		Summary:     "自由文からtodoを作成（日付・時刻・繰り返し・!優先度・#タグを解析）",
+		Tags:        []string{"todos"},
+		Security:    bearer,
+		Parameters: []*openapi.Parameter{
+			{Name: "dry_run", In: "query", Description: "trueなら作成せずに解析結果を返す", Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "tz", In: "query", Description: "日付を解釈するタイムゾーン（省略時はユーザーの設定）", Schema: &openapi.Schema{Type: "string"}},
+		},
+		RequestBody: b.body(QuickAddRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK:      b.response("解析結果（dry_run）", QuickAddPreview{}),
+			http.StatusCreated: b.response("作成したtodo", models.Todo{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusInternalServerError),
+	})
+	doc.Add("GET", "/todos/:id", &openapi.Operation{
+		OperationID: "getTodo",
+		Summary:     "todoを取得（If-None-Matchで条件付きGET）",
+		Tags:        []string{"todos"},
+		Security:    bearer,
+		Parameters: []*openapi.Parameter{todoIDParam, {
+			Name: "If-None-Match", In: "header", Description: "ETagが一致すれば304を返す", Schema: &openapi.Schema{Type: "string"},
+		}},
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK:          b.response("todo", models.Todo{}),
+			http.StatusNotModified: b.response("If-None-MatchのETagと一致した", nil),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusInternalServerError),
+	})
+	doc.Add("PATCH", "/todos/:id", &openapi.Operation{
+		OperationID: "updateTodo",
+		Summary:     "todoを更新（nullを指定したフィールドは値を外す）",
+		Tags:        []string{"todos"},
+		Security:    bearer,
+		Parameters:  []*openapi.Parameter{todoIDParam, ifMatch},
+		RequestBody: b.body(UpdateTodoRequest{}),
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusOK: b.response("更新後のtodo", models.Todo{}),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusConflict, http.StatusPreconditionFailed, http.StatusInternalServerError),
+	})
+	doc.Add("DELETE", "/todos/:id", &openapi.Operation{
+		OperationID: "deleteTodo",
+		Summary:     "todoをゴミ箱に移動",
+		Tags:        []string{"todos"},
+		Security:    bearer,
+		Parameters: []*openapi.Parameter{todoIDParam, ifMatch, {
+			Name: "children", In: "query", Description: "サブタスクも削除するか、親に付け替えるか",
+			Schema: &openapi.Schema{Type: "string", Enum: []string{"cascade", "reparent"}},
+		}},
+		Responses: b.responses(map[int]*openapi.Response{
+			http.StatusNoContent: b.response("ゴミ箱に移動した", nil),
+		}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusPreconditionFailed, http.StatusInternalServerError),
+	})
+	return doc
+}
+
+// OpenAPIDocument 起動時に作成するOpenAPIドキュメント
+var OpenAPIDocument = newOpenAPIDocument()
+
+// GetOpenAPISpec OpenAPIドキュメントをJSONで返します
+func GetOpenAPISpec(c *gin.Context) {
+	c.JSON(http.StatusOK, OpenAPIDocument)
+}
+
+// swaggerInitializer Swagger UIに/openapi.jsonを表示させる設定
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    persistAuthorization: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    plugins: [SwaggerUIBundle.plugins.DownloadUrl],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// SwaggerUI バイナリに埋め込んだSwagger UIを配信します（/docs/*filepath）
+func SwaggerUI(c *gin.Context) {
+	file := c.Param("filepath")
+	if file == "/swagger-initializer.js" {
+		c.Data(http.StatusOK, "application/javascript; charset=utf-8", []byte(swaggerInitializer))
+		return
+	}
+	c.FileFromFS(file, http.FS(swaggerFiles.FS))
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// CreateProjectRequest プロジェクト作成リクエスト
+type CreateProjectRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// UpdateProjectRequest プロジェクト更新リクエスト
+type UpdateProjectRequest struct {
+	Name *string `json:"name"`
+}
+
+// checkTodoProject todoの所属先に指定されたプロジェクトにtodoを追加でき（editor以上）、アーカイブされていないか検証します
+func checkTodoProject(tx *gorm.DB, userID uint, projectID uint) (*models.Project, error) {
+	project, err := authorizeProject(tx, userID, projectID, services.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.ArchivedAt != nil {
+		return nil, utils.NewConflictError("Project is archived")
+	}
+
+	return project, nil
+}
+
+// GetProjects 自分のプロジェクトと共有されたプロジェクトの一覧を取得（?archived=true|allでアーカイブ済みも取得）
+func GetProjects(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	query := database.DB.Scopes(services.VisibleProjects(userID.(uint)))
+	switch c.DefaultQuery("archived", "false") {
+	case "false":
+		query = query.Where("archived_at IS NULL")
+	case "true":
+		query = query.Where("archived_at IS NOT NULL")
+	case "all":
+	default:
+		utils.RespondBadRequest(c, "Invalid archived: must be true, false or all")
+		return
+	}
+
+	var projects []models.Project
+	if err := query.Order("created_at ASC, id ASC").Find(&projects).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, projects)
+}
+
+// CreateProject プロジェクトを作成
+func CreateProject(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req CreateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	project := models.Project{
+		UserID: userID.(uint),
+		Name:   req.Name,
+	}
+
+	if err := database.DB.Create(&project).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// GetProject 特定のプロジェクトを取得（閲覧できるものだけ）
+func GetProject(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	project, ok := findAuthorizedProject(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// UpdateProject プロジェクトを更新
+func UpdateProject(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	project, ok := findAuthorizedProject(c, userID.(uint), services.RoleOwner)
+	if !ok {
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		if *req.Name == "" {
+			utils.RespondBadRequest(c, "name must not be empty")
+			return
+		}
+		updates["name"] = *req.Name
+	}
+
+	if len(updates) == 0 {
+		utils.RespondBadRequest(c, "No fields to update")
+		return
+	}
+
+	if err := database.DB.Model(project).Updates(updates).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	// 更新後のデータを取得
+	database.DB.First(project, project.ID)
+	c.JSON(http.StatusOK, project)
+}
+
+// setProjectArchived プロジェクトのアーカイブ状態を切り替えます
+func setProjectArchived(c *gin.Context, archived bool) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	project, ok := findAuthorizedProject(c, userID.(uint), services.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var archivedAt *time.Time
+	if archived {
+		if project.ArchivedAt != nil {
+			// 既にアーカイブ済みの場合はアーカイブ日時を維持
+			c.JSON(http.StatusOK, project)
+			return
+		}
+		now := time.Now()
+		archivedAt = &now
+	}
+
+	if err := database.DB.Model(project).Update("archived_at", archivedAt).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	database.DB.First(project, project.ID)
+	c.JSON(http.StatusOK, project)
+}
+
+// ArchiveProject プロジェクトをアーカイブ（所属するtodoも一覧から除外される）
+func ArchiveProject(c *gin.Context) {
+	setProjectArchived(c, true)
+}
+
+// UnarchiveProject プロジェクトのアーカイブを解除
+func UnarchiveProject(c *gin.Context) {
+	setProjectArchived(c, false)
+}
+
+// DeleteProject プロジェクトを削除
+// ?todos=delete（デフォルト）で所属するtodoも削除、?todos=detachでtodoはプロジェクト未所属として残す
+func DeleteProject(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	mode := c.DefaultQuery("todos", "delete")
+	if mode != "delete" && mode != "detach" {
+		utils.RespondBadRequest(c, "Invalid todos: must be delete or detach")
+		return
+	}
+
+	project, ok := findAuthorizedProject(c, userID.(uint), services.RoleOwner)
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var todos []models.Todo
+		if err := tx.Where("project_id = ?", project.ID).Order("id ASC").Find(&todos).Error; err != nil {
+			return err
+		}
+		ids := make([]uint, len(todos))
+		for i := range todos {
+			ids[i] = todos[i].ID
+		}
+		// 1件ずつ削除・更新したときと同じく、バージョンを進めてtodoごとに変更履歴を記録・通知する
+		// プロジェクトの共有で閲覧していたユーザーにも届くよう、共有を削除する前に通知する
+		if mode == "delete" && len(ids) > 0 {
+			deletedAt := time.Now()
+			if err := tx.Model(&models.Todo{}).Where("id IN ?", ids).Update("deleted_at", deletedAt).Error; err != nil {
+				return err
+			}
+			if err := recordTrashRevisions(tx, todos, userID.(uint), models.RevisionActionDelete, deletedAt); err != nil {
+				return err
+			}
+			if err := emitTodoEventsByID(tx, models.WebhookEventTodoDeleted, ids); err != nil {
+				return err
+			}
+		} else if err := changeTodos(tx, userID.(uint), ids, func() error {
+			return tx.Model(&models.Todo{}).Where("id IN ?", ids).Update("project_id", nil).Error
+		}); err != nil {
+			return err
+		}
+		if err := tx.Where("resource_type = ? AND resource_id = ?", models.ShareResourceProject, project.ID).Delete(&models.Share{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(project).Error
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetProjectTodos プロジェクトに所属するtodo一覧を取得
+func GetProjectTodos(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	project, ok := findAuthorizedProject(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	sort, err := parseTodoSort(c.DefaultQuery("sort", "created_at"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	query, err := applyTodoFilters(database.DB.Where("project_id = ?", project.ID), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	respondTodoPage(c, query, sort)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// QuickAddRequest クイック追加リクエスト
+// textから期限・繰り返し・優先度・タグを取り出し、残りをタイトルにします
+type QuickAddRequest struct {
+	Text      string `json:"text" binding:"required,max=1000"`
+	ProjectID *uint  `json:"project_id"`
+	ParentID  *uint  `json:"parent_id"`
+}
+
+// QuickAddLabel タグに対応するラベル（IDがnullなら作成時に新しく作る）
+type QuickAddLabel struct {
+	ID   *uint  `json:"id"`
+	Name string `json:"name"`
+}
+
+// QuickAddPreview dry_runで返す解析結果
+type QuickAddPreview struct {
+	Title     string          `json:"title"`
+	ProjectID *uint           `json:"project_id"`
+	ParentID  *uint           `json:"parent_id"`
+	DueAt     *time.Time      `json:"due_at"`
+	RRule     *string         `json:"rrule"`
+	Priority  *string         `json:"priority"`
+	Labels    []QuickAddLabel `json:"labels"`
+	Timezone  string          `json:"timezone"`
+}
+
+// newQuickAddCreateRequest 解析結果からtodo作成リクエストを作ります（ラベルは呼び出し側で設定する）
+func newQuickAddCreateRequest(req QuickAddRequest, parsed *utils.QuickAdd) CreateTodoRequest {
+	create := CreateTodoRequest{
+		Title:     parsed.Title,
+		ProjectID: req.ProjectID,
+		ParentID:  req.ParentID,
+		DueAt:     parsed.DueAt,
+	}
+	if parsed.RRule != nil {
+		rrule := parsed.RRule.String()
+		create.RRule = &rrule
+	}
+	if parsed.Priority != "" {
+		create.Priority = &parsed.Priority
+	}
+	return create
+}
+
+// CreateQuickTodo 自由文からtodoを作成（例: "pay rent tomorrow 9am #home !high every month"）
+// 日付・時刻はユーザーのタイムゾーン（tzクエリパラメータで上書き可能）で解釈します
+// ?dry_run=trueなら作成せずに解析結果を返します。タグは同じ名前のラベルを付け、なければ作成します。
+func CreateQuickTodo(c *gin.Context) {
+	userID, loc, ok := scheduleView(c)
+	if !ok {
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			utils.RespondBadRequest(c, "Invalid dry_run")
+			return
+		}
+	}
+
+	var req QuickAddRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	parsed, err := utils.ParseQuickAdd(req.Text, time.Now(), loc)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	create := newQuickAddCreateRequest(req, parsed)
+
+	if dryRun {
+		resolver, err := newTodoTxtResolver(database.DB, userID.(uint))
+		if err != nil {
+			utils.RespondAPIError(c, err)
+			return
+		}
+		labels := make([]QuickAddLabel, len(parsed.Tags))
+		for i, tag := range parsed.Tags {
+			labels[i] = QuickAddLabel{Name: tag}
+			if id, ok := resolver.labels[todoTxtName(tag)]; ok {
+				labels[i].ID = &id
+			}
+		}
+		c.JSON(http.StatusOK, QuickAddPreview{
+			Title:     create.Title,
+			ProjectID: create.ProjectID,
+			ParentID:  create.ParentID,
+			DueAt:     create.DueAt,
+			RRule:     create.RRule,
+			Priority:  create.Priority,
+			Labels:    labels,
+			Timezone:  loc.String(),
+		})
+		return
+	}
+
+	var todo *models.Todo
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// タグはtodo.txtの@contextと同じく名前でラベルに対応付ける
+		resolver, err := newTodoTxtResolver(tx, userID.(uint))
+		if err != nil {
+			return err
+		}
+		if create.LabelIDs, err = resolver.labelIDs(parsed.Tags); err != nil {
+			return err
+		}
+		todo, err = createTodo(tx, userID.(uint), create)
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.Header("ETag", todo.ETag)
+	c.JSON(http.StatusCreated, todo)
+}
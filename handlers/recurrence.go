@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOccurrenceCount = 5
+	maxOccurrenceCount     = 100
+)
+
+// OccurrencesResponse 繰り返しの今後の発生日時プレビュー
+type OccurrencesResponse struct {
+	RRule       string      `json:"rrule"`
+	Timezone    string      `json:"timezone"`
+	Occurrences []time.Time `json:"occurrences"`
+}
+
+var errRRuleWithoutDue = fmt.Errorf("rrule requires due_at")
+
+// normalizeRRule RRULEを検証して正規化した文字列を返します
+func normalizeRRule(s string) (string, error) {
+	rule, err := utils.ParseRRule(s)
+	if err != nil {
+		return "", fmt.Errorf("Invalid rrule: %v", err)
+	}
+	return rule.String(), nil
+}
+
+// recurrence todoの繰り返しルールと、発生日時を計算する基準（DTSTART）を返します
+// 日時はユーザーのタイムゾーンで計算するため、夏時間をまたいでも同じ時刻に繰り返します
+func recurrence(todo *models.Todo) (*utils.RRule, time.Time, error) {
+	if todo.RRule == nil || todo.DueAt == nil {
+		return nil, time.Time{}, fmt.Errorf("Todo is not recurring")
+	}
+
+	rule, err := utils.ParseRRule(*todo.RRule)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	loc, err := userLocation(todo.UserID)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	// COUNTはシリーズの最初から数える。それ以外は現在の回を起点にすれば十分
+	dtstart := *todo.DueAt
+	if rule.Count > 0 && todo.SeriesStart != nil {
+		dtstart = *todo.SeriesStart
+	}
+	return rule, dtstart.In(loc), nil
+}
+
+// shiftStart 期限の移動に合わせて開始日時も同じだけずらします
+func shiftStart(todo *models.Todo, next time.Time) *time.Time {
+	if todo.StartAt == nil || todo.DueAt == nil {
+		return nil
+	}
+	start := next.Add(todo.StartAt.Sub(*todo.DueAt))
+	return &start
+}
+
+// spawnNextOccurrence 完了した回から次の回を作成し、繰り返しルールを引き継ぎます
+// シリーズが終了している場合は何も作成しません
+func spawnNextOccurrence(tx *gorm.DB, todo *models.Todo, actorID uint) error {
+	if todo.RRule == nil || todo.DueAt == nil {
+		return nil
+	}
+
+	rule, dtstart, err := recurrence(todo)
+	if err != nil {
+		return err
+	}
+
+	// 完了した回はルールを手放す（再度未完了に戻しても二重に生成されない）
+	if err := tx.Model(todo).Update("rrule", nil).Error; err != nil {
+		return err
+	}
+
+	next, ok := rule.Next(dtstart, *todo.DueAt)
+	if !ok {
+		return nil
+	}
+
+	var labels []models.Label
+	if err := tx.Model(todo).Association("Labels").Find(&labels); err != nil {
+		return err
+	}
+
+	nextTodo := models.Todo{
+		UserID:      todo.UserID,
+		Title:       todo.Title,
+		ProjectID:   todo.ProjectID,
+		ParentID:    todo.ParentID,
+		StartAt:     shiftStart(todo, next),
+		DueAt:       &next,
+		RRule:       todo.RRule,
+		SeriesID:    todo.SeriesID,
+		SeriesStart: todo.SeriesStart,
+		Priority:    todo.Priority,
+		Labels:      labels,
+	}
+	if err := tx.Create(&nextTodo).Error; err != nil {
+		return err
+	}
+	todo.RRule = nil
+
+	labelIDs := make([]uint, len(labels))
+	for i, label := range labels {
+		labelIDs[i] = label.ID
+	}
+	if err := recordRevision(tx, nextTodo.ID, actorID, models.RevisionActionCreate, nil, snapshotTodo(&nextTodo, labelIDs), nil); err != nil {
+		return err
+	}
+	return emitTodoEvent(tx, models.WebhookEventTodoCreated, &nextTodo)
+}
+
+// findRecurringTodo 繰り返しtodoを取得してrequired以上の権限があるか確認し、なければエラーレスポンスを返します
+func findRecurringTodo(c *gin.Context, required services.Role) (*models.Todo, bool) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return nil, false
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), required)
+	if !ok {
+		return nil, false
+	}
+
+	if todo.RRule == nil {
+		utils.RespondConflict(c, "Todo is not recurring")
+		return nil, false
+	}
+
+	return todo, true
+}
+
+// GetTodoOccurrences 繰り返しtodoの今後の発生日時をプレビュー（現在の回を含む）
+func GetTodoOccurrences(c *gin.Context) {
+	count := defaultOccurrenceCount
+	if countStr := c.Query("count"); countStr != "" {
+		parsed, err := strconv.Atoi(countStr)
+		if err != nil || parsed < 1 || parsed > maxOccurrenceCount {
+			utils.RespondBadRequest(c, "count must be between 1 and 100")
+			return
+		}
+		count = parsed
+	}
+
+	todo, ok := findRecurringTodo(c, services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	rule, dtstart, err := recurrence(todo)
+	if err != nil {
+		utils.RespondInternalError(c, "Failed to evaluate rrule")
+		return
+	}
+
+	c.JSON(http.StatusOK, OccurrencesResponse{
+		RRule:       *todo.RRule,
+		Timezone:    dtstart.Location().String(),
+		Occurrences: rule.Upcoming(dtstart, *todo.DueAt, count),
+	})
+}
+
+// SkipTodoOccurrence 現在の回をスキップし、期限を次の発生日時に進める
+func SkipTodoOccurrence(c *gin.Context) {
+	todo, ok := findRecurringTodo(c, services.RoleEditor)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet(middleware.UserIDKey).(uint)
+	var updated *models.Todo
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		// 同時にスキップしても同じ回を二重に進めないよう、ロックしてから次の発生日時を計算する
+		locked, err := lockTodo(tx, userID, todo.ID, services.RoleEditor, c.GetHeader("If-Match"))
+		if err != nil {
+			return err
+		}
+		if locked.RRule == nil {
+			return utils.NewConflictError("Todo is not recurring")
+		}
+		rule, dtstart, err := recurrence(locked)
+		if err != nil {
+			return err
+		}
+		next, found := rule.Next(dtstart, *locked.DueAt)
+		if !found {
+			return utils.NewConflictError("No more occurrences in the series")
+		}
+
+		req := UpdateTodoRequest{
+			DueAt:   utils.Optional[time.Time]{Set: true, Value: &next},
+			StartAt: utils.Optional[time.Time]{Set: true, Value: shiftStart(locked, next)},
+		}
+		updated, err = updateTodo(tx, userID, locked.ID, req, "")
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.Header("ETag", updated.ETag)
+	c.JSON(http.StatusOK, updated)
+}
+
+// EndTodoSeries 繰り返しを終了（現在の回は通常のtodoとして残る）
+func EndTodoSeries(c *gin.Context) {
+	todo, ok := findRecurringTodo(c, services.RoleEditor)
+	if !ok {
+		return
+	}
+
+	userID := c.MustGet(middleware.UserIDKey).(uint)
+	var updated *models.Todo
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		req := UpdateTodoRequest{RRule: utils.Optional[string]{Set: true}}
+		updated, err = updateTodo(tx, userID, todo.ID, req, c.GetHeader("If-Match"))
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.Header("ETag", updated.ETag)
+	c.JSON(http.StatusOK, updated)
+}
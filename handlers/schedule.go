@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+)
+
+const (
+	defaultUpcomingDays = 7
+	maxUpcomingDays     = 365
+)
+
+// dueSort 期限ビューの並び順（期限が近い順）
+var dueSort = todoSort{Column: "due_at"}
+
+// userLocation ユーザーに設定されたタイムゾーンを取得します
+func userLocation(userID interface{}) (*time.Location, error) {
+	var user models.User
+	if err := database.DB.Select("timezone").First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return utils.LoadLocation(user.Timezone)
+}
+
+// scheduleView 期限ビュー共通の前処理（tzクエリパラメータでタイムゾーンを上書き可能）
+func scheduleView(c *gin.Context) (interface{}, *time.Location, bool) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return nil, nil, false
+	}
+
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := utils.LoadLocation(tz)
+		if err != nil {
+			utils.RespondBadRequest(c, err.Error())
+			return nil, nil, false
+		}
+		return userID, loc, true
+	}
+
+	loc, err := userLocation(userID)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return nil, nil, false
+	}
+
+	return userID, loc, true
+}
+
+// GetOverdueTodos 期限切れの未完了todo一覧を取得
+func GetOverdueTodos(c *gin.Context) {
+	userID, _, ok := scheduleView(c)
+	if !ok {
+		return
+	}
+
+	query, err := applyTodoFilters(database.DB.Scopes(services.VisibleTodos(userID.(uint))), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	query = query.Where("completed = ?", false).Where("due_at < ?", time.Now())
+
+	respondTodoPage(c, excludeArchivedProjects(query, c), dueSort)
+}
+
+// GetTodayTodos ユーザーのタイムゾーンで今日が期限のtodo一覧を取得
+func GetTodayTodos(c *gin.Context) {
+	userID, loc, ok := scheduleView(c)
+	if !ok {
+		return
+	}
+
+	query, err := applyTodoFilters(database.DB.Scopes(services.VisibleTodos(userID.(uint))), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	today := utils.StartOfDay(time.Now(), loc)
+	query = query.Where("due_at >= ? AND due_at < ?", today, utils.AddDays(today, 1, loc))
+
+	respondTodoPage(c, excludeArchivedProjects(query, c), dueSort)
+}
+
+// GetUpcomingTodos 現在からN日後の終わりまでが期限のtodo一覧を取得
+func GetUpcomingTodos(c *gin.Context) {
+	userID, loc, ok := scheduleView(c)
+	if !ok {
+		return
+	}
+
+	days := defaultUpcomingDays
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 1 || parsed > maxUpcomingDays {
+			utils.RespondBadRequest(c, "days must be between 1 and 365")
+			return
+		}
+		days = parsed
+	}
+
+	query, err := applyTodoFilters(database.DB.Scopes(services.VisibleTodos(userID.(uint))), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	now := time.Now()
+	end := utils.AddDays(utils.StartOfDay(now, loc), days+1, loc)
+	query = query.Where("due_at >= ? AND due_at < ?", now, end)
+
+	respondTodoPage(c, excludeArchivedProjects(query, c), dueSort)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"html"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	searchModeAuto     = "auto"
+	searchModeFullText = "fulltext"
+	searchModeFuzzy    = "fuzzy"
+
+	// ハイライト部分を囲むマーカー（titleはHTMLエスケープしてから囲む）
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+
+	// ts_headlineに一致箇所を囲ませる制御文字（titleからは取り除いておき、エスケープした後でマーカーに置き換える）
+	headlineStartSel = "\x01"
+	headlineStopSel  = "\x02"
+)
+
+// SearchResult 検索結果の1件
+type SearchResult struct {
+	Todo      models.Todo `json:"todo"`
+	Rank      float64     `json:"rank"`
+	Highlight string      `json:"highlight"`
+}
+
+// SearchResponse 検索レスポンス
+type SearchResponse struct {
+	Mode  string         `json:"mode"`
+	Items []SearchResult `json:"items"`
+}
+
+// todoSearchRow 検索クエリのスキャン用
+type todoSearchRow struct {
+	models.Todo
+	Rank      float64
+	Highlight string
+}
+
+// tsqueryWord tsqueryとして安全に扱える単語
+var tsqueryWord = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// buildPrefixTSQuery 入力をAND結合の前方一致tsqueryに変換します（例: "buy milk" → "buy:* & milk:*"）
+func buildPrefixTSQuery(q string) string {
+	words := tsqueryWord.FindAllString(strings.ToLower(q), -1)
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		terms = append(terms, w+":*")
+	}
+	return strings.Join(terms, " & ")
+}
+
+// containsCJK 語幹処理が効かない日本語・中国語・韓国語の文字を含むか判定します
+func containsCJK(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeLike LIKEパターンの特殊文字をエスケープします
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// highlightSubstring titleをHTMLエスケープし、大文字小文字を区別せずにqの出現箇所をマーカーで囲みます
+// 小文字化でバイト長が変わる文字（ケルビン記号など）があっても位置がずれないよう、正規表現の一致位置を使います
+func highlightSubstring(text, q string) string {
+	if q == "" {
+		return html.EscapeString(text)
+	}
+	pattern, err := regexp.Compile("(?i)" + regexp.QuoteMeta(q))
+	if err != nil {
+		return html.EscapeString(text)
+	}
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringIndex(text, -1) {
+		b.WriteString(html.EscapeString(text[last:loc[0]]))
+		b.WriteString(highlightStart)
+		b.WriteString(html.EscapeString(text[loc[0]:loc[1]]))
+		b.WriteString(highlightStop)
+		last = loc[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// highlightHeadline ts_headlineの結果をHTMLエスケープし、一致箇所の制御文字をマーカーに置き換えます
+func highlightHeadline(headline string) string {
+	return strings.NewReplacer(headlineStartSel, highlightStart, headlineStopSel, highlightStop).Replace(html.EscapeString(headline))
+}
+
+// searchFullText tsvectorによる全文検索（語幹処理・前方一致）
+func searchFullText(base *gorm.DB, q string, limit int) ([]SearchResult, error) {
+	tsq := buildPrefixTSQuery(q)
+	if tsq == "" {
+		return []SearchResult{}, nil
+	}
+
+	headlineOpts := "StartSel=" + headlineStartSel + ", StopSel=" + headlineStopSel + ", HighlightAll=true"
+	var rows []todoSearchRow
+	err := base.
+		Select("todos.*, ts_rank(search_vector, to_tsquery('english', ?)) AS rank, ts_headline('english', translate(title, ?, ''), to_tsquery('english', ?), ?) AS highlight",
+			tsq, headlineStartSel+headlineStopSel, tsq, headlineOpts).
+		Where("search_vector @@ to_tsquery('english', ?)", tsq).
+		Order("rank DESC, id DESC").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, SearchResult{Todo: row.Todo, Rank: row.Rank, Highlight: highlightHeadline(row.Highlight)})
+	}
+	return results, nil
+}
+
+// searchFuzzy トライグラム類似度と部分一致による検索（日本語向けフォールバック）
+func searchFuzzy(base *gorm.DB, q string, limit int) ([]SearchResult, error) {
+	var rows []todoSearchRow
+	err := base.
+		Select("todos.*, similarity(title, ?) AS rank", q).
+		Where("title ILIKE ? OR title % ?", "%"+escapeLike(q)+"%", q).
+		Order("rank DESC, id DESC").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, SearchResult{
+			Todo:      row.Todo,
+			Rank:      row.Rank,
+			Highlight: highlightSubstring(row.Title, q),
+		})
+	}
+	return results, nil
+}
+
+// SearchTodos 自分のtodoと共有されたtodoを関連度順に検索
+func SearchTodos(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		utils.RespondBadRequest(c, "q is required")
+		return
+	}
+
+	mode := c.DefaultQuery("mode", searchModeAuto)
+	if mode != searchModeAuto && mode != searchModeFullText && mode != searchModeFuzzy {
+		utils.RespondBadRequest(c, "Invalid mode: must be auto, fulltext or fuzzy")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	base, err := applyTodoFilters(database.DB.Model(&models.Todo{}).Scopes(services.VisibleTodos(userID.(uint))), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	base = excludeArchivedProjects(base, c)
+
+	// autoの場合はCJK文字を含むとき、または全文検索で見つからないときにfuzzyへフォールバック
+	if mode == searchModeAuto && containsCJK(q) {
+		mode = searchModeFuzzy
+	}
+
+	var results []SearchResult
+	if mode != searchModeFuzzy {
+		results, err = searchFullText(base.Session(&gorm.Session{}), q, limit)
+		if err == nil && len(results) == 0 && mode == searchModeAuto {
+			mode = searchModeFuzzy
+		} else if mode == searchModeAuto {
+			mode = searchModeFullText
+		}
+	}
+	if err == nil && mode == searchModeFuzzy {
+		results, err = searchFuzzy(base.Session(&gorm.Session{}), q, limit)
+	}
+	if err == nil {
+		err = preloadSearchLabels(results)
+	}
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, SearchResponse{Mode: mode, Items: results})
+}
+
+// preloadSearchLabels 検索結果のtodoにラベルを読み込みます
+func preloadSearchLabels(results []SearchResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Todo.ID)
+	}
+
+	var todos []models.Todo
+	if err := database.DB.Preload("Labels").Where("id IN ?", ids).Find(&todos).Error; err != nil {
+		return err
+	}
+
+	labels := make(map[uint][]models.Label, len(todos))
+	for _, todo := range todos {
+		labels[todo.ID] = todo.Labels
+	}
+	for i := range results {
+		results[i].Todo.Labels = labels[results[i].Todo.ID]
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package handlers
+
+import "testing"
+
+func TestHighlightSubstring(t *testing.T) {
+	tests := []struct {
+		text, q, want string
+	}{
+		{text: "Buy Milk and milk", q: "milk", want: "Buy " + highlightStart + "Milk" + highlightStop + " and " + highlightStart + "milk" + highlightStop},
+		{text: "牛乳を買う", q: "牛乳", want: highlightStart + "牛乳" + highlightStop + "を買う"},
+		{text: "a.b axb", q: ".", want: "a" + highlightStart + "." + highlightStop + "b axb"},
+		{text: "no match", q: "zzz", want: "no match"},
+		{text: "empty query", q: "", want: "empty query"},
+		// 小文字化でバイト長が変わる文字を含んでもpanicしない
+		{text: "300 K reading", q: "k", want: "300 " + highlightStart + "K" + highlightStop + " reading"},
+		{text: "KKK", q: "K", want: highlightStart + "K" + highlightStop + highlightStart + "K" + highlightStop + highlightStart + "K" + highlightStop},
+		{text: "KK x", q: "kk", want: highlightStart + "KK" + highlightStop + " x"},
+		// titleはエスケープしてからマーカーで囲む
+		{text: "<script>alert(1)</script>", q: "script", want: "&lt;" + highlightStart + "script" + highlightStop + "&gt;alert(1)&lt;/" + highlightStart + "script" + highlightStop + "&gt;"},
+		{text: `a&b "<mark>"`, q: "&", want: "a" + highlightStart + "&amp;" + highlightStop + "b &#34;&lt;mark&gt;&#34;"},
+		{text: "<b>", q: "", want: "&lt;b&gt;"},
+	}
+
+	for _, tt := range tests {
+		if got := highlightSubstring(tt.text, tt.q); got != tt.want {
+			t.Errorf("highlightSubstring(%q, %q) = %q, want %q", tt.text, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestHighlightHeadline(t *testing.T) {
+	tests := map[string]string{
+		"Buy " + headlineStartSel + "milk" + headlineStopSel:                "Buy " + highlightStart + "milk" + highlightStop,
+		headlineStartSel + "<img src=x onerror=alert(1)>" + headlineStopSel: highlightStart + "&lt;img src=x onerror=alert(1)&gt;" + highlightStop,
+		"<mark>fake</mark> & more":                                          "&lt;mark&gt;fake&lt;/mark&gt; &amp; more",
+	}
+	for headline, want := range tests {
+		if got := highlightHeadline(headline); got != want {
+			t.Errorf("highlightHeadline(%q) = %q, want %q", headline, got, want)
+		}
+	}
+}
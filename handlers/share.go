@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// CreateShareRequest 共有（招待）作成リクエスト
+type CreateShareRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
+// UpdateShareRequest 共有の権限変更リクエスト
+type UpdateShareRequest struct {
+	Role string `json:"role" binding:"required,oneof=viewer editor owner"`
+}
+
+// UserSummary レスポンスに含めるユーザーの概要（IDとメールアドレスのみ）
+type UserSummary struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
+// ShareResponse 共有（招待）レスポンス
+type ShareResponse struct {
+	ID           uint        `json:"id"`
+	ResourceType string      `json:"resource_type"`
+	ResourceID   uint        `json:"resource_id"`
+	ResourceName string      `json:"resource_name"`
+	Role         string      `json:"role"`
+	Status       string      `json:"status"`
+	User         UserSummary `json:"user"`
+	InvitedBy    UserSummary `json:"invited_by"`
+	RespondedAt  *time.Time  `json:"responded_at"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+// shareResponses 共有をユーザー情報・リソース名付きのレスポンスに変換します
+func shareResponses(shares []models.Share) ([]ShareResponse, error) {
+	userIDs := make([]uint, 0, len(shares)*2)
+	projectIDs := make([]uint, 0)
+	todoIDs := make([]uint, 0)
+	for _, share := range shares {
+		userIDs = append(userIDs, share.UserID, share.InvitedByID)
+		if share.ResourceType == models.ShareResourceProject {
+			projectIDs = append(projectIDs, share.ResourceID)
+		} else {
+			todoIDs = append(todoIDs, share.ResourceID)
+		}
+	}
+
+	var users []models.User
+	if err := database.DB.Select("id", "email").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	emails := make(map[uint]string, len(users))
+	for _, user := range users {
+		emails[user.ID] = user.Email
+	}
+
+	names := map[string]map[uint]string{
+		models.ShareResourceProject: {},
+		models.ShareResourceTodo:    {},
+	}
+	if len(projectIDs) > 0 {
+		var projects []models.Project
+		if err := database.DB.Select("id", "name").Where("id IN ?", projectIDs).Find(&projects).Error; err != nil {
+			return nil, err
+		}
+		for _, project := range projects {
+			names[models.ShareResourceProject][project.ID] = project.Name
+		}
+	}
+	if len(todoIDs) > 0 {
+		var todos []models.Todo
+		if err := database.DB.Select("id", "title").Where("id IN ?", todoIDs).Find(&todos).Error; err != nil {
+			return nil, err
+		}
+		for _, todo := range todos {
+			names[models.ShareResourceTodo][todo.ID] = todo.Title
+		}
+	}
+
+	resp := make([]ShareResponse, len(shares))
+	for i, share := range shares {
+		resp[i] = ShareResponse{
+			ID:           share.ID,
+			ResourceType: share.ResourceType,
+			ResourceID:   share.ResourceID,
+			ResourceName: names[share.ResourceType][share.ResourceID],
+			Role:         share.Role,
+			Status:       share.Status,
+			User:         UserSummary{ID: share.UserID, Email: emails[share.UserID]},
+			InvitedBy:    UserSummary{ID: share.InvitedByID, Email: emails[share.InvitedByID]},
+			RespondedAt:  share.RespondedAt,
+			CreatedAt:    share.CreatedAt,
+		}
+	}
+	return resp, nil
+}
+
+// respondShare 1件の共有をレスポンスとして返します
+func respondShare(c *gin.Context, statusCode int, share models.Share) {
+	resp, err := shareResponses([]models.Share{share})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	c.JSON(statusCode, resp[0])
+}
+
+// authorizeShareResource 共有の対象（プロジェクトまたはtodo）にrequired以上の権限があるか確認し、持ち主のIDを返します
+func authorizeShareResource(tx *gorm.DB, userID uint, resourceType string, resourceID uint, required services.Role) (uint, error) {
+	if resourceType == models.ShareResourceProject {
+		project, err := authorizeProject(tx, userID, resourceID, required)
+		if err != nil {
+			return 0, err
+		}
+		return project.UserID, nil
+	}
+	todo, err := authorizeTodo(tx, userID, resourceID, required)
+	if err != nil {
+		return 0, err
+	}
+	return todo.UserID, nil
+}
+
+// createShare プロジェクトまたはtodoへの招待を作成します（owner権限が必要）
+func createShare(c *gin.Context, resourceType string) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	resourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid "+resourceType+" ID")
+		return
+	}
+
+	var req CreateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	ownerID, err := authorizeShareResource(database.DB, userID.(uint), resourceType, uint(resourceID), services.RoleOwner)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	var invitee models.User
+	if err := database.DB.Where("email = ?", req.Email).First(&invitee).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "User not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return
+	}
+	if invitee.ID == ownerID || invitee.ID == userID.(uint) {
+		utils.RespondBadRequest(c, "Cannot share with the owner or yourself")
+		return
+	}
+
+	share := models.Share{
+		ResourceType: resourceType,
+		ResourceID:   uint(resourceID),
+		UserID:       invitee.ID,
+		InvitedByID:  userID.(uint),
+		Role:         req.Role,
+		Status:       models.ShareStatusPending,
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.Share
+		err := tx.Where("resource_type = ? AND resource_id = ? AND user_id = ?", resourceType, resourceID, invitee.ID).
+			First(&existing).Error
+		if err == nil {
+			if existing.Status != models.ShareStatusDeclined {
+				return utils.NewConflictError("Already shared with this user")
+			}
+			// 辞退された招待は送り直せる
+			share.ID = existing.ID
+			share.CreatedAt = existing.CreatedAt
+			return tx.Model(&existing).Updates(map[string]interface{}{
+				"invited_by_id": share.InvitedByID,
+				"role":          share.Role,
+				"status":        share.Status,
+				"responded_at":  nil,
+			}).Error
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		return tx.Create(&share).Error
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	respondShare(c, http.StatusCreated, share)
+}
+
+// listShares プロジェクトまたはtodoの共有一覧を取得します（閲覧権限があれば参照可）
+func listShares(c *gin.Context, resourceType string) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	resourceID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid "+resourceType+" ID")
+		return
+	}
+
+	if _, err := authorizeShareResource(database.DB, userID.(uint), resourceType, uint(resourceID), services.RoleViewer); err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	var shares []models.Share
+	if err := database.DB.Where("resource_type = ? AND resource_id = ?", resourceType, resourceID).
+		Order("created_at ASC, id ASC").Find(&shares).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	resp, err := shareResponses(shares)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateProjectShare プロジェクトにユーザーを招待
+func CreateProjectShare(c *gin.Context) {
+	createShare(c, models.ShareResourceProject)
+}
+
+// GetProjectShares プロジェクトの共有一覧を取得
+func GetProjectShares(c *gin.Context) {
+	listShares(c, models.ShareResourceProject)
+}
+
+// CreateTodoShare todo（サブタスクを含む）にユーザーを招待
+func CreateTodoShare(c *gin.Context) {
+	createShare(c, models.ShareResourceTodo)
+}
+
+// GetTodoShares todoの共有一覧を取得
+func GetTodoShares(c *gin.Context) {
+	listShares(c, models.ShareResourceTodo)
+}
+
+// findShare URLの共有を取得し、見つからなければエラーレスポンスを返します
+func findShare(c *gin.Context) (*models.Share, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid share ID")
+		return nil, false
+	}
+
+	var share models.Share
+	if err := database.DB.First(&share, id).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Share not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	return &share, true
+}
+
+// UpdateShare 共有の権限を変更（対象のowner権限が必要）
+func UpdateShare(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req UpdateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	share, ok := findShare(c)
+	if !ok {
+		return
+	}
+
+	if _, err := authorizeShareResource(database.DB, userID.(uint), share.ResourceType, share.ResourceID, services.RoleOwner); err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	if err := database.DB.Model(share).Update("role", req.Role).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	database.DB.First(share, share.ID)
+	respondShare(c, http.StatusOK, *share)
+}
+
+// DeleteShare 共有を取り消す（対象のowner権限が必要。招待されたユーザー自身は共有から抜けられる）
+func DeleteShare(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	share, ok := findShare(c)
+	if !ok {
+		return
+	}
+
+	if share.UserID != userID.(uint) {
+		if _, err := authorizeShareResource(database.DB, userID.(uint), share.ResourceType, share.ResourceID, services.RoleViewer); err != nil {
+			utils.RespondAPIError(c, err)
+			return
+		}
+		if _, err := authorizeShareResource(database.DB, userID.(uint), share.ResourceType, share.ResourceID, services.RoleOwner); err != nil {
+			utils.RespondForbidden(c, "Only owners can remove other users")
+			return
+		}
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(share).Error; err != nil {
+			return err
+		}
+		// 共有されていたtodoが見えなくなるため、差分同期をやり直させる
+		if share.Status == models.ShareStatusAccepted {
+			return services.ResetSync(tx, []uint{share.UserID})
+		}
+		return nil
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetInvitations 自分宛ての未回答の招待一覧を取得
+func GetInvitations(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var shares []models.Share
+	if err := database.DB.Where("user_id = ? AND status = ?", userID, models.ShareStatusPending).
+		Order("created_at DESC, id DESC").Find(&shares).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	resp, err := shareResponses(shares)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondInvitation 自分宛ての招待を承諾・辞退します
+func respondInvitation(c *gin.Context, status string) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	share, ok := findShare(c)
+	if !ok {
+		return
+	}
+
+	// 他のユーザー宛ての招待は存在を明かさない
+	if share.UserID != userID.(uint) {
+		utils.RespondNotFound(c, "Invitation not found")
+		return
+	}
+	if share.Status != models.ShareStatusPending {
+		utils.RespondConflict(c, "Invitation has already been "+share.Status)
+		return
+	}
+
+	updates := map[string]interface{}{
+		"status":       status,
+		"responded_at": time.Now(),
+	}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(share).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 共有されたtodoが見えるようになるため、差分同期をやり直させる
+		if status == models.ShareStatusAccepted {
+			return services.ResetSync(tx, []uint{share.UserID})
+		}
+		return nil
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	database.DB.First(share, share.ID)
+	respondShare(c, http.StatusOK, *share)
+}
+
+// AcceptInvitation 招待を承諾（共有されたプロジェクト・todoにアクセスできるようになる）
+func AcceptInvitation(c *gin.Context) {
+	respondInvitation(c, models.ShareStatusAccepted)
+}
+
+// DeclineInvitation 招待を辞退
+func DeclineInvitation(c *gin.Context) {
+	respondInvitation(c, models.ShareStatusDeclined)
+}
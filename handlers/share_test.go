@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database/dbtest"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+// shareFixture 共有プロジェクトとサブタスク、それぞれの共有
+type shareFixture struct {
+	// delegateは親のtodoをowner権限で共有されたユーザー、todoViewerは親のtodoをviewer権限で共有されたユーザー
+	owner, editor, pending, todoViewer, delegate, childGuest, stranger models.User
+
+	// projectShareはeditorのプロジェクトの共有、childShareはchildGuestのサブタスクの共有
+	projectShare, childShare models.Share
+}
+
+func newShareFixture(t *testing.T, db *gorm.DB) *shareFixture {
+	t.Helper()
+	f := &shareFixture{}
+	for _, user := range []*models.User{&f.owner, &f.editor, &f.pending, &f.todoViewer, &f.delegate, &f.childGuest, &f.stranger} {
+		*user = dbtest.CreateUser(t, db)
+	}
+
+	project := models.Project{UserID: f.owner.ID, Name: "共有プロジェクト"}
+	dbtest.Create(t, db, &project)
+	root := models.Todo{UserID: f.owner.ID, Title: "root", ProjectID: &project.ID}
+	dbtest.Create(t, db, &root)
+	child := models.Todo{UserID: f.owner.ID, Title: "child", ProjectID: &project.ID, ParentID: &root.ID}
+	dbtest.Create(t, db, &child)
+
+	share := func(resourceType string, resourceID uint, user models.User, role, status string) models.Share {
+		s := models.Share{
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			UserID:       user.ID,
+			InvitedByID:  f.owner.ID,
+			Role:         role,
+			Status:       status,
+		}
+		dbtest.Create(t, db, &s)
+		return s
+	}
+	f.projectShare = share(models.ShareResourceProject, project.ID, f.editor, "editor", models.ShareStatusAccepted)
+	share(models.ShareResourceProject, project.ID, f.pending, "owner", models.ShareStatusPending)
+	share(models.ShareResourceTodo, root.ID, f.todoViewer, "viewer", models.ShareStatusAccepted)
+	share(models.ShareResourceTodo, root.ID, f.delegate, "owner", models.ShareStatusAccepted)
+	f.childShare = share(models.ShareResourceTodo, child.ID, f.childGuest, "viewer", models.ShareStatusAccepted)
+	return f
+}
+
+// callShareHandler userIDのユーザーとして/shares/:idのハンドラーを呼び出し、ステータスコードを返します
+func callShareHandler(handler gin.HandlerFunc, method string, userID, shareID uint, body string) int {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, "/shares/"+strconv.FormatUint(uint64(shareID), 10), strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: strconv.FormatUint(uint64(shareID), 10)}}
+	c.Set(middleware.UserIDKey, userID)
+	handler(c)
+	return c.Writer.Status()
+}
+
+func TestUpdateShare(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor func(f *shareFixture) models.User
+		share func(f *shareFixture) models.Share
+		want  int
+	}{
+		{"project owner", func(f *shareFixture) models.User { return f.owner }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusOK},
+		{"editor cannot change roles", func(f *shareFixture) models.User { return f.editor }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusForbidden},
+		{"todo share does not grant the project", func(f *shareFixture) models.User { return f.todoViewer }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNotFound},
+		{"pending owner invitation grants nothing", func(f *shareFixture) models.User { return f.pending }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNotFound},
+		{"stranger", func(f *shareFixture) models.User { return f.stranger }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNotFound},
+		{"owner role inherited from parent todo", func(f *shareFixture) models.User { return f.delegate }, func(f *shareFixture) models.Share { return f.childShare }, http.StatusOK},
+		{"viewer role inherited from parent todo", func(f *shareFixture) models.User { return f.todoViewer }, func(f *shareFixture) models.Share { return f.childShare }, http.StatusForbidden},
+		{"invitee cannot raise own role", func(f *shareFixture) models.User { return f.childGuest }, func(f *shareFixture) models.Share { return f.childShare }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			f := newShareFixture(t, db)
+			share := tt.share(f)
+
+			got := callShareHandler(UpdateShare, http.MethodPatch, tt.actor(f).ID, share.ID, `{"role": "editor"}`)
+			if got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+
+			var updated models.Share
+			if err := db.First(&updated, share.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			wantRole := share.Role
+			if tt.want == http.StatusOK {
+				wantRole = "editor"
+			}
+			if updated.Role != wantRole {
+				t.Errorf("role = %s, want %s", updated.Role, wantRole)
+			}
+		})
+	}
+}
+
+func TestDeleteShare(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor func(f *shareFixture) models.User
+		share func(f *shareFixture) models.Share
+		want  int
+	}{
+		{"project owner", func(f *shareFixture) models.User { return f.owner }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNoContent},
+		{"invitee leaves", func(f *shareFixture) models.User { return f.editor }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNoContent},
+		{"pending owner invitation grants nothing", func(f *shareFixture) models.User { return f.pending }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNotFound},
+		{"stranger", func(f *shareFixture) models.User { return f.stranger }, func(f *shareFixture) models.Share { return f.projectShare }, http.StatusNotFound},
+		{"owner role inherited from parent todo", func(f *shareFixture) models.User { return f.delegate }, func(f *shareFixture) models.Share { return f.childShare }, http.StatusNoContent},
+		{"viewer cannot remove other users", func(f *shareFixture) models.User { return f.todoViewer }, func(f *shareFixture) models.Share { return f.childShare }, http.StatusForbidden},
+		{"project editor cannot remove other users", func(f *shareFixture) models.User { return f.editor }, func(f *shareFixture) models.Share { return f.childShare }, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := dbtest.Open(t)
+			f := newShareFixture(t, db)
+			share := tt.share(f)
+
+			got := callShareHandler(DeleteShare, http.MethodDelete, tt.actor(f).ID, share.ID, "")
+			if got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+
+			var count int64
+			if err := db.Model(&models.Share{}).Where("id = ?", share.ID).Count(&count).Error; err != nil {
+				t.Fatal(err)
+			}
+			if deleted := count == 0; deleted != (tt.want == http.StatusNoContent) {
+				t.Errorf("share deleted = %v after status %d", deleted, got)
+			}
+		})
+	}
+}
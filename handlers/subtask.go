@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// TodoTreeNode サブタスクを含むtodoのツリー
+type TodoTreeNode struct {
+	models.Todo
+	Children []*TodoTreeNode `json:"children"`
+}
+
+var errParentCycle = utils.NewBadRequestError("parent_id would create a cycle")
+
+var errParentProject = utils.NewBadRequestError("parent_id must be in the same project as the todo")
+
+// descendantIDs todoの子孫（子・孫...）のうちゴミ箱にないもののIDを取得します
+func descendantIDs(tx *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM todos WHERE parent_id = ? AND deleted_at IS NULL
+			UNION
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at IS NULL
+		)
+		SELECT id FROM subtree`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// trashedDescendantIDs 親と一緒にゴミ箱に入った（削除日時が同じ）子孫のIDを取得します
+func trashedDescendantIDs(tx *gorm.DB, id uint, deletedAt time.Time) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw(`WITH RECURSIVE subtree AS (
+			SELECT id FROM todos WHERE parent_id = ? AND deleted_at = ?
+			UNION
+			SELECT t.id FROM todos t JOIN subtree s ON t.parent_id = s.id WHERE t.deleted_at = ?
+		)
+		SELECT id FROM subtree`, id, deletedAt, deletedAt).Scan(&ids).Error
+	return ids, err
+}
+
+// ancestorIDs todo自身とその祖先のIDを取得します
+func ancestorIDs(tx *gorm.DB, id uint) ([]uint, error) {
+	var ids []uint
+	err := tx.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM todos WHERE id = ?
+			UNION
+			SELECT t.id, t.parent_id FROM todos t JOIN ancestors a ON t.id = a.parent_id
+		)
+		SELECT id FROM ancestors`, id).Scan(&ids).Error
+	return ids, err
+}
+
+// validateParent 親に指定されたtodoにサブタスクを追加でき（editor以上）、循環参照にならないか検証します
+// todoIDは更新対象のtodo（新規作成時は0）
+func validateParent(tx *gorm.DB, userID uint, todoID, parentID uint) (*models.Todo, error) {
+	parent, err := authorizeTodo(tx, userID, parentID, services.RoleEditor)
+	if err != nil {
+		var apiErr *utils.APIError
+		if errors.As(err, &apiErr) && apiErr.Code == utils.ErrorCodeNotFound {
+			return nil, utils.NewNotFoundError("Parent todo not found")
+		}
+		return nil, err
+	}
+	if todoID == 0 {
+		return parent, nil
+	}
+
+	// 新しい親の祖先に自分自身が含まれていれば循環する
+	ancestors, err := ancestorIDs(tx, parentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ancestors {
+		if id == todoID {
+			return nil, errParentCycle
+		}
+	}
+	return parent, nil
+}
+
+// checkParentProject サブタスクが親と同じプロジェクトに属するか検証します（projectIDはサブタスクの所属先）
+func checkParentProject(parent *models.Todo, projectID *uint) error {
+	if !sameProjectID(parent.ProjectID, projectID) {
+		return errParentProject
+	}
+	return nil
+}
+
+func sameProjectID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// loadCompletionRules todoの持ち主のサブタスク完了ルールを取得します
+func loadCompletionRules(tx *gorm.DB, userID uint) (models.User, error) {
+	var user models.User
+	err := tx.Select("id", "cascade_completion", "auto_complete_parent").First(&user, userID).Error
+	return user, err
+}
+
+// propagateCompletion todoの完了状態を子孫・祖先に反映します（変更履歴はactorIDの変更として記録）
+func propagateCompletion(tx *gorm.DB, todo *models.Todo, actorID uint) error {
+	rules, err := loadCompletionRules(tx, todo.UserID)
+	if err != nil {
+		return err
+	}
+
+	if todo.Completed && rules.CascadeCompletion {
+		ids, err := descendantIDs(tx, todo.ID)
+		if err != nil {
+			return err
+		}
+		var changed []uint
+		if len(ids) > 0 {
+			if err := tx.Model(&models.Todo{}).Where("id IN ? AND completed = ?", ids, false).Pluck("id", &changed).Error; err != nil {
+				return err
+			}
+		}
+		if err := changeTodos(tx, actorID, changed, func() error {
+			return tx.Model(&models.Todo{}).Where("id IN ?", changed).Update("completed", true).Error
+		}); err != nil {
+			return err
+		}
+	}
+
+	if rules.AutoCompleteParent && todo.ParentID != nil {
+		return syncParentCompletion(tx, actorID, *todo.ParentID)
+	}
+	return nil
+}
+
+// syncParentCompletion 子の完了状態に合わせて親を自動完了・再開し、祖先へ順に反映します
+func syncParentCompletion(tx *gorm.DB, actorID uint, parentID uint) error {
+	next := &parentID
+	for next != nil {
+		var parent models.Todo
+		if err := tx.First(&parent, *next).Error; err != nil {
+			return err
+		}
+
+		var total, open int64
+		if err := tx.Model(&models.Todo{}).Where("parent_id = ?", parent.ID).Count(&total).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Todo{}).Where("parent_id = ? AND completed = ?", parent.ID, false).Count(&open).Error; err != nil {
+			return err
+		}
+
+		// 子がいなくなった場合は親の状態をそのまま残す
+		completed := open == 0
+		if total == 0 || parent.Completed == completed {
+			return nil
+		}
+		if err := changeTodos(tx, actorID, []uint{parent.ID}, func() error {
+			return tx.Model(&parent).Update("completed", completed).Error
+		}); err != nil {
+			return err
+		}
+		next = parent.ParentID
+	}
+	return nil
+}
+
+// syncParentIfEnabled 自動完了ルールが有効な場合に親の完了状態を同期します（子の追加・移動・削除時）
+// userIDはtodoの持ち主（ルールの設定を読む）、actorIDは変更したユーザーです
+func syncParentIfEnabled(tx *gorm.DB, userID, actorID uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	rules, err := loadCompletionRules(tx, userID)
+	if err != nil {
+		return err
+	}
+	if !rules.AutoCompleteParent {
+		return nil
+	}
+	return syncParentCompletion(tx, actorID, *parentID)
+}
+
+// GetTodoTree todoとそのサブタスクをツリーで取得
+func GetTodoTree(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
+		return
+	}
+
+	var root models.Todo
+	if err := database.DB.Preload("Labels").First(&root, todo.ID).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	ids, err := descendantIDs(database.DB, root.ID)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	var descendants []models.Todo
+	if len(ids) > 0 {
+		if err := database.DB.Preload("Labels").Where("id IN ?", ids).Order("created_at ASC, id ASC").Find(&descendants).Error; err != nil {
+			statusCode, message := utils.HandleDBError(err)
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, buildTodoTree(root, descendants))
+}
+
+// buildTodoTree 子孫の一覧からツリーを組み立てます
+func buildTodoTree(root models.Todo, descendants []models.Todo) *TodoTreeNode {
+	rootNode := &TodoTreeNode{Todo: root, Children: []*TodoTreeNode{}}
+	nodes := map[uint]*TodoTreeNode{root.ID: rootNode}
+	for _, todo := range descendants {
+		nodes[todo.ID] = &TodoTreeNode{Todo: todo, Children: []*TodoTreeNode{}}
+	}
+	// descendantsは作成順なので子の並びも作成順になる
+	for _, todo := range descendants {
+		if parent, ok := nodes[*todo.ParentID]; ok {
+			parent.Children = append(parent.Children, nodes[todo.ID])
+		}
+	}
+	return rootNode
+}
@@ -0,0 +1,411 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// maxSyncPushChanges 1回のPOST /syncで送れる変更の上限
+const maxSyncPushChanges = 100
+
+// 送られた変更ごとの結果
+const (
+	syncStatusApplied  = "applied"
+	syncStatusConflict = "conflict" // サーバー側の変更を優先したため適用しなかった
+	syncStatusFailed   = "failed"
+)
+
+// 競合の理由
+const (
+	syncConflictModified = "modified" // base_versionより後にサーバー側で更新された
+	syncConflictDeleted  = "deleted"  // サーバー側で削除された
+)
+
+// SyncChange 差分同期で返す1件の変更
+// 削除されたtodo（ゴミ箱に入れた・完全に削除した）はdeletedがtrueの墓標として返し、todoは含めません
+type SyncChange struct {
+	ID        uint         `json:"id"`
+	ChangeSeq int64        `json:"change_seq"`
+	Deleted   bool         `json:"deleted"`
+	DeletedAt *time.Time   `json:"deleted_at,omitempty"`
+	Todo      *models.Todo `json:"todo,omitempty"`
+}
+
+// SyncResponse 差分同期のレスポンス
+// next_tokenを次のsinceに指定すると続きの変更を取得でき、has_moreがtrueの間は続けて取得します
+type SyncResponse struct {
+	Changes   []SyncChange `json:"changes"`
+	NextToken string       `json:"next_token"`
+	HasMore   bool         `json:"has_more"`
+}
+
+// SyncPushChange クライアントで行った変更
+// opはcreate / update / delete。dataにはPOST /todos・PATCH /todos/:idと同じ内容を指定します
+// base_versionはクライアントが最後に同期したtodoのversionで、省略するとサーバー側の変更に関係なく上書きします
+// client_idはcreateでクライアントが付けたIDで、同じclient_idのcreateを再送しても重複して作成しません
+type SyncPushChange struct {
+	Op          string          `json:"op" binding:"required,oneof=create update delete"`
+	ID          uint            `json:"id"`
+	ClientID    string          `json:"client_id" binding:"max=64"`
+	BaseVersion *uint           `json:"base_version"`
+	Children    string          `json:"children"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// SyncPushRequest 変更の送信リクエスト
+type SyncPushRequest struct {
+	Changes []SyncPushChange `json:"changes" binding:"required,min=1,dive"`
+}
+
+// SyncPushResult 送られた変更ごとの結果
+// conflictの場合、todoにはサーバー側の現在のtodoが入ります（削除された場合は含めない）
+type SyncPushResult struct {
+	Index    int                `json:"index"`
+	Op       string             `json:"op"`
+	ClientID string             `json:"client_id,omitempty"`
+	Status   string             `json:"status"`
+	Reason   string             `json:"reason,omitempty"`
+	Todo     *models.Todo       `json:"todo,omitempty"`
+	Error    *utils.ErrorDetail `json:"error,omitempty"`
+}
+
+// SyncPushResponse 変更の送信レスポンス
+type SyncPushResponse struct {
+	Results []SyncPushResult `json:"results"`
+}
+
+// errSyncTokenExpired 墓標の保持期間より前に発行された変更トークンのエラー
+var errSyncTokenExpired = utils.NewResyncRequiredError("Sync token has expired")
+
+// errSyncReset 共有の変更で閲覧できるtodoが入れ替わった後の変更トークンのエラー
+var errSyncReset = utils.NewResyncRequiredError("Shared todos have changed")
+
+// loadSyncChanges 変更トークンより後の変更を取得し、次の変更トークンとまだ続きがあるかを返します
+// 変更番号は採番した順にコミットされるとは限らないため、前回の発行時に実行中だったトランザクションの変更（XMin以降）は
+// 変更番号が前回より小さくても返し直します（クライアントは同じ変更を何度受け取っても上書きするだけで済む）
+func loadSyncChanges(tx *gorm.DB, userID uint, since *utils.SyncToken, limit int) ([]SyncChange, utils.SyncToken, bool, error) {
+	var next utils.SyncToken
+	var user struct {
+		SyncResetAt *time.Time
+	}
+	if err := tx.Model(&models.User{}).Select("sync_reset_at").Where("id = ?", userID).Scan(&user).Error; err != nil {
+		return nil, next, false, err
+	}
+	if user.SyncResetAt != nil {
+		next.ResetAt = user.SyncResetAt.UnixMicro()
+	}
+	if since != nil && since.ResetAt != next.ResetAt {
+		return nil, next, false, errSyncReset
+	}
+	// このトランザクションのスナップショットで実行中だった最古のトランザクション（これより前のものはすべて見えている）
+	if err := tx.Raw("SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint").Scan(&next.XMin).Error; err != nil {
+		return nil, next, false, err
+	}
+	next.IssuedAt = time.Now().Unix()
+
+	var seq int64
+	if since != nil {
+		seq = since.Seq
+	}
+	next.Seq = seq
+
+	todos := tx.Unscoped().Model(&models.Todo{}).Scopes(services.VisibleTodos(userID)).Preload("Labels")
+	tombstones := tx.Model(&models.TodoTombstone{}).Where("user_id = ?", userID)
+
+	var changes []SyncChange
+	if since != nil {
+		// 前回の発行時に実行中で、その後にコミットされた変更
+		xmin := strconv.FormatUint(since.XMin, 10)
+		var lateTodos []models.Todo
+		if err := todos.Session(&gorm.Session{}).
+			Where("todos.change_xid >= ?::xid8 AND todos.change_seq <= ?", xmin, seq).
+			Find(&lateTodos).Error; err != nil {
+			return nil, next, false, err
+		}
+		var lateTombstones []models.TodoTombstone
+		if err := tombstones.Session(&gorm.Session{}).
+			Where("change_xid >= ?::xid8 AND change_seq <= ?", xmin, seq).
+			Find(&lateTombstones).Error; err != nil {
+			return nil, next, false, err
+		}
+		changes = mergeSyncChanges(lateTodos, lateTombstones)
+	}
+
+	var freshTodos []models.Todo
+	query := todos.Session(&gorm.Session{}).Where("todos.change_seq > ?", seq)
+	if since == nil {
+		// 初回の同期では削除済みのtodoは不要
+		query = query.Where("todos.deleted_at IS NULL")
+	}
+	if err := query.Order("todos.change_seq ASC").Limit(limit + 1).Find(&freshTodos).Error; err != nil {
+		return nil, next, false, err
+	}
+	var freshTombstones []models.TodoTombstone
+	if since != nil {
+		if err := tombstones.Session(&gorm.Session{}).Where("change_seq > ?", seq).
+			Order("change_seq ASC").Limit(limit + 1).Find(&freshTombstones).Error; err != nil {
+			return nil, next, false, err
+		}
+	}
+
+	fresh := mergeSyncChanges(freshTodos, freshTombstones)
+	hasMore := len(fresh) > limit
+	if hasMore {
+		fresh = fresh[:limit]
+	}
+	if len(fresh) > 0 {
+		next.Seq = fresh[len(fresh)-1].ChangeSeq
+	}
+	return append(changes, fresh...), next, hasMore, nil
+}
+
+// mergeSyncChanges todoと墓標を変更番号の順に並べた変更にします
+func mergeSyncChanges(todos []models.Todo, tombstones []models.TodoTombstone) []SyncChange {
+	changes := make([]SyncChange, 0, len(todos)+len(tombstones))
+	for i := range todos {
+		todo := &todos[i]
+		change := SyncChange{ID: todo.ID, ChangeSeq: todo.ChangeSeq}
+		if todo.DeletedAt.Valid {
+			change.Deleted = true
+			change.DeletedAt = &todo.DeletedAt.Time
+		} else {
+			change.Todo = todo
+		}
+		changes = append(changes, change)
+	}
+	for i := range tombstones {
+		tombstone := &tombstones[i]
+		changes = append(changes, SyncChange{
+			ID:        tombstone.TodoID,
+			ChangeSeq: tombstone.ChangeSeq,
+			Deleted:   true,
+			DeletedAt: &tombstone.DeletedAt,
+		})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].ChangeSeq < changes[j].ChangeSeq })
+	return changes
+}
+
+// GetSync 変更トークン（?since）より後に作成・更新・削除された、閲覧できるtodoを取得
+// sinceを省略すると、削除されていないすべてのtodoと最初の変更トークンを返します
+// トークンが墓標の保持期間より古い、または共有の変更で閲覧できるtodoが入れ替わった場合は410 Goneを返すので、sinceなしで同期し直してください
+func GetSync(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	var since *utils.SyncToken
+	if s := c.Query("since"); s != "" {
+		token, err := utils.DecodeSyncToken(s)
+		if err != nil {
+			utils.RespondBadRequest(c, err.Error())
+			return
+		}
+		if time.Since(time.Unix(token.IssuedAt, 0)) > services.TombstoneRetention() {
+			utils.RespondAPIError(c, errSyncTokenExpired)
+			return
+		}
+		since = &token
+	}
+
+	var resp SyncResponse
+	// すべての読み取りを同じスナップショットで行い、トークンに記録する実行中のトランザクションと食い違わないようにする
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		changes, next, hasMore, err := loadSyncChanges(tx, userID.(uint), since, limit)
+		if err != nil {
+			return err
+		}
+		resp = SyncResponse{Changes: changes, NextToken: utils.EncodeSyncToken(next), HasMore: hasMore}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// findSyncedTodo 自分が同じclient_idで作成済みのtodoを探します（見つからなければnil）
+// 共有プロジェクトのtodoは持ち主が別のユーザーになるため、作成したユーザーで探します
+func findSyncedTodo(tx *gorm.DB, userID uint, clientID string) (*models.Todo, error) {
+	var todo models.Todo
+	err := tx.Unscoped().Scopes(services.VisibleTodos(userID)).Preload("Labels").
+		Where("todos.client_user_id = ? AND todos.client_id = ?", userID, clientID).First(&todo).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// isTodoDeleted 閲覧できるtodoがゴミ箱に入っている、または完全に削除されているかを判定します
+func isTodoDeleted(tx *gorm.DB, userID uint, id uint) (bool, error) {
+	var count int64
+	if err := tx.Unscoped().Model(&models.Todo{}).Scopes(services.VisibleTodos(userID)).
+		Where("todos.id = ? AND todos.deleted_at IS NOT NULL", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+	if err := tx.Model(&models.TodoTombstone{}).Where("user_id = ? AND todo_id = ?", userID, id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// pushSyncChange 1つの変更を適用します
+func pushSyncChange(tx *gorm.DB, userID uint, change SyncPushChange) (*models.Todo, error) {
+	if change.Op == "create" && change.ClientID != "" {
+		// 応答を受け取れずに再送されたcreateは、作成済みのtodoを返す
+		existing, err := findSyncedTodo(tx, userID, change.ClientID)
+		if err != nil || existing != nil {
+			return existing, err
+		}
+	}
+
+	op := BatchOperation{Op: change.Op, ID: change.ID, Children: change.Children, Data: change.Data}
+	if change.BaseVersion != nil {
+		op.IfMatch = models.FormatETag(*change.BaseVersion)
+	}
+	todo, err := runBatchOperation(tx, userID, op)
+	if err != nil {
+		return nil, err
+	}
+	if change.Op == "create" && change.ClientID != "" {
+		if err := tx.Model(todo).UpdateColumns(map[string]interface{}{
+			"client_id":      change.ClientID,
+			"client_user_id": userID,
+		}).Error; err != nil {
+			return nil, err
+		}
+		// トリガーで採番し直された変更番号を返すよう読み直す
+		if err := tx.Preload("Labels").First(todo, todo.ID).Error; err != nil {
+			return nil, err
+		}
+	}
+	return todo, nil
+}
+
+// resolveSyncConflict 適用できなかった変更がサーバー側の変更と競合したものかを判定し、競合なら結果に反映します
+func resolveSyncConflict(tx *gorm.DB, userID uint, change SyncPushChange, cause error, result *SyncPushResult) (bool, error) {
+	if change.Op == "create" {
+		return false, nil
+	}
+	if errors.Is(cause, errTodoModified) {
+		var todo models.Todo
+		if err := tx.Preload("Labels").First(&todo, change.ID).Error; err != nil {
+			return false, err
+		}
+		result.Status = syncStatusConflict
+		result.Reason = syncConflictModified
+		result.Todo = &todo
+		return true, nil
+	}
+
+	var apiErr *utils.APIError
+	if !errors.As(cause, &apiErr) || apiErr.Status != http.StatusNotFound {
+		return false, nil
+	}
+	deleted, err := isTodoDeleted(tx, userID, change.ID)
+	if err != nil || !deleted {
+		return false, err
+	}
+	if change.Op == "delete" {
+		// 削除済みのtodoの削除は、すでに望んだ状態なので適用済みとする
+		result.Status = syncStatusApplied
+	} else {
+		result.Status = syncStatusConflict
+		result.Reason = syncConflictDeleted
+	}
+	return true, nil
+}
+
+// PushSync オフラインの間にクライアントで行った変更を、送られた順に適用
+// 変更ごとに独立して適用し、失敗・競合した変更があっても他の変更は適用します
+//
+// 競合の解決方針:
+//   - base_versionがサーバーのversionと一致すれば適用する。一致しなければ適用せず、conflict（modified）とサーバーのtodoを返す
+//   - base_versionを省略したupdate / deleteは、サーバー側の変更に関係なく適用する（後勝ち）
+//   - サーバー側で削除されたtodoのupdateは適用せず、conflict（deleted）を返す（削除を優先する）
+//   - サーバー側で削除されたtodoのdeleteは適用済み（applied）とする
+//   - 同じclient_idのcreateは一度だけ作成し、再送には作成済みのtodoを返す
+func PushSync(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req SyncPushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	if len(req.Changes) > maxSyncPushChanges {
+		utils.RespondBadRequest(c, fmt.Sprintf("changes must not exceed %d", maxSyncPushChanges))
+		return
+	}
+
+	results := make([]SyncPushResult, len(req.Changes))
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, change := range req.Changes {
+			results[i] = SyncPushResult{Index: i, Op: change.Op, ClientID: change.ClientID}
+
+			// 各変更はセーブポイント内で適用し、失敗した変更だけを巻き戻す
+			var todo *models.Todo
+			err := tx.Transaction(func(sp *gorm.DB) error {
+				var err error
+				todo, err = pushSyncChange(sp, userID.(uint), change)
+				return err
+			})
+			if err == nil {
+				results[i].Status = syncStatusApplied
+				results[i].Todo = todo
+				continue
+			}
+
+			conflict, resolveErr := resolveSyncConflict(tx, userID.(uint), change, err, &results[i])
+			if resolveErr != nil {
+				return resolveErr
+			}
+			if !conflict {
+				detail := utils.ToAPIError(err).Detail()
+				results[i].Status = syncStatusFailed
+				results[i].Error = &detail
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SyncPushResponse{Results: results})
+}
@@ -1,28 +1,295 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go-gin-todo-api/database"
 	"go-gin-todo-api/middleware"
 	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
 	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateTodoRequest Todo作成リクエスト
 type CreateTodoRequest struct {
-	Title string `json:"title" binding:"required"`
+	Title     string     `json:"title" binding:"required"`
+	ProjectID *uint      `json:"project_id"`
+	ParentID  *uint      `json:"parent_id"`
+	StartAt   *time.Time `json:"start_at"`
+	DueAt     *time.Time `json:"due_at"`
+	RRule     *string    `json:"rrule"`
+	Priority  *string    `json:"priority"`
+	LabelIDs  []uint     `json:"label_ids"`
 }
 
 // UpdateTodoRequest Todo更新リクエスト
+// project_id / parent_id / start_at / due_at / rrule / priority はnullを指定するとクリアされます
+// label_idsはラベルを丸ごと置き換え、add_label_ids / remove_label_idsは差分で付け外しします
 type UpdateTodoRequest struct {
-	Title     *string `json:"title"`
-	Completed *bool   `json:"completed"`
+	Title          *string                   `json:"title"`
+	Completed      *bool                     `json:"completed"`
+	ProjectID      utils.Optional[uint]      `json:"project_id"`
+	ParentID       utils.Optional[uint]      `json:"parent_id"`
+	StartAt        utils.Optional[time.Time] `json:"start_at"`
+	DueAt          utils.Optional[time.Time] `json:"due_at"`
+	RRule          utils.Optional[string]    `json:"rrule"`
+	Priority       utils.Optional[string]    `json:"priority"`
+	LabelIDs       *[]uint                   `json:"label_ids"`
+	AddLabelIDs    []uint                    `json:"add_label_ids"`
+	RemoveLabelIDs []uint                    `json:"remove_label_ids"`
 }
 
-// GetTodos 自分のtodo一覧を取得
+// hasLabelChanges ラベルの付け外しが指定されているか
+func (r UpdateTodoRequest) hasLabelChanges() bool {
+	return r.LabelIDs != nil || len(r.AddLabelIDs) > 0 || len(r.RemoveLabelIDs) > 0
+}
+
+// applyLabelChanges リクエストに従ってtodoのラベルを付け外しします
+func applyLabelChanges(tx *gorm.DB, todo *models.Todo, userID interface{}, req UpdateTodoRequest) error {
+	if req.LabelIDs != nil {
+		labels, err := findOwnedLabels(tx, userID, *req.LabelIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(todo).Association("Labels").Replace(labels); err != nil {
+			return err
+		}
+	}
+	if len(req.AddLabelIDs) > 0 {
+		labels, err := findOwnedLabels(tx, userID, req.AddLabelIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(todo).Association("Labels").Append(labels); err != nil {
+			return err
+		}
+	}
+	if len(req.RemoveLabelIDs) > 0 {
+		labels, err := findOwnedLabels(tx, userID, req.RemoveLabelIDs)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(todo).Association("Labels").Delete(labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateSchedule 開始日時が期限より後になっていないか検証します
+func validateSchedule(startAt, dueAt *time.Time) error {
+	if startAt != nil && dueAt != nil && startAt.After(*dueAt) {
+		return fmt.Errorf("start_at must not be after due_at")
+	}
+	return nil
+}
+
+// normalizePriority 優先度（todo.txtと同じくAが最も高いA〜Zの1文字）を検証して大文字にします
+func normalizePriority(priority string) (string, error) {
+	p := strings.ToUpper(strings.TrimSpace(priority))
+	if len(p) != 1 || p[0] < 'A' || p[0] > 'Z' {
+		return "", fmt.Errorf("priority must be a single letter from A to Z")
+	}
+	return p, nil
+}
+
+// TodoListResponse Todo一覧レスポンス
+type TodoListResponse struct {
+	Items      []models.Todo `json:"items"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+// todoSortColumns ソートに使用できるカラム
+var todoSortColumns = map[string]bool{
+	"id":         true,
+	"title":      true,
+	"completed":  true,
+	"created_at": true,
+	"updated_at": true,
+}
+
+// todoSort ソート指定（"-created_at"のように先頭の"-"で降順）
+type todoSort struct {
+	Column string
+	Desc   bool
+}
+
+func parseTodoSort(s string) (todoSort, error) {
+	sort := todoSort{Column: s}
+	if strings.HasPrefix(s, "-") {
+		sort = todoSort{Column: s[1:], Desc: true}
+	}
+	if !todoSortColumns[sort.Column] {
+		return sort, fmt.Errorf("Invalid sort: %s", s)
+	}
+	return sort, nil
+}
+
+func (s todoSort) String() string {
+	if s.Desc {
+		return "-" + s.Column
+	}
+	return s.Column
+}
+
+// OrderClause ORDER BY句を返します（同値の場合はidで順序を確定させる）
+func (s todoSort) OrderClause() string {
+	dir := "ASC"
+	if s.Desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, id %s", s.Column, dir, dir)
+}
+
+// cursorValue カーソルに保存するソートキーを文字列化します
+func (s todoSort) cursorValue(todo models.Todo) string {
+	switch s.Column {
+	case "title":
+		return todo.Title
+	case "completed":
+		return strconv.FormatBool(todo.Completed)
+	case "created_at":
+		return todo.CreatedAt.Format(time.RFC3339Nano)
+	case "updated_at":
+		return todo.UpdatedAt.Format(time.RFC3339Nano)
+	case "due_at":
+		// 期限ビューでのみ使用（due_atがNULLの行は対象外）
+		return todo.DueAt.Format(time.RFC3339Nano)
+	case "deleted_at":
+		// ゴミ箱でのみ使用
+		return todo.DeletedAt.Time.Format(time.RFC3339Nano)
+	default:
+		return strconv.FormatUint(uint64(todo.ID), 10)
+	}
+}
+
+// applyCursor カーソル位置より後ろの行だけに絞り込みます
+func (s todoSort) applyCursor(query *gorm.DB, cursor utils.Cursor) (*gorm.DB, error) {
+	if cursor.Sort != s.String() {
+		return nil, fmt.Errorf("Cursor does not match sort")
+	}
+
+	var value interface{}
+	var err error
+	switch s.Column {
+	case "title":
+		value = cursor.Value
+	case "completed":
+		value, err = strconv.ParseBool(cursor.Value)
+	case "created_at", "updated_at", "due_at", "deleted_at":
+		value, err = time.Parse(time.RFC3339Nano, cursor.Value)
+	default:
+		value, err = strconv.ParseUint(cursor.Value, 10, 32)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Invalid cursor")
+	}
+
+	op := ">"
+	if s.Desc {
+		op = "<"
+	}
+	return query.Where(fmt.Sprintf("(%s, id) %s (?, ?)", s.Column, op), value, cursor.ID), nil
+}
+
+// todoListParams 一覧取得の絞り込み・ページネーションの指定（RESTのクエリパラメータ、GraphQL・gRPCの引数）
+type todoListParams interface {
+	Query(key string) string
+	DefaultQuery(key, defaultValue string) string
+}
+
+// queryParams GraphQL・gRPCの引数を、RESTの一覧取得と同じクエリパラメータとして扱います
+type queryParams map[string]string
+
+// Query 引数の値を返します（未指定なら空文字列）
+func (p queryParams) Query(key string) string {
+	return p[key]
+}
+
+// DefaultQuery 引数の値を返します（未指定ならdefaultValue）
+func (p queryParams) DefaultQuery(key, defaultValue string) string {
+	if value, ok := p[key]; ok {
+		return value
+	}
+	return defaultValue
+}
+
+// applyTodoFilters 一覧取得のフィルタ条件をクエリに適用します
+func applyTodoFilters(query *gorm.DB, c todoListParams) (*gorm.DB, error) {
+	if completed := c.Query("completed"); completed != "" {
+		value, err := strconv.ParseBool(completed)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid completed: %s", completed)
+		}
+		query = query.Where("completed = ?", value)
+	}
+
+	// project_id=noneでプロジェクト未所属のtodoのみ
+	if projectID := c.Query("project_id"); projectID == "none" {
+		query = query.Where("project_id IS NULL")
+	} else if projectID != "" {
+		value, err := strconv.ParseUint(projectID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid project_id: %s", projectID)
+		}
+		query = query.Where("project_id = ?", value)
+	}
+
+	// parent_id=noneでサブタスクではないtodoのみ
+	if parentID := c.Query("parent_id"); parentID == "none" {
+		query = query.Where("parent_id IS NULL")
+	} else if parentID != "" {
+		value, err := strconv.ParseUint(parentID, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid parent_id: %s", parentID)
+		}
+		query = query.Where("parent_id = ?", value)
+	}
+
+	rangeFilters := []struct {
+		param string
+		cond  string
+	}{
+		{"created_after", "created_at >= ?"},
+		{"created_before", "created_at < ?"},
+		{"updated_after", "updated_at >= ?"},
+		{"updated_before", "updated_at < ?"},
+		{"due_after", "due_at >= ?"},
+		{"due_before", "due_at < ?"},
+	}
+	for _, f := range rangeFilters {
+		raw := c.Query(f.param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid %s: must be RFC3339", f.param)
+		}
+		query = query.Where(f.cond, t)
+	}
+
+	return applyLabelFilter(query, c)
+}
+
+// excludeArchivedProjects アーカイブ済みプロジェクトのtodoを除外します（?include_archived=trueで無効化）
+func excludeArchivedProjects(query *gorm.DB, c todoListParams) *gorm.DB {
+	if c.Query("include_archived") == "true" {
+		return query
+	}
+	return query.Where("project_id IS NULL OR project_id NOT IN (?)",
+		database.DB.Model(&models.Project{}).Select("id").Where("archived_at IS NOT NULL"))
+}
+
+// GetTodos 自分のtodoと共有されたtodoの一覧を取得（キーセットページネーション）
 func GetTodos(c *gin.Context) {
 	userID, exists := c.Get(middleware.UserIDKey)
 	if !exists {
@@ -30,14 +297,567 @@ func GetTodos(c *gin.Context) {
 		return
 	}
 
-	var todos []models.Todo
-	if err := database.DB.Where("user_id = ?", userID).Find(&todos).Error; err != nil {
-		statusCode, message := utils.HandleDBError(err)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+	sort, err := parseTodoSort(c.DefaultQuery("sort", "created_at"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, todos)
+	query, err := applyTodoFilters(database.DB.Scopes(services.VisibleTodos(userID.(uint))), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	respondTodoPage(c, excludeArchivedProjects(query, c), sort)
+}
+
+// listTodos GetTodosと同じ絞り込み・並び順で1ページ分のtodoを取得します（GraphQL・gRPCで共通）
+// queryには閲覧できるtodoに絞り込んだクエリを渡してください
+func listTodos(query *gorm.DB, params todoListParams) (*TodoListResponse, error) {
+	sort, err := parseTodoSort(params.DefaultQuery("sort", "created_at"))
+	if err != nil {
+		return nil, utils.NewBadRequestError(err.Error())
+	}
+	query, err = applyTodoFilters(query, params)
+	if err != nil {
+		return nil, utils.NewBadRequestError(err.Error())
+	}
+	return loadTodoPage(excludeArchivedProjects(query, params), sort, params)
+}
+
+// respondTodoPage limit/cursorクエリに従って1ページ分のtodoを返します
+func respondTodoPage(c *gin.Context, query *gorm.DB, sort todoSort) {
+	resp, err := loadTodoPage(query.Preload("Labels"), sort, c)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// loadTodoPage limit/cursorの指定に従って1ページ分のtodoを取得します
+func loadTodoPage(query *gorm.DB, sort todoSort, c todoListParams) (*TodoListResponse, error) {
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		return nil, utils.NewBadRequestError(err.Error())
+	}
+
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := utils.DecodeCursor(cursorStr)
+		if err != nil {
+			return nil, utils.NewBadRequestError("Invalid cursor")
+		}
+		if query, err = sort.applyCursor(query, cursor); err != nil {
+			return nil, utils.NewBadRequestError(err.Error())
+		}
+	}
+
+	// 次ページの有無を判定するため1件多く取得
+	var todos []models.Todo
+	if err := query.Order(sort.OrderClause()).Limit(limit + 1).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &TodoListResponse{Items: todos}
+	if len(todos) > limit {
+		resp.Items = todos[:limit]
+		last := resp.Items[limit-1]
+		next := utils.EncodeCursor(utils.Cursor{
+			Sort:  sort.String(),
+			Value: sort.cursorValue(last),
+			ID:    last.ID,
+		})
+		resp.NextCursor = &next
+	}
+	return resp, nil
+}
+
+// emitTodoEvent todoの変更を持ち主のwebhookのキューと、閲覧できるユーザーのリアルタイム配信に記録します
+// todoを変更する処理は、一括更新を含めてすべてこれ（またはchangeTodos・emitTodoEventsByID）で通知してください
+func emitTodoEvent(tx *gorm.DB, event string, todo *models.Todo) error {
+	return services.EmitTodoEvent(tx, event, todo)
+}
+
+// emitTodoRemoved todoが元のプロジェクト（oldProjectID）から外れていれば、閲覧できなくなったユーザーに知らせます
+func emitTodoRemoved(tx *gorm.DB, todo *models.Todo, oldProjectID *uint) error {
+	if oldProjectID == nil || sameProjectID(oldProjectID, todo.ProjectID) {
+		return nil
+	}
+	return services.RecordTodoRemoved(tx, todo, *oldProjectID)
+}
+
+// emitTodoEventsByID 一括で変更したtodoをラベル付きで読み直し、1件ずつ通知します（ゴミ箱のtodoも対象）
+func emitTodoEventsByID(tx *gorm.DB, event string, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var todos []models.Todo
+	if err := tx.Unscoped().Preload("Labels").Where("id IN ?", ids).Order("id ASC").Find(&todos).Error; err != nil {
+		return err
+	}
+	for i := range todos {
+		if err := emitTodoEvent(tx, event, &todos[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// changeTodos 複数のtodoをchangeでまとめて変更し、1件ずつ更新したときと同じく変更履歴を記録して通知します
+// changeでは更新日時も進めてください（versionと変更番号が進み、差分同期やETagにも変更が伝わる）
+func changeTodos(tx *gorm.DB, actorID uint, ids []uint, change func() error) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	before, err := loadTodoLabelIDs(tx, ids)
+	if err != nil {
+		return err
+	}
+	var todos []models.Todo
+	if err := tx.Unscoped().Where("id IN ?", ids).Find(&todos).Error; err != nil {
+		return err
+	}
+	snapshots := make(map[uint]models.TodoSnapshot, len(todos))
+	for i := range todos {
+		snapshots[todos[i].ID] = snapshotTodo(&todos[i], before[todos[i].ID])
+	}
+
+	if err := change(); err != nil {
+		return err
+	}
+
+	after, err := loadTodoLabelIDs(tx, ids)
+	if err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Preload("Labels").Where("id IN ?", ids).Order("id ASC").Find(&todos).Error; err != nil {
+		return err
+	}
+	for i := range todos {
+		todo := &todos[i]
+		snapshot := snapshots[todo.ID]
+		if err := recordRevision(tx, todo.ID, actorID, models.RevisionActionUpdate, &snapshot, snapshotTodo(todo, after[todo.ID]), nil); err != nil {
+			return err
+		}
+		if err := emitTodoRemoved(tx, todo, snapshot.ProjectID); err != nil {
+			return err
+		}
+		if err := emitTodoEvent(tx, models.WebhookEventTodoUpdated, todo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errDifferentOwner 持ち主の異なるプロジェクト・親todoを組み合わせた場合のエラー
+var errDifferentOwner = utils.NewBadRequestError("project_id and parent_id must belong to the todo's owner")
+
+// createTodo todoを作成します（バッチ処理からも使うためトランザクションは呼び出し側で開始する）
+// 共有されたプロジェクトや親todoに追加したtodoは、そのプロジェクト・親todoの持ち主のものになります
+func createTodo(tx *gorm.DB, userID uint, req CreateTodoRequest) (*models.Todo, error) {
+	if err := validateSchedule(req.StartAt, req.DueAt); err != nil {
+		return nil, utils.NewBadRequestError(err.Error())
+	}
+
+	ownerID := userID
+	if req.ProjectID != nil {
+		project, err := checkTodoProject(tx, userID, *req.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		ownerID = project.UserID
+	}
+
+	if req.ParentID != nil {
+		parent, err := validateParent(tx, userID, 0, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		if req.ProjectID == nil {
+			// プロジェクトを省略したサブタスクは親と同じプロジェクトに入れる
+			req.ProjectID = parent.ProjectID
+		} else {
+			if err := checkParentProject(parent, req.ProjectID); err != nil {
+				return nil, err
+			}
+			if parent.UserID != ownerID {
+				return nil, errDifferentOwner
+			}
+		}
+		ownerID = parent.UserID
+	}
+
+	// ラベルはtodoの持ち主のもの
+	labels, err := findOwnedLabels(tx, ownerID, req.LabelIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RRule != nil {
+		if req.DueAt == nil {
+			return nil, utils.NewBadRequestError(errRRuleWithoutDue.Error())
+		}
+		rrule, err := normalizeRRule(*req.RRule)
+		if err != nil {
+			return nil, utils.NewBadRequestError(err.Error())
+		}
+		req.RRule = &rrule
+	}
+
+	if req.Priority != nil {
+		priority, err := normalizePriority(*req.Priority)
+		if err != nil {
+			return nil, utils.NewBadRequestError(err.Error())
+		}
+		req.Priority = &priority
+	}
+
+	todo := models.Todo{
+		UserID:    ownerID,
+		Title:     req.Title,
+		Completed: false,
+		ProjectID: req.ProjectID,
+		ParentID:  req.ParentID,
+		StartAt:   req.StartAt,
+		DueAt:     req.DueAt,
+		Priority:  req.Priority,
+		Labels:    labels,
+	}
+	if req.RRule != nil {
+		todo.RRule = req.RRule
+		todo.SeriesStart = req.DueAt
+	}
+
+	if err := tx.Create(&todo).Error; err != nil {
+		return nil, err
+	}
+	if todo.RRule != nil {
+		// シリーズIDは最初の回のID
+		// 作成の続きなので、BeforeUpdateフックでバージョンを進めないようUpdateColumnで書き込む
+		todo.SeriesID = &todo.ID
+		if err := tx.Model(&todo).UpdateColumn("series_id", todo.ID).Error; err != nil {
+			return nil, err
+		}
+		// 変更番号はトリガーで採番し直されるため読み直す
+		if err := tx.Model(&models.Todo{}).Select("change_seq").Where("id = ?", todo.ID).Row().Scan(&todo.ChangeSeq); err != nil {
+			return nil, err
+		}
+	}
+	// 未完了の子が増えるため、自動完了ルールが有効なら親を再開する
+	if err := syncParentIfEnabled(tx, todo.UserID, userID, todo.ParentID); err != nil {
+		return nil, err
+	}
+
+	labelIDs := make([]uint, len(labels))
+	for i, label := range labels {
+		labelIDs[i] = label.ID
+	}
+	if err := recordRevision(tx, todo.ID, userID, models.RevisionActionCreate, nil, snapshotTodo(&todo, labelIDs), nil); err != nil {
+		return nil, err
+	}
+	if err := emitTodoEvent(tx, models.WebhookEventTodoCreated, &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// errTodoModified If-Matchで指定されたバージョンから更新されている場合のエラー
+var errTodoModified = utils.NewPreconditionFailedError("Todo has been modified")
+
+// lockTodo 更新・削除するtodoを行ロックして取得し、権限とIf-Matchの条件を検証します（ifMatchが空なら検証しない）
+func lockTodo(tx *gorm.DB, userID uint, id uint, required services.Role, ifMatch string) (*models.Todo, error) {
+	var todo models.Todo
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&todo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, utils.NewNotFoundError("Todo not found")
+		}
+		return nil, err
+	}
+	if err := checkTodoRole(tx, userID, &todo, required); err != nil {
+		return nil, err
+	}
+
+	if !utils.IfMatch(ifMatch, todo.ETag) {
+		return nil, errTodoModified
+	}
+	return &todo, nil
+}
+
+// updateTodo todoを更新し、更新後のtodoを返します
+func updateTodo(tx *gorm.DB, userID uint, id uint, req UpdateTodoRequest, ifMatch string) (*models.Todo, error) {
+	return applyTodoUpdate(tx, userID, id, req, ifMatch, nil)
+}
+
+// applyTodoUpdate todoを更新して変更履歴を記録します（revertedFromIDはリバート元の履歴）
+func applyTodoUpdate(tx *gorm.DB, userID uint, id uint, req UpdateTodoRequest, ifMatch string, revertedFromID *uint) (*models.Todo, error) {
+	// 編集権限を確認
+	found, err := lockTodo(tx, userID, id, services.RoleEditor, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+	todo := *found
+
+	before, err := loadSnapshot(tx, &todo)
+	if err != nil {
+		return nil, err
+	}
+	// プロジェクト・親の変更で閲覧できるユーザーが変わる場合に備えて、変更前のユーザーを控える
+	var viewersBefore []uint
+	if req.ProjectID.Set || req.ParentID.Set {
+		if viewersBefore, err = services.TodoViewerIDs(tx, &todo); err != nil {
+			return nil, err
+		}
+	}
+
+	// 更新フィールドを設定
+	updates := make(map[string]interface{})
+	if req.Title != nil {
+		updates["title"] = *req.Title
+	}
+	completionChanged := req.Completed != nil && *req.Completed != todo.Completed
+	if req.Completed != nil {
+		updates["completed"] = *req.Completed
+		todo.Completed = *req.Completed
+	}
+	oldParentID := todo.ParentID
+	oldProjectID := todo.ProjectID
+	projectID := todo.ProjectID
+	if req.ProjectID.Set {
+		projectID = req.ProjectID.Value
+	}
+	var parent *models.Todo
+	if req.ParentID.Set {
+		// サブタスクの付け替え（nullでトップレベルに戻す）
+		if req.ParentID.Value != nil {
+			parent, err = validateParent(tx, userID, todo.ID, *req.ParentID.Value)
+			if err != nil {
+				return nil, err
+			}
+			if parent.UserID != todo.UserID {
+				return nil, errDifferentOwner
+			}
+		}
+		updates["parent_id"] = req.ParentID.Value
+		todo.ParentID = req.ParentID.Value
+	} else if req.ProjectID.Set && todo.ParentID != nil {
+		parent = &models.Todo{}
+		if err := tx.Select("id", "project_id").First(parent, *todo.ParentID).Error; err != nil {
+			return nil, err
+		}
+	}
+	// サブタスクは親と同じプロジェクトに属する
+	if parent != nil {
+		if err := checkParentProject(parent, projectID); err != nil {
+			return nil, err
+		}
+	}
+	if req.ProjectID.Set {
+		// プロジェクト間の移動（nullでプロジェクトから外す）
+		if req.ProjectID.Value != nil {
+			project, err := checkTodoProject(tx, userID, *req.ProjectID.Value)
+			if err != nil {
+				return nil, err
+			}
+			if project.UserID != todo.UserID {
+				return nil, errDifferentOwner
+			}
+		}
+		updates["project_id"] = req.ProjectID.Value
+	}
+	if req.StartAt.Set {
+		updates["start_at"] = req.StartAt.Value
+		todo.StartAt = req.StartAt.Value
+	}
+	if req.DueAt.Set {
+		updates["due_at"] = req.DueAt.Value
+		todo.DueAt = req.DueAt.Value
+	}
+	if req.RRule.Set {
+		// 繰り返しの設定・変更（nullで繰り返しを終了）
+		if req.RRule.Value == nil {
+			updates["rrule"] = nil
+			todo.RRule = nil
+		} else {
+			rrule, err := normalizeRRule(*req.RRule.Value)
+			if err != nil {
+				return nil, utils.NewBadRequestError(err.Error())
+			}
+			updates["rrule"] = rrule
+			updates["series_start"] = todo.DueAt
+			todo.RRule = &rrule
+			if todo.SeriesID == nil {
+				updates["series_id"] = todo.ID
+				todo.SeriesID = &todo.ID
+			}
+		}
+	}
+	if todo.RRule != nil && todo.DueAt == nil {
+		return nil, utils.NewBadRequestError(errRRuleWithoutDue.Error())
+	}
+	if req.Priority.Set {
+		// 優先度の設定・変更（nullで優先度なし）
+		if req.Priority.Value == nil {
+			updates["priority"] = nil
+		} else {
+			priority, err := normalizePriority(*req.Priority.Value)
+			if err != nil {
+				return nil, utils.NewBadRequestError(err.Error())
+			}
+			updates["priority"] = priority
+		}
+	}
+
+	if len(updates) == 0 && !req.hasLabelChanges() {
+		return nil, utils.NewBadRequestError("No fields to update")
+	}
+
+	if err := validateSchedule(todo.StartAt, todo.DueAt); err != nil {
+		return nil, utils.NewBadRequestError(err.Error())
+	}
+
+	// ラベルの付け外しも同じトランザクションで行う
+	if err := applyLabelChanges(tx, &todo, todo.UserID, req); err != nil {
+		return nil, err
+	}
+	if len(updates) == 0 {
+		// ラベルのみの変更でも更新日時は進める
+		updates["updated_at"] = time.Now()
+	}
+	if err := tx.Model(&todo).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	// プロジェクトを移動したら子孫も一緒に移動する
+	if !sameProjectID(oldProjectID, projectID) {
+		ids, err := descendantIDs(tx, todo.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := changeTodos(tx, userID, ids, func() error {
+			return tx.Model(&models.Todo{}).Where("id IN ?", ids).Update("project_id", projectID).Error
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// 繰り返しtodoを完了したら次の回を作成
+	if completionChanged && todo.Completed {
+		if err := spawnNextOccurrence(tx, &todo, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// サブタスクの完了ルールを適用
+	if completionChanged {
+		if err := propagateCompletion(tx, &todo, userID); err != nil {
+			return nil, err
+		}
+	}
+	if req.ParentID.Set {
+		if err := syncParentIfEnabled(tx, todo.UserID, userID, oldParentID); err != nil {
+			return nil, err
+		}
+		if !completionChanged {
+			if err := syncParentIfEnabled(tx, todo.UserID, userID, todo.ParentID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// 更新後のデータを取得
+	if err := tx.Preload("Labels").First(&todo, todo.ID).Error; err != nil {
+		return nil, err
+	}
+	if viewersBefore != nil {
+		// サブタスクごと見えるようになった・見えなくなったユーザーには、差分同期をやり直させる
+		viewersAfter, err := services.TodoViewerIDs(tx, &todo)
+		if err != nil {
+			return nil, err
+		}
+		if err := services.ResetSync(tx, services.ChangedViewerIDs(viewersBefore, viewersAfter)); err != nil {
+			return nil, err
+		}
+	}
+
+	labelIDs := make([]uint, len(todo.Labels))
+	for i, label := range todo.Labels {
+		labelIDs[i] = label.ID
+	}
+	action := models.RevisionActionUpdate
+	if revertedFromID != nil {
+		action = models.RevisionActionRevert
+	}
+	if err := recordRevision(tx, todo.ID, userID, action, &before, snapshotTodo(&todo, labelIDs), revertedFromID); err != nil {
+		return nil, err
+	}
+	if err := emitTodoRemoved(tx, &todo, oldProjectID); err != nil {
+		return nil, err
+	}
+	if err := emitTodoEvent(tx, models.WebhookEventTodoUpdated, &todo); err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// deleteTodo todoをゴミ箱に移動します（childrenはcascadeまたはreparent）
+func deleteTodo(tx *gorm.DB, userID uint, id uint, children string, ifMatch string) error {
+	if children != "cascade" && children != "reparent" {
+		return utils.NewBadRequestError("Invalid children: must be cascade or reparent")
+	}
+
+	// 編集権限を確認
+	todo, err := lockTodo(tx, userID, id, services.RoleEditor, ifMatch)
+	if err != nil {
+		return err
+	}
+
+	// サブタスクは親と同じ削除日時にして、復元時にまとめて戻せるようにする
+	deletedAt := time.Now()
+	trashed := []models.Todo{*todo}
+	if children == "cascade" {
+		ids, err := descendantIDs(tx, todo.ID)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			var descendants []models.Todo
+			if err := tx.Where("id IN ?", ids).Find(&descendants).Error; err != nil {
+				return err
+			}
+			trashed = append(trashed, descendants...)
+			if err := tx.Model(&models.Todo{}).Where("id IN ?", ids).Update("deleted_at", deletedAt).Error; err != nil {
+				return err
+			}
+		}
+	} else {
+		var childIDs []uint
+		if err := tx.Model(&models.Todo{}).Where("parent_id = ?", todo.ID).Pluck("id", &childIDs).Error; err != nil {
+			return err
+		}
+		if err := changeTodos(tx, userID, childIDs, func() error {
+			return tx.Model(&models.Todo{}).Where("id IN ?", childIDs).Update("parent_id", todo.ParentID).Error
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(todo).Update("deleted_at", deletedAt).Error; err != nil {
+		return err
+	}
+	if err := recordTrashRevisions(tx, trashed, userID, models.RevisionActionDelete, deletedAt); err != nil {
+		return err
+	}
+	// サブタスクもまとめて削除した場合は、削除したtodoごとに通知する
+	for _, t := range trashed {
+		t.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+		if err := emitTodoEvent(tx, models.WebhookEventTodoDeleted, &t); err != nil {
+			return err
+		}
+	}
+	// 未完了の子が消えた場合、自動完了ルールが有効なら親を完了する
+	return syncParentIfEnabled(tx, todo.UserID, userID, todo.ParentID)
 }
 
 // CreateTodo Todoを作成
@@ -54,22 +874,23 @@ func CreateTodo(c *gin.Context) {
 		return
 	}
 
-	todo := models.Todo{
-		UserID:    userID.(uint),
-		Title:     req.Title,
-		Completed: false,
-	}
-
-	if err := database.DB.Create(&todo).Error; err != nil {
-		statusCode, message := utils.HandleDBError(err)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+	var todo *models.Todo
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		todo, err = createTodo(tx, userID.(uint), req)
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
 		return
 	}
 
+	c.Header("ETag", todo.ETag)
 	c.JSON(http.StatusCreated, todo)
 }
 
-// GetTodo 特定のtodoを取得（自分のものだけ）
+// GetTodo 特定のtodoを取得（閲覧できるものだけ）
+// If-None-MatchのETagが現在のバージョンと一致する場合は304 Not Modifiedを返します
 func GetTodo(c *gin.Context) {
 	userID, exists := c.Get(middleware.UserIDKey)
 	if !exists {
@@ -77,23 +898,21 @@ func GetTodo(c *gin.Context) {
 		return
 	}
 
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		utils.RespondBadRequest(c, "Invalid todo ID")
+	todo, ok := findAuthorizedTodo(c, userID.(uint), services.RoleViewer)
+	if !ok {
 		return
 	}
-
-	var todo models.Todo
-	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&todo).Error; err != nil {
+	if err := database.DB.Model(todo).Association("Labels").Find(&todo.Labels); err != nil {
 		statusCode, message := utils.HandleDBError(err)
-		if statusCode == 404 {
-			utils.RespondNotFound(c, "Todo not found")
-		} else {
-			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
-		}
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
 		return
 	}
 
+	c.Header("ETag", todo.ETag)
+	if utils.IfNoneMatch(c.GetHeader("If-None-Match"), todo.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
 	c.JSON(http.StatusOK, todo)
 }
 
@@ -117,45 +936,23 @@ func UpdateTodo(c *gin.Context) {
 		return
 	}
 
-	// 自分のtodoか確認
-	var todo models.Todo
-	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&todo).Error; err != nil {
-		statusCode, message := utils.HandleDBError(err)
-		if statusCode == 404 {
-			utils.RespondNotFound(c, "Todo not found")
-		} else {
-			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
-		}
-		return
-	}
-
-	// 更新フィールドを設定
-	updates := make(map[string]interface{})
-	if req.Title != nil {
-		updates["title"] = *req.Title
-	}
-	if req.Completed != nil {
-		updates["completed"] = *req.Completed
-	}
-
-	if len(updates) == 0 {
-		utils.RespondBadRequest(c, "No fields to update")
-		return
-	}
-
-	// 更新実行
-	if err := database.DB.Model(&todo).Updates(updates).Error; err != nil {
-		statusCode, message := utils.HandleDBError(err)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+	var todo *models.Todo
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		todo, err = updateTodo(tx, userID.(uint), uint(id), req, c.GetHeader("If-Match"))
+		return err
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
 		return
 	}
 
-	// 更新後のデータを取得
-	database.DB.First(&todo, id)
+	c.Header("ETag", todo.ETag)
 	c.JSON(http.StatusOK, todo)
 }
 
-// DeleteTodo Todoを削除
+// DeleteTodo Todoをゴミ箱に移動（論理削除）
+// ?children=cascade（デフォルト）でサブタスクも削除、?children=reparentでサブタスクを削除したtodoの親に付け替え
 func DeleteTodo(c *gin.Context) {
 	userID, exists := c.Get(middleware.UserIDKey)
 	if !exists {
@@ -169,16 +966,12 @@ func DeleteTodo(c *gin.Context) {
 		return
 	}
 
-	// 自分のtodoか確認して削除
-	result := database.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Todo{})
-	if result.Error != nil {
-		statusCode, message := utils.HandleDBError(result.Error)
-		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
-		return
-	}
-
-	if result.RowsAffected == 0 {
-		utils.RespondNotFound(c, "Todo not found")
+	children := c.DefaultQuery("children", "cascade")
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		return deleteTodo(tx, userID.(uint), uint(id), children, c.GetHeader("If-Match"))
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
 		return
 	}
 
@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// maxTodoTxtImportSize インポートするtodo.txtファイルの最大サイズ
+const maxTodoTxtImportSize = 5 << 20
+
+// todo.txtのkey:valueのうち、todoのフィールドに対応するキー
+// 時刻はtodo.txtの日付に含められないため、0時以外なら*_time（HHMMまたはHHMMSS）に分けて書き出す
+const (
+	todoTxtKeyDue       = "due"
+	todoTxtKeyDueTime   = "due_time"
+	todoTxtKeyStart     = "t"
+	todoTxtKeyStartTime = "t_time"
+	todoTxtKeyRec       = "rec"
+	todoTxtKeyRRule     = "rrule"
+	todoTxtKeyID        = "id"
+	todoTxtKeyParent    = "p"
+)
+
+// todoTxtReservedKeys todoのフィールドとして解釈するキー（extrasには保存しない）
+var todoTxtReservedKeys = map[string]bool{
+	todoTxtKeyDue:       true,
+	todoTxtKeyDueTime:   true,
+	todoTxtKeyStart:     true,
+	todoTxtKeyStartTime: true,
+	todoTxtKeyRec:       true,
+	todoTxtKeyRRule:     true,
+	todoTxtKeyID:        true,
+	todoTxtKeyParent:    true,
+}
+
+// todoTxtRecPattern rec:の値（例: 1w、+2m。+は期限基準の繰り返しを表し、このAPIの繰り返しは常に期限基準）
+var todoTxtRecPattern = regexp.MustCompile(`^\+?(\d*)([dwmy])$`)
+
+var todoTxtRecFreqs = map[string]utils.Frequency{
+	"d": utils.FreqDaily,
+	"w": utils.FreqWeekly,
+	"m": utils.FreqMonthly,
+	"y": utils.FreqYearly,
+}
+
+// ImportTodoTxtResponse todo.txtインポートのレスポンス（todo_idsはファイルの行順）
+type ImportTodoTxtResponse struct {
+	Imported int    `json:"imported"`
+	TodoIDs  []uint `json:"todo_ids"`
+}
+
+// todoTxtName プロジェクト名・ラベル名をtodo.txtの+project / @contextにします（空白は_に置き換える）
+func todoTxtName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// formatTodoTxtTime 時刻をHHMM（秒があればHHMMSS）にします
+func formatTodoTxtTime(t time.Time) string {
+	if t.Second() != 0 {
+		return t.Format("150405")
+	}
+	return t.Format("1504")
+}
+
+// appendTodoTxtDate 日時をkey:YYYY-MM-DDと、0時でなければ時刻のkeyに分けて追加します
+func appendTodoTxtDate(extras []utils.TodoTxtExtra, dateKey, timeKey string, t time.Time, loc *time.Location) []utils.TodoTxtExtra {
+	local := t.In(loc)
+	extras = append(extras, utils.TodoTxtExtra{Key: dateKey, Value: local.Format(utils.TodoTxtDateLayout)})
+	if !local.Equal(utils.StartOfDay(local, loc)) {
+		extras = append(extras, utils.TodoTxtExtra{Key: timeKey, Value: formatTodoTxtTime(local)})
+	}
+	return extras
+}
+
+// todoTxtRec 単純な繰り返し（FREQとINTERVALのみ）をrec:の値にします（表せなければ空）
+func todoTxtRec(rrule string) string {
+	rule, err := utils.ParseRRule(rrule)
+	if err != nil || rule.Count > 0 || rule.Until != nil || len(rule.ByDay) > 0 ||
+		len(rule.ByMonthDay) > 0 || len(rule.ByMonth) > 0 || rule.WeekStart != time.Monday {
+		return ""
+	}
+	for unit, freq := range todoTxtRecFreqs {
+		if freq == rule.Freq {
+			interval := rule.Interval
+			if interval < 1 {
+				interval = 1
+			}
+			return strconv.Itoa(interval) + unit
+		}
+	}
+	return ""
+}
+
+// newTodoTxtTask todoをtodo.txtのタスクにします
+// parentIDsはエクスポートするtodoのうち子を持つもの、exportedはエクスポートするtodoのIDです
+func newTodoTxtTask(todo *models.Todo, loc *time.Location, parentIDs, exported map[uint]bool) utils.TodoTxtTask {
+	createdAt := todo.CreatedAt
+	task := utils.TodoTxtTask{
+		Completed:    todo.Completed,
+		CreationDate: &createdAt,
+		Description:  todo.Title,
+	}
+	if todo.Priority != nil {
+		task.Priority = *todo.Priority
+	}
+	if todo.Completed {
+		// 完了日時の記録を始める前に完了したtodoは更新日時で代用する
+		completedAt := todo.UpdatedAt
+		if todo.CompletedAt != nil {
+			completedAt = *todo.CompletedAt
+		}
+		task.CompletionDate = &completedAt
+	}
+	if todo.Project != nil {
+		task.Projects = []string{todoTxtName(todo.Project.Name)}
+	}
+	for _, label := range todo.Labels {
+		task.Contexts = append(task.Contexts, todoTxtName(label.Name))
+	}
+
+	if todo.DueAt != nil {
+		task.Extras = appendTodoTxtDate(task.Extras, todoTxtKeyDue, todoTxtKeyDueTime, *todo.DueAt, loc)
+	}
+	if todo.StartAt != nil {
+		task.Extras = appendTodoTxtDate(task.Extras, todoTxtKeyStart, todoTxtKeyStartTime, *todo.StartAt, loc)
+	}
+	if todo.RRule != nil {
+		if rec := todoTxtRec(*todo.RRule); rec != "" {
+			task.Extras = append(task.Extras, utils.TodoTxtExtra{Key: todoTxtKeyRec, Value: rec})
+		} else {
+			task.Extras = append(task.Extras, utils.TodoTxtExtra{Key: todoTxtKeyRRule, Value: *todo.RRule})
+		}
+	}
+	// サブタスクの関係は親にid:、子にp:を付けて表す
+	if parentIDs[todo.ID] {
+		task.Extras = append(task.Extras, utils.TodoTxtExtra{Key: todoTxtKeyID, Value: strconv.FormatUint(uint64(todo.ID), 10)})
+	}
+	if todo.ParentID != nil && exported[*todo.ParentID] {
+		task.Extras = append(task.Extras, utils.TodoTxtExtra{Key: todoTxtKeyParent, Value: strconv.FormatUint(uint64(*todo.ParentID), 10)})
+	}
+
+	keys := make([]string, 0, len(todo.Extras))
+	for key := range todo.Extras {
+		if !todoTxtReservedKeys[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		task.Extras = append(task.Extras, utils.TodoTxtExtra{Key: key, Value: todo.Extras[key]})
+	}
+	return task
+}
+
+// ExportTodosTxt 自分のtodoと共有されたtodoをtodo.txt形式でエクスポート（一覧取得と同じフィルタが使えます）
+func ExportTodosTxt(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	query, err := applyTodoFilters(database.DB.Scopes(services.VisibleTodos(userID.(uint))), c)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	var todos []models.Todo
+	if err := excludeArchivedProjects(query, c).Preload("Labels").Preload("Project").Order("created_at ASC, id ASC").Find(&todos).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	loc, err := userLocation(userID)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	exported := make(map[uint]bool, len(todos))
+	for _, todo := range todos {
+		exported[todo.ID] = true
+	}
+	parentIDs := make(map[uint]bool)
+	for _, todo := range todos {
+		if todo.ParentID != nil && exported[*todo.ParentID] {
+			parentIDs[*todo.ParentID] = true
+		}
+	}
+
+	var buf bytes.Buffer
+	for i := range todos {
+		buf.WriteString(utils.FormatTodoTxt(newTodoTxtTask(&todos[i], loc, parentIDs, exported), loc))
+		buf.WriteByte('\n')
+	}
+	c.Header("Content-Disposition", `attachment; filename="todo.txt"`)
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", buf.Bytes())
+}
+
+// todoTxtItem todo.txtの1行から読み取ったtodoの内容
+type todoTxtItem struct {
+	Line        int
+	Request     CreateTodoRequest
+	Completed   bool
+	CompletedAt *time.Time
+	CreatedAt   *time.Time
+	Project     string
+	Contexts    []string
+	Extras      models.TodoExtras
+	ID          string // id:（ファイル内でサブタスクの親を指すためのID）
+	ParentRef   string // p:
+}
+
+// parseTodoTxtDateTime key:YYYY-MM-DDと時刻のkeyから日時を組み立てます
+func parseTodoTxtDateTime(task *utils.TodoTxtTask, dateKey, timeKey string, loc *time.Location) (*time.Time, error) {
+	date, ok := task.Extra(dateKey)
+	if !ok {
+		if _, ok := task.Extra(timeKey); ok {
+			return nil, fmt.Errorf("%s requires %s", timeKey, dateKey)
+		}
+		return nil, nil
+	}
+	t, err := utils.ParseTodoTxtDate(date, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", dateKey, date)
+	}
+	if clock, ok := task.Extra(timeKey); ok {
+		layout := "1504"
+		if len(clock) == len("150405") {
+			layout = "150405"
+		}
+		parsed, err := time.Parse(layout, clock)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", timeKey, clock)
+		}
+		t = time.Date(t.Year(), t.Month(), t.Day(), parsed.Hour(), parsed.Minute(), parsed.Second(), 0, loc)
+	}
+	return &t, nil
+}
+
+// newTodoTxtItem todo.txtのタスクをtodoの内容に変換します
+func newTodoTxtItem(task *utils.TodoTxtTask, loc *time.Location) (*todoTxtItem, error) {
+	seen := make(map[string]bool, len(task.Extras))
+	for _, extra := range task.Extras {
+		if seen[extra.Key] {
+			return nil, fmt.Errorf("duplicate key: %s", extra.Key)
+		}
+		seen[extra.Key] = true
+	}
+
+	item := &todoTxtItem{
+		Line:        task.Line,
+		Completed:   task.Completed,
+		CompletedAt: task.CompletionDate,
+		CreatedAt:   task.CreationDate,
+		Contexts:    task.Contexts,
+	}
+
+	// 2つ目以降の+projectはtodoに対応するフィールドがないため、タイトルに残す
+	title := task.Description
+	if len(task.Projects) > 0 {
+		item.Project = task.Projects[0]
+		for _, project := range task.Projects[1:] {
+			title += " +" + project
+		}
+	}
+	item.Request.Title = title
+	if task.Priority != "" {
+		priority := task.Priority
+		item.Request.Priority = &priority
+	}
+
+	var err error
+	if item.Request.DueAt, err = parseTodoTxtDateTime(task, todoTxtKeyDue, todoTxtKeyDueTime, loc); err != nil {
+		return nil, err
+	}
+	if item.Request.StartAt, err = parseTodoTxtDateTime(task, todoTxtKeyStart, todoTxtKeyStartTime, loc); err != nil {
+		return nil, err
+	}
+
+	rec, hasRec := task.Extra(todoTxtKeyRec)
+	rrule, hasRRule := task.Extra(todoTxtKeyRRule)
+	switch {
+	case hasRec && hasRRule:
+		return nil, fmt.Errorf("rec and rrule must not be used together")
+	case hasRec:
+		m := todoTxtRecPattern.FindStringSubmatch(rec)
+		if m == nil {
+			return nil, fmt.Errorf("unsupported rec: %s", rec)
+		}
+		rule := "FREQ=" + string(todoTxtRecFreqs[m[2]])
+		if m[1] != "" {
+			rule += ";INTERVAL=" + m[1]
+		}
+		item.Request.RRule = &rule
+	case hasRRule:
+		item.Request.RRule = &rrule
+	}
+
+	item.ID, _ = task.Extra(todoTxtKeyID)
+	item.ParentRef, _ = task.Extra(todoTxtKeyParent)
+
+	for _, extra := range task.Extras {
+		if todoTxtReservedKeys[extra.Key] {
+			continue
+		}
+		if item.Extras == nil {
+			item.Extras = models.TodoExtras{}
+		}
+		item.Extras[extra.Key] = extra.Value
+	}
+	return item, nil
+}
+
+// todoTxtResolver +project / @contextを自分のプロジェクト・ラベルに対応付けます（なければ作成する）
+type todoTxtResolver struct {
+	tx       *gorm.DB
+	userID   uint
+	projects map[string]uint
+	labels   map[string]uint
+}
+
+// newTodoTxtResolver 自分のプロジェクト（アーカイブ済みを除く）とラベルを読み込みます
+func newTodoTxtResolver(tx *gorm.DB, userID uint) (*todoTxtResolver, error) {
+	r := &todoTxtResolver{tx: tx, userID: userID, projects: map[string]uint{}, labels: map[string]uint{}}
+
+	var projects []models.Project
+	if err := tx.Where("user_id = ? AND archived_at IS NULL", userID).Order("id ASC").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		if _, ok := r.projects[todoTxtName(project.Name)]; !ok {
+			r.projects[todoTxtName(project.Name)] = project.ID
+		}
+	}
+
+	var labels []models.Label
+	if err := tx.Where("user_id = ?", userID).Order("id ASC").Find(&labels).Error; err != nil {
+		return nil, err
+	}
+	for _, label := range labels {
+		if _, ok := r.labels[todoTxtName(label.Name)]; !ok {
+			r.labels[todoTxtName(label.Name)] = label.ID
+		}
+	}
+	return r, nil
+}
+
+// projectID プロジェクトのIDを返します
+func (r *todoTxtResolver) projectID(name string) (uint, error) {
+	if id, ok := r.projects[name]; ok {
+		return id, nil
+	}
+	project := models.Project{UserID: r.userID, Name: name}
+	if err := r.tx.Create(&project).Error; err != nil {
+		return 0, err
+	}
+	r.projects[name] = project.ID
+	return project.ID, nil
+}
+
+// labelIDs ラベルのIDを返します
+func (r *todoTxtResolver) labelIDs(names []string) ([]uint, error) {
+	ids := make([]uint, 0, len(names))
+	for _, name := range names {
+		id, ok := r.labels[name]
+		if !ok {
+			label := models.Label{UserID: r.userID, Name: name}
+			if err := r.tx.Create(&label).Error; err != nil {
+				return nil, err
+			}
+			id = label.ID
+			r.labels[name] = id
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// importTodoTxtItem todo.txtの1行をtodoとして作成します
+func importTodoTxtItem(tx *gorm.DB, resolver *todoTxtResolver, userID uint, item *todoTxtItem) (*models.Todo, error) {
+	req := item.Request
+	if item.Project != "" {
+		projectID, err := resolver.projectID(item.Project)
+		if err != nil {
+			return nil, err
+		}
+		req.ProjectID = &projectID
+	}
+	labelIDs, err := resolver.labelIDs(item.Contexts)
+	if err != nil {
+		return nil, err
+	}
+	req.LabelIDs = labelIDs
+
+	todo, err := createTodo(tx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if item.Completed {
+		completed := true
+		if todo, err = updateTodo(tx, userID, todo.ID, UpdateTodoRequest{Completed: &completed}, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// ファイルの作成日・完了日・追加情報はそのまま残す（バージョンは進めない）
+	columns := make(map[string]interface{})
+	if item.CreatedAt != nil {
+		columns["created_at"] = *item.CreatedAt
+	}
+	if item.Completed && item.CompletedAt != nil {
+		columns["completed_at"] = *item.CompletedAt
+	}
+	if len(item.Extras) > 0 {
+		columns["extras"] = item.Extras
+	}
+	if len(columns) > 0 {
+		if err := tx.Model(todo).UpdateColumns(columns).Error; err != nil {
+			return nil, err
+		}
+	}
+	return todo, nil
+}
+
+// todoTxtLineError 行の取り込みに失敗した理由を行番号付きのエラーにします（DBエラーはそのまま返す）
+func todoTxtLineError(line int, err error) error {
+	apiErr := utils.ToAPIError(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		return err
+	}
+	return utils.NewValidationError("Invalid todo.txt", []utils.ErrorItem{{Line: line, Message: apiErr.Message}})
+}
+
+// ImportTodosTxt todo.txtファイルをインポート（multipart/form-dataのfileフィールド、またはtext/plainの本文）
+// 1行でも不正な行があれば何も取り込まず、行番号付きのエラーをすべて返します
+func ImportTodosTxt(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	data, err := readImportFile(c, maxTodoTxtImportSize)
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	loc, err := userLocation(userID)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	tasks, lineErrors := utils.ParseTodoTxt(bytes.NewReader(data), loc)
+	items := make([]*todoTxtItem, 0, len(tasks))
+	itemIDs := make(map[string]int)
+	for i := range tasks {
+		item, err := newTodoTxtItem(&tasks[i], loc)
+		if err != nil {
+			lineErrors = append(lineErrors, utils.ErrorItem{Line: tasks[i].Line, Message: err.Error()})
+			continue
+		}
+		if item.ID != "" {
+			if _, ok := itemIDs[item.ID]; ok {
+				lineErrors = append(lineErrors, utils.ErrorItem{Line: item.Line, Message: "duplicate id: " + item.ID})
+				continue
+			}
+			itemIDs[item.ID] = len(items)
+		}
+		items = append(items, item)
+	}
+	for _, item := range items {
+		if _, ok := itemIDs[item.ParentRef]; item.ParentRef != "" && !ok {
+			lineErrors = append(lineErrors, utils.ErrorItem{Line: item.Line, Message: "unknown parent id: " + item.ParentRef})
+		}
+	}
+	if len(lineErrors) > 0 {
+		sort.SliceStable(lineErrors, func(i, j int) bool { return lineErrors[i].Line < lineErrors[j].Line })
+		utils.RespondAPIError(c, utils.NewValidationError("Invalid todo.txt", lineErrors))
+		return
+	}
+	if len(items) == 0 {
+		utils.RespondBadRequest(c, "No tasks in todo.txt")
+		return
+	}
+
+	todoIDs := make([]uint, len(items))
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		resolver, err := newTodoTxtResolver(tx, userID.(uint))
+		if err != nil {
+			return err
+		}
+		for i, item := range items {
+			todo, err := importTodoTxtItem(tx, resolver, userID.(uint), item)
+			if err != nil {
+				return todoTxtLineError(item.Line, err)
+			}
+			todoIDs[i] = todo.ID
+		}
+
+		// 親が後の行にある場合もあるため、すべて作成してからサブタスクにする
+		for i, item := range items {
+			if item.ParentRef == "" {
+				continue
+			}
+			parentID := todoIDs[itemIDs[item.ParentRef]]
+			req := UpdateTodoRequest{ParentID: utils.Optional[uint]{Set: true, Value: &parentID}}
+			if item.Project == "" {
+				// プロジェクトのない行は親と同じプロジェクトに入れる
+				var parent models.Todo
+				if err := tx.Select("id", "project_id").First(&parent, parentID).Error; err != nil {
+					return err
+				}
+				req.ProjectID = utils.Optional[uint]{Set: true, Value: parent.ProjectID}
+			}
+			if _, err := updateTodo(tx, userID.(uint), todoIDs[i], req, ""); err != nil {
+				return todoTxtLineError(item.Line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.RespondAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, ImportTodoTxtResponse{Imported: len(items), TodoIDs: todoIDs})
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/storage"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// trashSort ゴミ箱の並び順（削除日時の新しい順）
+var trashSort = todoSort{Column: "deleted_at", Desc: true}
+
+// findTrashedTodo ゴミ箱にあるtodoを取得して持ち主の権限（owner）を確認し、なければエラーレスポンスを返します
+func findTrashedTodo(c *gin.Context, userID uint) (*models.Todo, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid todo ID")
+		return nil, false
+	}
+
+	var todo models.Todo
+	if err := database.DB.Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&todo).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Todo not found in trash")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	if err := checkTodoRole(database.DB, userID, &todo, services.RoleOwner); err != nil {
+		utils.RespondAPIError(c, err)
+		return nil, false
+	}
+
+	return &todo, true
+}
+
+// GetTrash ゴミ箱のtodo一覧を取得（自分が持ち主のtodoのみ）
+func GetTrash(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	query := database.DB.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	respondTodoPage(c, query, trashSort)
+}
+
+// RestoreTodo ゴミ箱のtodoを復元（一緒に削除されたサブタスクも復元）
+func RestoreTodo(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findTrashedTodo(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedDescendantIDs(tx, todo.ID, todo.DeletedAt.Time)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"deleted_at": nil}
+		if todo.ParentID != nil {
+			// 親がゴミ箱にある場合はトップレベルとして復元
+			var parents int64
+			if err := tx.Model(&models.Todo{}).Where("id = ?", *todo.ParentID).Count(&parents).Error; err != nil {
+				return err
+			}
+			if parents == 0 {
+				updates["parent_id"] = nil
+				todo.ParentID = nil
+			}
+		}
+
+		restored := []models.Todo{*todo}
+		if len(ids) > 0 {
+			var descendants []models.Todo
+			if err := tx.Unscoped().Where("id IN ?", ids).Find(&descendants).Error; err != nil {
+				return err
+			}
+			restored = append(restored, descendants...)
+		}
+
+		if err := tx.Unscoped().Model(todo).Updates(updates).Error; err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			if err := tx.Unscoped().Model(&models.Todo{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+		if err := recordTrashRevisions(tx, restored, userID.(uint), models.RevisionActionRestore, todo.DeletedAt.Time); err != nil {
+			return err
+		}
+		restoredIDs := append([]uint{todo.ID}, ids...)
+		if err := emitTodoEventsByID(tx, models.WebhookEventTodoUpdated, restoredIDs); err != nil {
+			return err
+		}
+		return syncParentIfEnabled(tx, todo.UserID, userID.(uint), todo.ParentID)
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	database.DB.Preload("Labels").First(todo, todo.ID)
+	c.JSON(http.StatusOK, todo)
+}
+
+// PurgeTodo ゴミ箱のtodoを完全に削除（一緒に削除されたサブタスクも削除）
+func PurgeTodo(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	todo, ok := findTrashedTodo(c, userID.(uint))
+	if !ok {
+		return
+	}
+
+	var keys []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		ids, err := trashedDescendantIDs(tx, todo.ID, todo.DeletedAt.Time)
+		if err != nil {
+			return err
+		}
+		keys, err = services.PurgeTodos(tx, append(ids, todo.ID))
+		return err
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	storage.DeleteObjects(c.Request.Context(), keys)
+
+	c.Status(http.StatusNoContent)
+}
+
+// EmptyTrash ゴミ箱を空にする
+func EmptyTrash(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var keys []string
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Unscoped().Model(&models.Todo{}).
+			Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		var err error
+		keys, err = services.PurgeTodos(tx, ids)
+		return err
+	})
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+	storage.DeleteObjects(c.Request.Context(), keys)
+
+	c.Status(http.StatusNoContent)
+}
@@ -8,6 +8,25 @@ import (
 	"go-gin-todo-api/utils"
 )
 
+// MeResponse 自分の情報のレスポンス
+type MeResponse struct {
+	ID                 uint   `json:"id"`
+	Email              string `json:"email"`
+	Timezone           string `json:"timezone"`
+	CascadeCompletion  bool   `json:"cascade_completion"`
+	AutoCompleteParent bool   `json:"auto_complete_parent"`
+}
+
+func newMeResponse(user *models.User) MeResponse {
+	return MeResponse{
+		ID:                 user.ID,
+		Email:              user.Email,
+		Timezone:           user.Timezone,
+		CascadeCompletion:  user.CascadeCompletion,
+		AutoCompleteParent: user.AutoCompleteParent,
+	}
+}
+
 // GetMe 自分の情報を取得
 func GetMe(c *gin.Context) {
 	userID, exists := c.Get(middleware.UserIDKey)
@@ -27,8 +46,69 @@ func GetMe(c *gin.Context) {
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"id":    user.ID,
-		"email": user.Email,
-	})
+	c.JSON(200, newMeResponse(&user))
+}
+
+// UpdateMeRequest ユーザー設定更新リクエスト
+type UpdateMeRequest struct {
+	Timezone           *string `json:"timezone"`
+	CascadeCompletion  *bool   `json:"cascade_completion"`
+	AutoCompleteParent *bool   `json:"auto_complete_parent"`
+}
+
+// UpdateMe 自分の設定を更新
+func UpdateMe(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.Timezone != nil {
+		// IANAタイムゾーン名として解釈できるか確認
+		if _, err := utils.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			utils.RespondBadRequest(c, "Invalid timezone")
+			return
+		}
+		updates["timezone"] = *req.Timezone
+	}
+	if req.CascadeCompletion != nil {
+		updates["cascade_completion"] = *req.CascadeCompletion
+	}
+	if req.AutoCompleteParent != nil {
+		updates["auto_complete_parent"] = *req.AutoCompleteParent
+	}
+
+	if len(updates) == 0 {
+		utils.RespondBadRequest(c, "No fields to update")
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "User not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return
+	}
+
+	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	// 更新後のデータを取得
+	database.DB.First(&user, userID)
+	c.JSON(200, newMeResponse(&user))
 }
@@ -0,0 +1,386 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+)
+
+// webhookDeliverySort 配信ログの並び順（新しい順）
+const webhookDeliverySort = "-id"
+
+// CreateWebhookRequest webhook登録リクエスト
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required"`
+	Events []string `json:"events" binding:"required,min=1"`
+	Active *bool    `json:"active"`
+}
+
+// UpdateWebhookRequest webhook更新リクエスト
+type UpdateWebhookRequest struct {
+	URL    *string   `json:"url"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// WebhookCreatedResponse webhook登録レスポンス（署名の鍵は登録時にしか返さない）
+type WebhookCreatedResponse struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryListResponse 配信ログ一覧レスポンス
+type WebhookDeliveryListResponse struct {
+	Items      []models.WebhookDelivery `json:"items"`
+	NextCursor *string                  `json:"next_cursor"`
+}
+
+// WebhookDeliveryResponse 送信した本文を含む配信ログ
+type WebhookDeliveryResponse struct {
+	models.WebhookDelivery
+	Payload json.RawMessage `json:"payload"`
+}
+
+// normalizeWebhookEvents 購読するイベントを検証し、重複を除きます（"*"はすべてのイベント）
+func normalizeWebhookEvents(events []string) (models.WebhookEventSet, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("events must not be empty")
+	}
+	set := models.WebhookEventSet{}
+	for _, event := range events {
+		if event == "*" {
+			return append(models.WebhookEventSet{}, models.WebhookEvents...), nil
+		}
+		if !models.WebhookEventSet(models.WebhookEvents).Has(event) {
+			return nil, fmt.Errorf("unknown event: %s", event)
+		}
+		if !set.Has(event) {
+			set = append(set, event)
+		}
+	}
+	return set, nil
+}
+
+// findWebhook URLの自分のwebhookを取得し、なければエラーレスポンスを返します
+func findWebhook(c *gin.Context, userID interface{}) (*models.Webhook, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid webhook ID")
+		return nil, false
+	}
+
+	var webhook models.Webhook
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&webhook).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Webhook not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	return &webhook, true
+}
+
+// findWebhookDelivery URLのwebhookの配信ログを取得し、なければエラーレスポンスを返します
+func findWebhookDelivery(c *gin.Context, webhook *models.Webhook) (*models.WebhookDelivery, bool) {
+	id, err := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+	if err != nil {
+		utils.RespondBadRequest(c, "Invalid delivery ID")
+		return nil, false
+	}
+
+	var delivery models.WebhookDelivery
+	if err := database.DB.Where("id = ? AND webhook_id = ?", id, webhook.ID).First(&delivery).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		if statusCode == 404 {
+			utils.RespondNotFound(c, "Delivery not found")
+		} else {
+			utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		}
+		return nil, false
+	}
+
+	return &delivery, true
+}
+
+// GetWebhooks 自分のwebhook一覧を取得
+func GetWebhooks(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var webhooks []models.Webhook
+	if err := database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&webhooks).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook webhookを登録（署名の鍵はこのレスポンスでしか返さない）
+func CreateWebhook(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	if err := services.ValidateWebhookURL(c.Request.Context(), req.URL); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	secret, err := services.GenerateWebhookSecret()
+	if err != nil {
+		utils.RespondInternalError(c, "Failed to generate secret")
+		return
+	}
+
+	webhook := models.Webhook{
+		UserID: userID.(uint),
+		URL:    req.URL,
+		Secret: secret,
+		Events: events,
+		Active: req.Active == nil || *req.Active,
+	}
+	if err := database.DB.Create(&webhook).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.JSON(http.StatusCreated, WebhookCreatedResponse{Webhook: webhook, Secret: secret})
+}
+
+// GetWebhook 特定のwebhookを取得
+func GetWebhook(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	webhook, ok := findWebhook(c, userID)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook webhookのURL・購読するイベント・有効かどうかを更新
+func UpdateWebhook(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	webhook, ok := findWebhook(c, userID)
+	if !ok {
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.URL != nil {
+		if err := services.ValidateWebhookURL(c.Request.Context(), *req.URL); err != nil {
+			utils.RespondBadRequest(c, err.Error())
+			return
+		}
+		updates["url"] = *req.URL
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(*req.Events)
+		if err != nil {
+			utils.RespondBadRequest(c, err.Error())
+			return
+		}
+		updates["events"] = events
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+
+	if len(updates) == 0 {
+		utils.RespondBadRequest(c, "No fields to update")
+		return
+	}
+
+	if err := database.DB.Model(webhook).Updates(updates).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	// 更新後のデータを取得
+	database.DB.First(webhook, webhook.ID)
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook webhookを削除（配信ログも外部キーで削除される）
+func DeleteWebhook(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	webhook, ok := findWebhook(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(webhook).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries webhookの配信ログを取得（新しい順、?status=pending|succeeded|failedで絞り込み）
+func GetWebhookDeliveries(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	limit, err := utils.ParseLimit(c.Query("limit"))
+	if err != nil {
+		utils.RespondBadRequest(c, err.Error())
+		return
+	}
+
+	webhook, ok := findWebhook(c, userID)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Omit("payload").Where("webhook_id = ?", webhook.ID)
+	switch status := c.Query("status"); status {
+	case "":
+	case models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+		query = query.Where("status = ?", status)
+	default:
+		utils.RespondBadRequest(c, "status must be pending, succeeded or failed")
+		return
+	}
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := utils.DecodeCursor(cursorStr)
+		if err != nil || cursor.Sort != webhookDeliverySort {
+			utils.RespondBadRequest(c, "Invalid cursor")
+			return
+		}
+		query = query.Where("id < ?", cursor.ID)
+	}
+
+	// 次ページの有無を判定するため1件多く取得
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit + 1).Find(&deliveries).Error; err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	var next *string
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		last := deliveries[limit-1]
+		cursor := utils.EncodeCursor(utils.Cursor{
+			Sort:  webhookDeliverySort,
+			Value: strconv.FormatUint(uint64(last.ID), 10),
+			ID:    last.ID,
+		})
+		next = &cursor
+	}
+
+	c.JSON(http.StatusOK, WebhookDeliveryListResponse{Items: deliveries, NextCursor: next})
+}
+
+// GetWebhookDelivery 送信した本文を含む配信ログを取得
+func GetWebhookDelivery(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	webhook, ok := findWebhook(c, userID)
+	if !ok {
+		return
+	}
+	delivery, ok := findWebhookDelivery(c, webhook)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, WebhookDeliveryResponse{WebhookDelivery: *delivery, Payload: json.RawMessage(delivery.Payload)})
+}
+
+// RedeliverWebhookDelivery 配信を同じ本文で送り直す（新しい配信としてキューに追加する）
+func RedeliverWebhookDelivery(c *gin.Context) {
+	userID, exists := c.Get(middleware.UserIDKey)
+	if !exists {
+		utils.RespondUnauthorized(c, "Unauthorized")
+		return
+	}
+
+	webhook, ok := findWebhook(c, userID)
+	if !ok {
+		return
+	}
+	delivery, ok := findWebhookDelivery(c, webhook)
+	if !ok {
+		return
+	}
+	if !webhook.Active {
+		utils.RespondConflict(c, "Webhook is disabled")
+		return
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		utils.RespondConflict(c, "Delivery is still pending")
+		return
+	}
+
+	redelivery, err := services.RedeliverWebhook(database.DB, delivery)
+	if err != nil {
+		statusCode, message := utils.HandleDBError(err)
+		utils.RespondError(c, statusCode, utils.ErrorCodeInternal, message)
+		return
+	}
+
+	c.Header("Location", fmt.Sprintf("/webhooks/%d/deliveries/%d", webhook.ID, redelivery.ID))
+	c.JSON(http.StatusAccepted, redelivery)
+}
@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/gorilla/websocket"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/realtime"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// wsAuthTimeout Authorizationヘッダーなしで接続した場合に、authメッセージを待つ時間
+	wsAuthTimeout = 10 * time.Second
+	// wsPingInterval サーバーからpingを送る間隔
+	wsPingInterval = 30 * time.Second
+	// wsPongTimeout pongもメッセージも届かないまま、この時間が過ぎた接続は切断する
+	wsPongTimeout = 2 * wsPingInterval
+	// wsWriteTimeout 1件の送信にかけられる時間
+	wsWriteTimeout = 10 * time.Second
+	// wsReauthWarning アクセストークンの有効期限のこの時間前にtoken_expiringを送る
+	wsReauthWarning = time.Minute
+	// wsSendBuffer 送信待ちにできるメッセージの数（溢れた接続は遅いクライアントとして切断する）
+	wsSendBuffer = 64
+	// wsMaxMessageSize 受信するメッセージの最大バイト数
+	wsMaxMessageSize = 64 << 10
+)
+
+// WebSocketのクローズコード（4000番台はアプリケーション定義）
+const (
+	wsCloseUnauthorized = 4001 // 認証に失敗した・アクセストークンの有効期限が切れた
+	wsCloseSlowConsumer = 4008 // 送信が追いつかない
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	// Cookieではなくアクセストークンで認証するため、他のオリジンからの接続を拒否する必要はない
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WSClientMessage クライアントから送られるメッセージ
+type WSClientMessage struct {
+	Type      string          `json:"type"`
+	ID        string          `json:"id,omitempty"` // 応答（ack / error）に付けて返すリクエストID
+	Token     string          `json:"token,omitempty"`
+	ProjectID uint            `json:"project_id,omitempty"`
+	TodoID    uint            `json:"todo_id,omitempty"`
+	IfMatch   string          `json:"if_match,omitempty"`
+	Children  string          `json:"children,omitempty"`
+	Todo      json.RawMessage `json:"todo,omitempty"`    // createの内容（POST /todosと同じ）
+	Changes   json.RawMessage `json:"changes,omitempty"` // updateの内容（PATCH /todos/:idと同じ）
+}
+
+// WSServerMessage サーバーから送るメッセージ
+type WSServerMessage struct {
+	Type      string             `json:"type"`
+	ID        string             `json:"id,omitempty"`
+	UserID    uint               `json:"user_id,omitempty"`
+	ExpiresAt *time.Time         `json:"expires_at,omitempty"`
+	ProjectID *uint              `json:"project_id,omitempty"`
+	Event     string             `json:"event,omitempty"`
+	EventID   uint               `json:"event_id,omitempty"`
+	Todo      interface{}        `json:"todo,omitempty"`
+	Users     []realtime.Member  `json:"users,omitempty"`
+	Error     *utils.ErrorDetail `json:"error,omitempty"`
+}
+
+// wsConn 1つのWebSocket接続
+type wsConn struct {
+	conn   *websocket.Conn
+	id     string
+	member realtime.Member
+	send   chan WSServerMessage
+	// reauth 認証し直して有効期限が変わったことを送信側に知らせる
+	reauth chan time.Time
+
+	mu       sync.Mutex
+	userID   uint            // 認証したユーザー（authメッセージで認証し直すため、読み書きはmuで守る）
+	projects map[uint]func() // 購読中のプロジェクトと、プレゼンスの監視の解除
+
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+}
+
+// newWSConnID 接続IDを生成します
+func newWSConnID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// enqueue メッセージを送信待ちにします（バッファが一杯なら遅いクライアントとして切断する）
+func (c *wsConn) enqueue(msg WSServerMessage) {
+	select {
+	case <-c.done:
+	case c.send <- msg:
+	default:
+		c.close(wsCloseSlowConsumer, "slow consumer")
+	}
+}
+
+// close 接続を閉じます（送信側がクローズフレームを送ってから切断する）
+func (c *wsConn) close(code int, text string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+	})
+}
+
+// respondError リクエストへのエラーを送ります
+func (c *wsConn) respondError(id string, err error) {
+	detail := utils.ToAPIError(err).Detail()
+	c.enqueue(WSServerMessage{Type: "error", ID: id, Error: &detail})
+}
+
+// authenticate アクセストークンを検証し、有効期限を返します（接続中のユーザーと違うユーザーのトークンはエラー）
+func (c *wsConn) authenticate(token string) (time.Time, error) {
+	userID, expiresAt, err := utils.ParseAccessToken(token)
+	if err != nil {
+		return time.Time{}, errors.New("invalid or expired token")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.userID != 0 && userID != c.userID {
+		return time.Time{}, errors.New("token belongs to another user")
+	}
+	c.userID = userID
+	return expiresAt, nil
+}
+
+// user 接続しているユーザーのIDを返します
+func (c *wsConn) user() uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.userID
+}
+
+// writeLoop 送信待ちのメッセージ・ping・再認証の案内を送ります（接続ごとに1つだけ動かす）
+func (c *wsConn) writeLoop(expiresAt time.Time) {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	warn := time.NewTimer(time.Until(expiresAt) - wsReauthWarning)
+	defer warn.Stop()
+	expire := time.NewTimer(time.Until(expiresAt))
+	defer expire.Stop()
+
+	write := func(msg WSServerMessage) bool {
+		c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		return c.conn.WriteJSON(msg) == nil
+	}
+
+	for {
+		select {
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(c.closeCode, c.closeText), time.Now().Add(wsWriteTimeout))
+			c.conn.Close()
+			return
+		case msg := <-c.send:
+			if !write(msg) {
+				c.close(websocket.CloseAbnormalClosure, "")
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				c.close(websocket.CloseAbnormalClosure, "")
+			}
+		case expiresAt = <-c.reauth:
+			warn.Reset(time.Until(expiresAt) - wsReauthWarning)
+			expire.Reset(time.Until(expiresAt))
+		case <-warn.C:
+			write(WSServerMessage{Type: "token_expiring", ExpiresAt: &expiresAt})
+		case <-expire.C:
+			c.close(wsCloseUnauthorized, "token expired")
+		}
+	}
+}
+
+// pumpEvents 購読中のプロジェクトのtodoの変更を送ります
+func (c *wsConn) pumpEvents(sub *realtime.Subscription) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				// ハブのバッファも溢れた
+				c.close(wsCloseSlowConsumer, "slow consumer")
+				return
+			}
+			if event.ProjectID == nil || !c.subscribed(*event.ProjectID) {
+				continue
+			}
+			c.enqueue(WSServerMessage{
+				Type:      "event",
+				Event:     event.Event,
+				EventID:   event.ID,
+				ProjectID: event.ProjectID,
+				Todo:      json.RawMessage(event.Data),
+			})
+		}
+	}
+}
+
+// subscribed プロジェクトを購読しているか
+func (c *wsConn) subscribed(projectID uint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.projects[projectID]
+	return ok
+}
+
+// subscribe プロジェクトの変更とプレゼンスの購読を始めます（閲覧できるプロジェクトのみ）
+func (c *wsConn) subscribe(msg WSClientMessage) {
+	var project models.Project
+	if err := database.DB.First(&project, msg.ProjectID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = utils.NewNotFoundError("Project not found")
+		}
+		c.respondError(msg.ID, err)
+		return
+	}
+	role, err := services.ProjectRole(database.DB, c.user(), &project)
+	if err != nil {
+		c.respondError(msg.ID, err)
+		return
+	}
+	if !role.Allows(services.RoleViewer) {
+		c.respondError(msg.ID, utils.NewNotFoundError("Project not found"))
+		return
+	}
+
+	projectID := project.ID
+	c.mu.Lock()
+	if _, ok := c.projects[projectID]; ok {
+		c.mu.Unlock()
+		c.enqueue(WSServerMessage{Type: "subscribed", ID: msg.ID, ProjectID: &projectID, Users: realtime.DefaultPresence.Members(projectID)})
+		return
+	}
+	c.projects[projectID] = realtime.DefaultPresence.Watch(projectID, func(members []realtime.Member) {
+		c.enqueue(WSServerMessage{Type: "presence", ProjectID: &projectID, Users: members})
+	})
+	c.mu.Unlock()
+
+	realtime.DefaultPresence.Join(c.id, projectID, c.member)
+	c.enqueue(WSServerMessage{Type: "subscribed", ID: msg.ID, ProjectID: &projectID, Users: realtime.DefaultPresence.Members(projectID)})
+}
+
+// unsubscribe プロジェクトの購読をやめます
+func (c *wsConn) unsubscribe(projectID uint) bool {
+	c.mu.Lock()
+	unwatch, ok := c.projects[projectID]
+	delete(c.projects, projectID)
+	c.mu.Unlock()
+	if !ok {
+		return false
+	}
+	unwatch()
+	realtime.DefaultPresence.Leave(c.id, projectID)
+	return true
+}
+
+// mutate create / update / deleteをRESTのエンドポイントと同じ処理で実行します
+// 変更は同じトランザクションでイベントとして記録され、購読中のほかの接続にも届きます
+func (c *wsConn) mutate(msg WSClientMessage) {
+	var todo *models.Todo
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		switch msg.Type {
+		case "create":
+			var req CreateTodoRequest
+			if err := binding.JSON.BindBody(msg.Todo, &req); err != nil {
+				return utils.NewBadRequestError(err.Error())
+			}
+			todo, err = createTodo(tx, c.user(), req)
+		case "update":
+			var req UpdateTodoRequest
+			if err := binding.JSON.BindBody(msg.Changes, &req); err != nil {
+				return utils.NewBadRequestError(err.Error())
+			}
+			todo, err = updateTodo(tx, c.user(), msg.TodoID, req, msg.IfMatch)
+		case "delete":
+			children := msg.Children
+			if children == "" {
+				children = "cascade"
+			}
+			err = deleteTodo(tx, c.user(), msg.TodoID, children, msg.IfMatch)
+		}
+		return err
+	})
+	if err != nil {
+		c.respondError(msg.ID, err)
+		return
+	}
+
+	ack := WSServerMessage{Type: "ack", ID: msg.ID}
+	if todo != nil {
+		ack.Todo = todo
+	}
+	c.enqueue(ack)
+}
+
+// readLoop クライアントからのメッセージを順に処理します
+func (c *wsConn) readLoop() {
+	for {
+		var msg WSClientMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.respondError("", utils.NewBadRequestError("Invalid message"))
+				continue
+			}
+			c.close(websocket.CloseNormalClosure, "")
+			return
+		}
+		c.conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+
+		switch msg.Type {
+		case "ping":
+			c.enqueue(WSServerMessage{Type: "pong", ID: msg.ID})
+		case "auth":
+			expiresAt, err := c.authenticate(msg.Token)
+			if err != nil {
+				c.close(wsCloseUnauthorized, err.Error())
+				return
+			}
+			select {
+			case c.reauth <- expiresAt:
+			case <-c.done:
+				return
+			}
+			c.enqueue(WSServerMessage{Type: "authenticated", ID: msg.ID, UserID: c.user(), ExpiresAt: &expiresAt})
+		case "subscribe":
+			c.subscribe(msg)
+		case "unsubscribe":
+			projectID := msg.ProjectID
+			if !c.unsubscribe(projectID) {
+				c.respondError(msg.ID, utils.NewBadRequestError("Not subscribed to the project"))
+				continue
+			}
+			c.enqueue(WSServerMessage{Type: "unsubscribed", ID: msg.ID, ProjectID: &projectID})
+		case "create", "update", "delete":
+			c.mutate(msg)
+		default:
+			c.respondError(msg.ID, utils.NewBadRequestError("Unknown message type: "+msg.Type))
+		}
+	}
+}
+
+// ServeWebSocket 共有リストを共同編集するためのWebSocket接続
+// Authorizationヘッダー、または接続後10秒以内のauthメッセージでアクセストークンを送って認証します
+func ServeWebSocket(c *gin.Context) {
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgradeがエラーレスポンスを返している
+		return
+	}
+	conn.SetReadLimit(wsMaxMessageSize)
+
+	ws := &wsConn{
+		conn:     conn,
+		id:       newWSConnID(),
+		send:     make(chan WSServerMessage, wsSendBuffer),
+		reauth:   make(chan time.Time, 1),
+		projects: make(map[uint]func()),
+		done:     make(chan struct{}),
+	}
+
+	// ブラウザのWebSocketはヘッダーを付けられないため、最初のメッセージでの認証も受け付ける
+	var expiresAt time.Time
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+		var msg WSClientMessage
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != "auth" {
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(wsCloseUnauthorized, "authentication required"), time.Now().Add(wsWriteTimeout))
+			conn.Close()
+			return
+		}
+		token = msg.Token
+	}
+	if expiresAt, err = ws.authenticate(token); err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(wsCloseUnauthorized, err.Error()), time.Now().Add(wsWriteTimeout))
+		conn.Close()
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Select("id", "email").First(&user, ws.user()).Error; err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(wsCloseUnauthorized, "user not found"), time.Now().Add(wsWriteTimeout))
+		conn.Close()
+		return
+	}
+	ws.member = realtime.Member{UserID: user.ID, Email: user.Email}
+
+	// pongが届くたびに、切断とみなすまでの時間を延ばす
+	conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		return nil
+	})
+
+	sub := realtime.DefaultHub.Subscribe(ws.user())
+	go ws.writeLoop(expiresAt)
+	go ws.pumpEvents(sub)
+	ws.enqueue(WSServerMessage{Type: "ready", UserID: ws.user(), ExpiresAt: &expiresAt})
+
+	ws.readLoop()
+
+	// 切断したらプレゼンスから外し、購読をやめる
+	ws.close(websocket.CloseNormalClosure, "")
+	ws.mu.Lock()
+	projectIDs := make([]uint, 0, len(ws.projects))
+	for projectID := range ws.projects {
+		projectIDs = append(projectIDs, projectID)
+	}
+	ws.mu.Unlock()
+	for _, projectID := range projectIDs {
+		ws.unsubscribe(projectID)
+	}
+	sub.Close()
+}
@@ -0,0 +1,167 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 取り込み元のサービス
+const (
+	SourceTodoist = "todoist"
+	SourceTrello  = "trello"
+)
+
+// ファイル形式
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Plan エクスポートファイルから読み取った、このAPIのモデルに合わせた取り込み内容
+// 同じファイルからは常に同じPlanができるため、途中から再開する場合もTasksの位置で続きを判断できます
+type Plan struct {
+	Projects []Project
+	Tasks    []Task  // 親は必ず子より前に並ぶ
+	Issues   []Issue // 読み取り時点で対応付けられなかったもの
+}
+
+// Project 取り込むプロジェクト（Todoistのプロジェクト / Trelloのボード）
+type Project struct {
+	Ref      string
+	Name     string
+	Archived bool
+}
+
+// Task 取り込むtodo
+type Task struct {
+	Ref        string
+	ParentRef  string
+	ProjectRef string
+	Title      string
+	Completed  bool
+	Priority   string // A〜Z（なければ空）
+	StartAt    *time.Time
+	DueAt      *time.Time
+	Labels     []string // ラベル名（セクション・リストもラベルにする）
+	Comments   []string
+}
+
+// Issue 対応付けられなかった項目
+type Issue struct {
+	Ref     string `json:"ref,omitempty"`
+	Title   string `json:"title,omitempty"`
+	Message string `json:"message"`
+}
+
+// Options 取り込みの設定
+type Options struct {
+	// ProjectName TodoistのCSV（1ファイル1プロジェクト）のプロジェクト名
+	ProjectName string
+	// Location 日付のみ・タイムゾーンなしの日時を解釈するタイムゾーン
+	Location *time.Location
+}
+
+// Parse 取り込み元と形式に応じてエクスポートファイルを読み取ります
+func Parse(source, format string, data []byte, opts Options) (*Plan, error) {
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+	switch {
+	case source == SourceTodoist && format == FormatJSON:
+		return parseTodoistJSON(data, opts)
+	case source == SourceTodoist && format == FormatCSV:
+		return parseTodoistCSV(data, opts)
+	case source == SourceTrello && format == FormatJSON:
+		return parseTrelloJSON(data, opts)
+	case source == SourceTrello && format == FormatCSV:
+		return parseTrelloCSV(data, opts)
+	}
+	return nil, fmt.Errorf("unsupported source or format: %s/%s", source, format)
+}
+
+// DetectFormat ファイル名と内容からJSONかCSVかを判定します
+func DetectFormat(filename string, data []byte) string {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".json"):
+		return FormatJSON
+	case strings.HasSuffix(name, ".csv"):
+		return FormatCSV
+	}
+	trimmed := strings.TrimLeft(strings.TrimPrefix(string(data[:min(len(data), 512)]), "\ufeff"), " \t\r\n")
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// addIssue 対応付けられなかった項目を記録します
+func (p *Plan) addIssue(ref, title, format string, args ...interface{}) {
+	p.Issues = append(p.Issues, Issue{Ref: ref, Title: title, Message: fmt.Sprintf(format, args...)})
+}
+
+// parseDateTime エクスポートファイルの日付・日時を解釈します（日付のみとタイムゾーンなしはlocで解釈）
+func parseDateTime(value string, loc *time.Location) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return &t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid date: %s", value)
+}
+
+// sortTasksByDepth 親が子より前に来るように、元の順序を保ったままtodoを並べ替えます
+// 親が見つからないtodoや循環している場合はトップレベルとして扱います
+func sortTasksByDepth(plan *Plan) {
+	byRef := make(map[string]*Task, len(plan.Tasks))
+	for i := range plan.Tasks {
+		byRef[plan.Tasks[i].Ref] = &plan.Tasks[i]
+	}
+
+	depth := make(map[string]int, len(plan.Tasks))
+	var depthOf func(task *Task, seen map[string]bool) int
+	depthOf = func(task *Task, seen map[string]bool) int {
+		if d, ok := depth[task.Ref]; ok {
+			return d
+		}
+		parent, ok := byRef[task.ParentRef]
+		if task.ParentRef == "" || !ok || seen[task.Ref] {
+			if task.ParentRef != "" {
+				plan.addIssue(task.Ref, task.Title, "parent task not found; imported as a top-level todo")
+				task.ParentRef = ""
+			}
+			depth[task.Ref] = 0
+			return 0
+		}
+		seen[task.Ref] = true
+		d := depthOf(parent, seen) + 1
+		if cut, ok := depth[task.Ref]; ok {
+			// 循環を切ったtodo
+			return cut
+		}
+		depth[task.Ref] = d
+		return d
+	}
+	for i := range plan.Tasks {
+		depthOf(&plan.Tasks[i], map[string]bool{})
+	}
+
+	maxDepth := 0
+	for _, d := range depth {
+		maxDepth = max(maxDepth, d)
+	}
+	sorted := make([]Task, 0, len(plan.Tasks))
+	for d := 0; d <= maxDepth; d++ {
+		for _, task := range plan.Tasks {
+			if depth[task.Ref] == d {
+				sorted = append(sorted, task)
+			}
+		}
+	}
+	plan.Tasks = sorted
+}
@@ -0,0 +1,117 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseUnsupported(t *testing.T) {
+	for _, tt := range [][2]string{{"asana", FormatJSON}, {SourceTodoist, "xml"}} {
+		if _, err := Parse(tt[0], tt[1], []byte("{}"), Options{}); err == nil {
+			t.Errorf("Parse(%s, %s) should fail", tt[0], tt[1])
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{filename: "export.JSON", data: "TYPE,CONTENT", want: FormatJSON},
+		{filename: "export.csv", data: `{"id": 1}`, want: FormatCSV},
+		{filename: "upload", data: "\ufeff  \r\n{\"id\": 1}", want: FormatJSON},
+		{filename: "upload", data: "[]", want: FormatJSON},
+		{filename: "upload", data: "TYPE,CONTENT\n", want: FormatCSV},
+		{filename: "", data: "", want: FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.filename, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) = %s, want %s", tt.filename, tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{value: "2024-10-20T09:00:00.000Z", want: time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{value: "2024-10-20T18:00:00+09:00", want: time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)},
+		{value: "2024-10-20T18:00:00", want: time.Date(2024, 10, 20, 18, 0, 0, 0, tokyo)},
+		{value: "2024-10-20T18:00", want: time.Date(2024, 10, 20, 18, 0, 0, 0, tokyo)},
+		{value: "2024-10-20 18:00:30", want: time.Date(2024, 10, 20, 18, 0, 30, 0, tokyo)},
+		{value: "2024-10-20 18:00", want: time.Date(2024, 10, 20, 18, 0, 0, 0, tokyo)},
+		{value: " 2024-10-20 ", want: time.Date(2024, 10, 20, 0, 0, 0, 0, tokyo)},
+	}
+	for _, tt := range tests {
+		got, err := parseDateTime(tt.value, tokyo)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseDateTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "tomorrow", "10/20/2024", "2024-13-01"} {
+		if _, err := parseDateTime(value, tokyo); err == nil {
+			t.Errorf("parseDateTime(%q) should fail", value)
+		}
+	}
+}
+
+func TestSortTasksByDepth(t *testing.T) {
+	plan := &Plan{Tasks: []Task{
+		{Ref: "grandchild", ParentRef: "child"},
+		{Ref: "child", ParentRef: "root"},
+		{Ref: "orphan", ParentRef: "missing"},
+		{Ref: "root"},
+		{Ref: "loop-a", ParentRef: "loop-b"},
+		{Ref: "loop-b", ParentRef: "loop-a"},
+		{Ref: "self", ParentRef: "self"},
+		{Ref: "second-child", ParentRef: "root"},
+	}}
+	sortTasksByDepth(plan)
+
+	// 親は子より前に並び、同じ深さでは元の順序を保つ
+	position := make(map[string]int, len(plan.Tasks))
+	parents := make(map[string]string, len(plan.Tasks))
+	for i, task := range plan.Tasks {
+		position[task.Ref] = i
+		parents[task.Ref] = task.ParentRef
+	}
+	if len(plan.Tasks) != 8 {
+		t.Fatalf("tasks = %v", taskRefs(plan))
+	}
+	for ref, parent := range parents {
+		if parent != "" && position[parent] >= position[ref] {
+			t.Errorf("parent %s is not before %s: %v", parent, ref, taskRefs(plan))
+		}
+	}
+	if position["child"] > position["second-child"] {
+		t.Errorf("original order not kept: %v", taskRefs(plan))
+	}
+
+	// 親が見つからないtodoはトップレベルにし、循環は1か所で切る
+	if parents["orphan"] != "" || parents["self"] != "" {
+		t.Errorf("orphan/self parents = %q/%q", parents["orphan"], parents["self"])
+	}
+	if (parents["loop-a"] == "") == (parents["loop-b"] == "") {
+		t.Errorf("loop parents = %q/%q, want exactly one cut", parents["loop-a"], parents["loop-b"])
+	}
+	var issueRefs []string
+	for _, issue := range plan.Issues {
+		issueRefs = append(issueRefs, issue.Ref)
+	}
+	if len(issueRefs) != 3 {
+		t.Errorf("issues = %v, want orphan, one of the loop and self", issueRefs)
+	}
+	if !reflect.DeepEqual(plan.Tasks[position["root"]], Task{Ref: "root"}) {
+		t.Errorf("root = %+v", plan.Tasks[position["root"]])
+	}
+}
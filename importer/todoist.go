@@ -0,0 +1,398 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flexID 文字列・数値のどちらでも書かれるID（古いTodoistのエクスポートは数値）
+type flexID string
+
+func (id *flexID) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = flexID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid id: %s", data)
+	}
+	*id = flexID(n.String())
+	return nil
+}
+
+// flexBool trueと1のどちらでも書かれる真偽値
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", "1":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// todoistExport TodoistのSync APIと同じ形式のJSON
+type todoistExport struct {
+	Projects []struct {
+		ID         flexID   `json:"id"`
+		Name       string   `json:"name"`
+		ParentID   flexID   `json:"parent_id"`
+		IsArchived flexBool `json:"is_archived"`
+		IsDeleted  flexBool `json:"is_deleted"`
+	} `json:"projects"`
+	Sections []struct {
+		ID        flexID   `json:"id"`
+		Name      string   `json:"name"`
+		IsDeleted flexBool `json:"is_deleted"`
+	} `json:"sections"`
+	Labels []struct {
+		ID   flexID `json:"id"`
+		Name string `json:"name"`
+	} `json:"labels"`
+	Items []todoistItem `json:"items"`
+	Tasks []todoistItem `json:"tasks"` // REST APIの形式
+	Notes []struct {
+		ItemID         flexID          `json:"item_id"`
+		TaskID         flexID          `json:"task_id"`
+		Content        string          `json:"content"`
+		FileAttachment json.RawMessage `json:"file_attachment"`
+		IsDeleted      flexBool        `json:"is_deleted"`
+	} `json:"notes"`
+}
+
+type todoistItem struct {
+	ID             flexID            `json:"id"`
+	Content        string            `json:"content"`
+	Description    string            `json:"description"`
+	ProjectID      flexID            `json:"project_id"`
+	SectionID      flexID            `json:"section_id"`
+	ParentID       flexID            `json:"parent_id"`
+	Checked        flexBool          `json:"checked"`
+	IsCompleted    flexBool          `json:"is_completed"`
+	IsDeleted      flexBool          `json:"is_deleted"`
+	Priority       int               `json:"priority"`
+	Labels         []json.RawMessage `json:"labels"` // ラベル名（古い形式ではラベルID）
+	ResponsibleUID flexID            `json:"responsible_uid"`
+	Duration       json.RawMessage   `json:"duration"`
+	Due            *struct {
+		Date        string `json:"date"`
+		Datetime    string `json:"datetime"`
+		IsRecurring bool   `json:"is_recurring"`
+		String      string `json:"string"`
+		Timezone    string `json:"timezone"`
+	} `json:"due"`
+}
+
+// todoistAPIPriority APIのpriority（4が最も高いp1）を優先度にします
+func todoistAPIPriority(priority int) string {
+	switch priority {
+	case 4:
+		return "A"
+	case 3:
+		return "B"
+	case 2:
+		return "C"
+	}
+	return ""
+}
+
+// todoistCSVPriority CSVのPRIORITY（1が最も高いp1）を優先度にします
+func todoistCSVPriority(priority string) string {
+	switch strings.TrimSpace(priority) {
+	case "1":
+		return "A"
+	case "2":
+		return "B"
+	case "3":
+		return "C"
+	}
+	return ""
+}
+
+// isJSONNull 値がない（省略またはnull）か
+func isJSONNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+// parseTodoistJSON TodoistのJSON（projects / sections / labels / items / notes）を読み取ります
+func parseTodoistJSON(data []byte, opts Options) (*Plan, error) {
+	var export todoistExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("invalid Todoist JSON: %v", err)
+	}
+	items := append(export.Items, export.Tasks...)
+	if len(export.Projects) == 0 && len(items) == 0 {
+		return nil, fmt.Errorf("invalid Todoist JSON: no projects or items")
+	}
+
+	plan := &Plan{}
+	for _, project := range export.Projects {
+		if project.IsDeleted {
+			continue
+		}
+		if project.ParentID != "" {
+			plan.addIssue("project:"+string(project.ID), project.Name, "nested project imported as a separate project")
+		}
+		plan.Projects = append(plan.Projects, Project{Ref: "project:" + string(project.ID), Name: project.Name, Archived: bool(project.IsArchived)})
+	}
+	sections := make(map[flexID]string)
+	for _, section := range export.Sections {
+		if !section.IsDeleted {
+			sections[section.ID] = section.Name
+		}
+	}
+	labelNames := make(map[string]string)
+	for _, label := range export.Labels {
+		labelNames[string(label.ID)] = label.Name
+	}
+
+	comments := make(map[flexID][]string)
+	for _, note := range export.Notes {
+		itemID := note.ItemID
+		if itemID == "" {
+			itemID = note.TaskID
+		}
+		if note.IsDeleted {
+			continue
+		}
+		if !isJSONNull(note.FileAttachment) {
+			plan.addIssue("item:"+string(itemID), "", "comment attachment not imported")
+		}
+		if strings.TrimSpace(note.Content) != "" {
+			comments[itemID] = append(comments[itemID], note.Content)
+		}
+	}
+
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		ref := "item:" + string(item.ID)
+		task := Task{
+			Ref:        ref,
+			ProjectRef: "project:" + string(item.ProjectID),
+			Title:      item.Content,
+			Completed:  bool(item.Checked || item.IsCompleted),
+			Priority:   todoistAPIPriority(item.Priority),
+			Comments:   comments[item.ID],
+		}
+		if item.ParentID != "" {
+			task.ParentRef = "item:" + string(item.ParentID)
+		}
+		if item.Description != "" {
+			task.Comments = append([]string{item.Description}, task.Comments...)
+		}
+		if name, ok := sections[item.SectionID]; ok && item.SectionID != "" {
+			task.Labels = append(task.Labels, name)
+		}
+		for _, raw := range item.Labels {
+			var name string
+			if err := json.Unmarshal(raw, &name); err != nil {
+				var id json.Number
+				if err := json.Unmarshal(raw, &id); err != nil {
+					continue
+				}
+				var ok bool
+				if name, ok = labelNames[id.String()]; !ok {
+					plan.addIssue(ref, item.Content, "unknown label id %s not imported", id)
+					continue
+				}
+			}
+			task.Labels = append(task.Labels, name)
+		}
+		if item.Due != nil {
+			value := item.Due.Date
+			if item.Due.Datetime != "" {
+				value = item.Due.Datetime
+			}
+			loc := opts.Location
+			if tz, err := time.LoadLocation(item.Due.Timezone); err == nil && item.Due.Timezone != "" {
+				loc = tz
+			}
+			if due, err := parseDateTime(value, loc); err == nil {
+				task.DueAt = due
+			} else {
+				plan.addIssue(ref, item.Content, "due date not imported: %s", value)
+			}
+			if item.Due.IsRecurring {
+				plan.addIssue(ref, item.Content, "recurrence not imported: %s", item.Due.String)
+			}
+		}
+		if item.ResponsibleUID != "" {
+			plan.addIssue(ref, item.Content, "assignee not imported")
+		}
+		if !isJSONNull(item.Duration) {
+			plan.addIssue(ref, item.Content, "duration not imported")
+		}
+		plan.Tasks = append(plan.Tasks, task)
+	}
+
+	sortTasksByDepth(plan)
+	return plan, nil
+}
+
+// csvHeader CSVのヘッダーから列名（大文字小文字を区別しない）の位置を引けるようにします
+type csvHeader map[string]int
+
+func newCSVHeader(row []string) csvHeader {
+	header := make(csvHeader, len(row))
+	for i, name := range row {
+		header[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	return header
+}
+
+// get 列の値を返します（列がなければ空）
+func (h csvHeader) get(row []string, name string) string {
+	if i, ok := h[strings.ToLower(name)]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+// readCSV CSVをすべて読み込みます（1行目はヘッダー）
+func readCSV(data []byte) (csvHeader, [][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("invalid CSV: empty file")
+	}
+	return newCSVHeader(rows[0]), rows[1:], nil
+}
+
+// splitTodoistLabels タイトルの@ラベルを取り出します
+func splitTodoistLabels(content string) (string, []string) {
+	var words, labels []string
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && word[0] == '@' {
+			labels = append(labels, word[1:])
+		} else {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " "), labels
+}
+
+// parseTodoistCSV TodoistのプロジェクトのCSV（TYPE / CONTENT / PRIORITY / INDENT / DATEなど）を読み取ります
+// TYPEがsectionの行はセクション、noteの行は直前のタスクのコメントです
+func parseTodoistCSV(data []byte, opts Options) (*Plan, error) {
+	header, rows, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := header["type"]; !ok {
+		return nil, fmt.Errorf("invalid Todoist CSV: missing TYPE column")
+	}
+	if _, ok := header["content"]; !ok {
+		return nil, fmt.Errorf("invalid Todoist CSV: missing CONTENT column")
+	}
+
+	name := opts.ProjectName
+	if name == "" {
+		name = "Todoist"
+	}
+	plan := &Plan{Projects: []Project{{Ref: "project", Name: name}}}
+
+	var (
+		section string
+		parents []string // インデントごとの親タスク
+		last    *Task
+	)
+	for i, row := range rows {
+		line := i + 2
+		ref := "row:" + strconv.Itoa(line)
+		content := header.get(row, "content")
+		switch strings.ToLower(header.get(row, "type")) {
+		case "section":
+			section = content
+			parents = nil
+			last = nil
+		case "note":
+			if last == nil {
+				plan.addIssue(ref, "", "comment without a task not imported")
+				continue
+			}
+			if content != "" {
+				last.Comments = append(last.Comments, content)
+			}
+		case "task":
+			title, labels := splitTodoistLabels(content)
+			if title == "" {
+				plan.addIssue(ref, content, "task without a title not imported")
+				continue
+			}
+			task := Task{
+				Ref:        ref,
+				ProjectRef: "project",
+				Title:      title,
+				Priority:   todoistCSVPriority(header.get(row, "priority")),
+				Labels:     labels,
+			}
+			if section != "" {
+				task.Labels = append([]string{section}, task.Labels...)
+			}
+			if description := header.get(row, "description"); description != "" {
+				task.Comments = []string{description}
+			}
+
+			indent, err := strconv.Atoi(header.get(row, "indent"))
+			if err != nil || indent < 1 {
+				indent = 1
+			}
+			if indent > len(parents)+1 {
+				indent = len(parents) + 1
+			}
+			parents = parents[:indent-1]
+			if len(parents) > 0 {
+				task.ParentRef = parents[len(parents)-1]
+			}
+			parents = append(parents, ref)
+
+			if date := header.get(row, "date"); date != "" {
+				loc := opts.Location
+				if tz, err := time.LoadLocation(header.get(row, "timezone")); err == nil && header.get(row, "timezone") != "" {
+					loc = tz
+				}
+				if due, err := parseDateTime(date, loc); err == nil {
+					task.DueAt = due
+				} else {
+					plan.addIssue(ref, title, "due date not imported: %s", date)
+				}
+			}
+			if header.get(row, "responsible") != "" {
+				plan.addIssue(ref, title, "assignee not imported")
+			}
+			if header.get(row, "duration") != "" {
+				plan.addIssue(ref, title, "duration not imported")
+			}
+
+			plan.Tasks = append(plan.Tasks, task)
+			last = &plan.Tasks[len(plan.Tasks)-1]
+		case "":
+			continue
+		default:
+			plan.addIssue(ref, content, "unknown row type %q not imported", header.get(row, "type"))
+		}
+	}
+
+	sortTasksByDepth(plan)
+	return plan, nil
+}
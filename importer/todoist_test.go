@@ -0,0 +1,236 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// findTask Refでtodoを探します
+func findTask(t *testing.T, plan *Plan, ref string) Task {
+	t.Helper()
+	for _, task := range plan.Tasks {
+		if task.Ref == ref {
+			return task
+		}
+	}
+	t.Fatalf("task %s not found in %+v", ref, plan.Tasks)
+	return Task{}
+}
+
+// issueMessages Refごとの対応付けられなかった項目のメッセージ
+func issueMessages(plan *Plan) map[string][]string {
+	messages := make(map[string][]string)
+	for _, issue := range plan.Issues {
+		messages[issue.Ref] = append(messages[issue.Ref], issue.Message)
+	}
+	return messages
+}
+
+func taskRefs(plan *Plan) []string {
+	refs := make([]string, len(plan.Tasks))
+	for i, task := range plan.Tasks {
+		refs[i] = task.Ref
+	}
+	return refs
+}
+
+const todoistJSON = `{
+  "projects": [
+    {"id": "100", "name": "仕事", "parent_id": null, "is_archived": false},
+    {"id": 101, "name": "サブ", "parent_id": "100", "is_archived": 1},
+    {"id": "102", "name": "削除済み", "is_deleted": true}
+  ],
+  "sections": [
+    {"id": "200", "name": "今週"},
+    {"id": "201", "name": "削除済み", "is_deleted": true}
+  ],
+  "labels": [{"id": 300, "name": "urgent"}],
+  "items": [
+    {"id": "2", "content": "子タスク", "project_id": "100", "parent_id": "1", "checked": 1, "priority": 1},
+    {"id": "1", "content": "企画書", "description": "概要を書く", "project_id": "100", "section_id": "200",
+     "priority": 4, "labels": ["office", 300, 999],
+     "due": {"date": "2024-10-20T18:00:00", "timezone": "America/New_York", "is_recurring": false}},
+    {"id": "3", "content": "毎週の定例", "project_id": "100", "priority": 3,
+     "due": {"date": "2024-10-21", "is_recurring": true, "string": "every monday"},
+     "responsible_uid": "42", "duration": {"amount": 30, "unit": "minute"}},
+    {"id": "4", "content": "削除済み", "project_id": "100", "is_deleted": 1},
+    {"id": "5", "content": "UTCの期限", "project_id": "101", "priority": 2,
+     "due": {"date": "2024-10-20", "datetime": "2024-10-20T09:00:00Z"}},
+    {"id": "6", "content": "不正な期限", "project_id": "101", "due": {"date": "someday"}}
+  ],
+  "notes": [
+    {"item_id": "1", "content": "最初のコメント"},
+    {"item_id": 1, "content": "添付付き", "file_attachment": {"file_name": "a.pdf"}},
+    {"item_id": "1", "content": "削除済み", "is_deleted": true},
+    {"task_id": "2", "content": "REST形式のコメント"}
+  ]
+}`
+
+func TestParseTodoistJSON(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	plan, err := Parse(SourceTodoist, FormatJSON, []byte(todoistJSON), Options{Location: tokyo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantProjects := []Project{
+		{Ref: "project:100", Name: "仕事"},
+		{Ref: "project:101", Name: "サブ", Archived: true},
+	}
+	if !reflect.DeepEqual(plan.Projects, wantProjects) {
+		t.Errorf("projects = %+v, want %+v", plan.Projects, wantProjects)
+	}
+
+	// 親は子より前に並ぶ（それ以外は元の順序）
+	if refs := taskRefs(plan); !reflect.DeepEqual(refs, []string{"item:1", "item:3", "item:5", "item:6", "item:2"}) {
+		t.Errorf("task order = %v", refs)
+	}
+
+	parent := findTask(t, plan, "item:1")
+	if parent.Title != "企画書" || parent.ProjectRef != "project:100" || parent.Priority != "A" || parent.Completed {
+		t.Errorf("parent = %+v", parent)
+	}
+	if !reflect.DeepEqual(parent.Labels, []string{"今週", "office", "urgent"}) {
+		t.Errorf("labels = %v", parent.Labels)
+	}
+	if !reflect.DeepEqual(parent.Comments, []string{"概要を書く", "最初のコメント", "添付付き"}) {
+		t.Errorf("comments = %v", parent.Comments)
+	}
+	// タイムゾーンなしの日時は期限のtimezoneで解釈する
+	if want := time.Date(2024, 10, 20, 22, 0, 0, 0, time.UTC); parent.DueAt == nil || !parent.DueAt.Equal(want) {
+		t.Errorf("due = %v, want %v", parent.DueAt, want)
+	}
+
+	child := findTask(t, plan, "item:2")
+	if child.ParentRef != "item:1" || !child.Completed || child.Priority != "" {
+		t.Errorf("child = %+v", child)
+	}
+	if !reflect.DeepEqual(child.Comments, []string{"REST形式のコメント"}) {
+		t.Errorf("child comments = %v", child.Comments)
+	}
+
+	// 日付のみはOptions.Locationで解釈する
+	recurring := findTask(t, plan, "item:3")
+	if want := time.Date(2024, 10, 21, 0, 0, 0, 0, tokyo); recurring.DueAt == nil || !recurring.DueAt.Equal(want) || recurring.Priority != "B" {
+		t.Errorf("recurring = %+v", recurring)
+	}
+	if due := findTask(t, plan, "item:5").DueAt; due == nil || !due.Equal(time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("datetime due = %v", due)
+	}
+	if findTask(t, plan, "item:6").DueAt != nil {
+		t.Error("invalid due should not be imported")
+	}
+
+	issues := issueMessages(plan)
+	want := map[string][]string{
+		"project:101": {"nested project imported as a separate project"},
+		"item:1":      {"comment attachment not imported", "unknown label id 999 not imported"},
+		"item:3":      {"recurrence not imported: every monday", "assignee not imported", "duration not imported"},
+		"item:6":      {"due date not imported: someday"},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %v\nwant %v", issues, want)
+	}
+}
+
+func TestParseTodoistJSONErrors(t *testing.T) {
+	for _, data := range []string{`not json`, `{}`, `{"projects": [], "items": []}`, `{"items": [{"id": true}]}`} {
+		if _, err := Parse(SourceTodoist, FormatJSON, []byte(data), Options{}); err == nil {
+			t.Errorf("Parse(%s) should fail", data)
+		}
+	}
+}
+
+const todoistCSV = "\ufeffTYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE,DURATION,DURATION_UNIT\n" +
+	"task,企画書 @office @urgent,概要,1,1,a,,2024-10-20,ja,Asia/Tokyo,,\n" +
+	"note,最初のコメント,,,,a,,,,,,\n" +
+	"task,子タスク,,4,2,a,b,,,,30,minute\n" +
+	"task,孫タスク,,2,4,a,,2024-10-21 09:30,,,,\n" +
+	",,,,,,,,,,,\n" +
+	"section,今週,,,,,,,,,,\n" +
+	"note,親のないコメント,,,,,,,,,,\n" +
+	"task,セクションのタスク,,3,2,a,,tomorrow,,,,\n" +
+	"task,@only,,,1,,,,,,,\n" +
+	"meta,view_style=list,,,,,,,,,,\n"
+
+func TestParseTodoistCSV(t *testing.T) {
+	plan, err := Parse(SourceTodoist, FormatCSV, []byte(todoistCSV), Options{ProjectName: "インポート", Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(plan.Projects, []Project{{Ref: "project", Name: "インポート"}}) {
+		t.Errorf("projects = %+v", plan.Projects)
+	}
+	if refs := taskRefs(plan); !reflect.DeepEqual(refs, []string{"row:2", "row:9", "row:4", "row:5"}) {
+		t.Errorf("task order = %v", refs)
+	}
+
+	task := findTask(t, plan, "row:2")
+	if task.Title != "企画書" || task.Priority != "A" || task.ParentRef != "" {
+		t.Errorf("task = %+v", task)
+	}
+	if !reflect.DeepEqual(task.Labels, []string{"office", "urgent"}) || !reflect.DeepEqual(task.Comments, []string{"概要", "最初のコメント"}) {
+		t.Errorf("labels = %v, comments = %v", task.Labels, task.Comments)
+	}
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	if task.DueAt == nil || !task.DueAt.Equal(time.Date(2024, 10, 20, 0, 0, 0, 0, tokyo)) {
+		t.Errorf("due = %v", task.DueAt)
+	}
+
+	// インデントが飛んでいても直前の階層の子にする
+	if child := findTask(t, plan, "row:4"); child.ParentRef != "row:2" || child.Priority != "" {
+		t.Errorf("child = %+v", child)
+	}
+	grandchild := findTask(t, plan, "row:5")
+	if grandchild.ParentRef != "row:4" || grandchild.Priority != "B" {
+		t.Errorf("grandchild = %+v", grandchild)
+	}
+	if grandchild.DueAt == nil || !grandchild.DueAt.Equal(time.Date(2024, 10, 21, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("grandchild due = %v", grandchild.DueAt)
+	}
+
+	// セクションの後はトップレベルからやり直し、セクション名をラベルにする
+	sectioned := findTask(t, plan, "row:9")
+	if sectioned.ParentRef != "" || !reflect.DeepEqual(sectioned.Labels, []string{"今週"}) || sectioned.Priority != "C" {
+		t.Errorf("sectioned = %+v", sectioned)
+	}
+
+	issues := issueMessages(plan)
+	want := map[string][]string{
+		"row:4":  {"assignee not imported", "duration not imported"},
+		"row:8":  {"comment without a task not imported"},
+		"row:9":  {"due date not imported: tomorrow"},
+		"row:10": {"task without a title not imported"},
+		"row:11": {`unknown row type "meta" not imported`},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %v\nwant %v", issues, want)
+	}
+}
+
+func TestParseTodoistCSVDefaultProjectName(t *testing.T) {
+	plan, err := Parse(SourceTodoist, FormatCSV, []byte("type,content\ntask,a\n"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Projects[0].Name != "Todoist" || len(plan.Tasks) != 1 {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+func TestParseTodoistCSVErrors(t *testing.T) {
+	tests := map[string]string{
+		"":             "empty file",
+		"CONTENT\nx\n": "missing TYPE column",
+		"TYPE\ntask\n": "missing CONTENT column",
+	}
+	for data, want := range tests {
+		_, err := Parse(SourceTodoist, FormatCSV, []byte(data), Options{})
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", data, err, want)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// trelloBoard TrelloのボードのJSONエクスポート
+type trelloBoard struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+	Lists  []struct {
+		ID     string `json:"id"`
+		Name   string `json:"name"`
+		Closed bool   `json:"closed"`
+	} `json:"lists"`
+	Labels []struct {
+		ID    string `json:"id"`
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	} `json:"labels"`
+	Cards []struct {
+		ID           string            `json:"id"`
+		Name         string            `json:"name"`
+		Desc         string            `json:"desc"`
+		IDList       string            `json:"idList"`
+		Closed       bool              `json:"closed"`
+		Pos          float64           `json:"pos"`
+		Start        *string           `json:"start"`
+		Due          *string           `json:"due"`
+		DueComplete  bool              `json:"dueComplete"`
+		IDLabels     []string          `json:"idLabels"`
+		IDMembers    []string          `json:"idMembers"`
+		CustomFields []json.RawMessage `json:"customFieldItems"`
+		Badges       struct {
+			Attachments int `json:"attachments"`
+		} `json:"badges"`
+	} `json:"cards"`
+	Checklists []struct {
+		ID         string  `json:"id"`
+		IDCard     string  `json:"idCard"`
+		Name       string  `json:"name"`
+		Pos        float64 `json:"pos"`
+		CheckItems []struct {
+			ID    string  `json:"id"`
+			Name  string  `json:"name"`
+			State string  `json:"state"`
+			Pos   float64 `json:"pos"`
+			Due   *string `json:"due"`
+		} `json:"checkItems"`
+	} `json:"checklists"`
+	Actions []struct {
+		Type string `json:"type"`
+		Date string `json:"date"`
+		Data struct {
+			Text string `json:"text"`
+			Card struct {
+				ID string `json:"id"`
+			} `json:"card"`
+		} `json:"data"`
+	} `json:"actions"`
+}
+
+// parseTrelloJSON TrelloのボードのJSONを読み取ります
+// ボードはプロジェクト、リストはラベル、チェックリストの項目はサブタスクになります
+func parseTrelloJSON(data []byte, opts Options) (*Plan, error) {
+	var board trelloBoard
+	if err := json.Unmarshal(data, &board); err != nil {
+		return nil, fmt.Errorf("invalid Trello JSON: %v", err)
+	}
+	if board.ID == "" || board.Lists == nil {
+		return nil, fmt.Errorf("invalid Trello JSON: not a board export")
+	}
+
+	name := board.Name
+	if name == "" {
+		name = "Trello"
+	}
+	plan := &Plan{Projects: []Project{{Ref: "board:" + board.ID, Name: name, Archived: board.Closed}}}
+
+	listOrder := make(map[string]int, len(board.Lists))
+	listNames := make(map[string]string, len(board.Lists))
+	closedLists := make(map[string]bool)
+	for i, list := range board.Lists {
+		listOrder[list.ID] = i
+		listNames[list.ID] = list.Name
+		if list.Closed {
+			closedLists[list.ID] = true
+		}
+	}
+	labelNames := make(map[string]string, len(board.Labels))
+	for _, label := range board.Labels {
+		// 名前のないラベルは色の名前にする
+		labelNames[label.ID] = label.Name
+		if label.Name == "" {
+			labelNames[label.ID] = label.Color
+		}
+	}
+
+	// コメントは新しい順に並んでいるため古い順に戻す
+	comments := make(map[string][]string)
+	for i := len(board.Actions) - 1; i >= 0; i-- {
+		action := board.Actions[i]
+		if action.Type == "commentCard" && strings.TrimSpace(action.Data.Text) != "" {
+			comments[action.Data.Card.ID] = append(comments[action.Data.Card.ID], action.Data.Text)
+		}
+	}
+
+	checklists := make(map[string][]int)
+	for i, checklist := range board.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], i)
+	}
+
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool {
+		if listOrder[cards[i].IDList] != listOrder[cards[j].IDList] {
+			return listOrder[cards[i].IDList] < listOrder[cards[j].IDList]
+		}
+		return cards[i].Pos < cards[j].Pos
+	})
+
+	for _, card := range cards {
+		ref := "card:" + card.ID
+		switch {
+		case card.Closed:
+			plan.addIssue(ref, card.Name, "archived card not imported")
+			continue
+		case closedLists[card.IDList]:
+			plan.addIssue(ref, card.Name, "card in archived list %q not imported", listNames[card.IDList])
+			continue
+		}
+
+		task := Task{
+			Ref:        ref,
+			ProjectRef: "board:" + board.ID,
+			Title:      card.Name,
+			Completed:  card.DueComplete,
+			Comments:   comments[card.ID],
+		}
+		if list, ok := listNames[card.IDList]; ok && list != "" {
+			task.Labels = append(task.Labels, list)
+		}
+		for _, id := range card.IDLabels {
+			if name, ok := labelNames[id]; ok && name != "" {
+				task.Labels = append(task.Labels, name)
+			}
+		}
+		if card.Desc != "" {
+			task.Comments = append([]string{card.Desc}, task.Comments...)
+		}
+		if card.Due != nil {
+			if due, err := parseDateTime(*card.Due, opts.Location); err == nil {
+				task.DueAt = due
+			} else {
+				plan.addIssue(ref, card.Name, "due date not imported: %s", *card.Due)
+			}
+		}
+		if card.Start != nil {
+			if start, err := parseDateTime(*card.Start, opts.Location); err == nil {
+				task.StartAt = start
+			} else {
+				plan.addIssue(ref, card.Name, "start date not imported: %s", *card.Start)
+			}
+		}
+		if card.Badges.Attachments > 0 {
+			plan.addIssue(ref, card.Name, "%d attachments not imported", card.Badges.Attachments)
+		}
+		if len(card.IDMembers) > 0 {
+			plan.addIssue(ref, card.Name, "members not imported")
+		}
+		if len(card.CustomFields) > 0 {
+			plan.addIssue(ref, card.Name, "custom fields not imported")
+		}
+		plan.Tasks = append(plan.Tasks, task)
+
+		// チェックリストが1つなら項目をカードのサブタスクに、複数ならチェックリストごとのサブタスクの下にする
+		cardChecklists := checklists[card.ID]
+		sort.SliceStable(cardChecklists, func(i, j int) bool {
+			return board.Checklists[cardChecklists[i]].Pos < board.Checklists[cardChecklists[j]].Pos
+		})
+		for _, index := range cardChecklists {
+			checklist := board.Checklists[index]
+			parentRef := ref
+			if len(cardChecklists) > 1 {
+				parentRef = "checklist:" + checklist.ID
+				plan.Tasks = append(plan.Tasks, Task{Ref: parentRef, ParentRef: ref, ProjectRef: task.ProjectRef, Title: checklist.Name})
+			}
+
+			items := checklist.CheckItems
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+			for _, item := range items {
+				sub := Task{
+					Ref:        "checkitem:" + item.ID,
+					ParentRef:  parentRef,
+					ProjectRef: task.ProjectRef,
+					Title:      item.Name,
+					Completed:  item.State == "complete",
+				}
+				if item.Due != nil {
+					if due, err := parseDateTime(*item.Due, opts.Location); err == nil {
+						sub.DueAt = due
+					}
+				}
+				plan.Tasks = append(plan.Tasks, sub)
+			}
+		}
+	}
+
+	sortTasksByDepth(plan)
+	return plan, nil
+}
+
+// splitTrelloCSVLabels CSVのLabels列（"名前 (色), (色)"）からラベル名を取り出します
+func splitTrelloCSVLabels(value string) []string {
+	var labels []string
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name := part
+		if open := strings.LastIndex(part, "("); open >= 0 && strings.HasSuffix(part, ")") {
+			name = strings.TrimSpace(part[:open])
+			if name == "" {
+				name = part[open+1 : len(part)-1]
+			}
+		}
+		labels = append(labels, name)
+	}
+	return labels
+}
+
+// parseTrelloCSV TrelloのCSVエクスポート（Card Name / List Name / Labels / Due Dateなど）を読み取ります
+// CSVにはチェックリストとコメントの内容が含まれないため、件数だけをレポートします
+func parseTrelloCSV(data []byte, opts Options) (*Plan, error) {
+	header, rows, err := readCSV(data)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := header["card name"]; !ok {
+		return nil, fmt.Errorf("invalid Trello CSV: missing Card Name column")
+	}
+
+	plan := &Plan{}
+	boards := make(map[string]bool)
+	for i, row := range rows {
+		line := i + 2
+		title := header.get(row, "card name")
+		ref := "card:" + header.get(row, "card id")
+		if header.get(row, "card id") == "" {
+			ref = "row:" + strconv.Itoa(line)
+		}
+		if title == "" {
+			plan.addIssue(ref, "", "card without a name not imported")
+			continue
+		}
+		if strings.EqualFold(header.get(row, "archived"), "true") {
+			plan.addIssue(ref, title, "archived card not imported")
+			continue
+		}
+
+		board := header.get(row, "board name")
+		if board == "" {
+			board = opts.ProjectName
+		}
+		if board == "" {
+			board = "Trello"
+		}
+		if !boards[board] {
+			boards[board] = true
+			plan.Projects = append(plan.Projects, Project{Ref: "board:" + board, Name: board})
+		}
+
+		task := Task{
+			Ref:        ref,
+			ProjectRef: "board:" + board,
+			Title:      title,
+			Completed:  strings.EqualFold(header.get(row, "due complete"), "true"),
+		}
+		if list := header.get(row, "list name"); list != "" {
+			task.Labels = append(task.Labels, list)
+		}
+		task.Labels = append(task.Labels, splitTrelloCSVLabels(header.get(row, "labels"))...)
+		if desc := header.get(row, "card description"); desc != "" {
+			task.Comments = []string{desc}
+		}
+		if due := header.get(row, "due date"); due != "" {
+			if t, err := parseDateTime(due, opts.Location); err == nil {
+				task.DueAt = t
+			} else {
+				plan.addIssue(ref, title, "due date not imported: %s", due)
+			}
+		}
+		if start := header.get(row, "start date"); start != "" {
+			if t, err := parseDateTime(start, opts.Location); err == nil {
+				task.StartAt = t
+			} else {
+				plan.addIssue(ref, title, "start date not imported: %s", start)
+			}
+		}
+		if n, _ := strconv.Atoi(header.get(row, "checklist item total count")); n > 0 {
+			plan.addIssue(ref, title, "%d checklist items not imported (not included in CSV exports)", n)
+		}
+		if n, _ := strconv.Atoi(header.get(row, "comment count")); n > 0 {
+			plan.addIssue(ref, title, "%d comments not imported (not included in CSV exports)", n)
+		}
+		if n, _ := strconv.Atoi(header.get(row, "attachment count")); n > 0 {
+			plan.addIssue(ref, title, "%d attachments not imported", n)
+		}
+		if header.get(row, "members") != "" {
+			plan.addIssue(ref, title, "members not imported")
+		}
+		plan.Tasks = append(plan.Tasks, task)
+	}
+
+	sortTasksByDepth(plan)
+	return plan, nil
+}
@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const trelloJSON = `{
+  "id": "board1",
+  "name": "開発",
+  "closed": false,
+  "lists": [
+    {"id": "listDone", "name": "Done", "closed": false},
+    {"id": "listTodo", "name": "ToDo", "closed": false},
+    {"id": "listOld", "name": "Old", "closed": true}
+  ],
+  "labels": [
+    {"id": "l1", "name": "bug", "color": "red"},
+    {"id": "l2", "name": "", "color": "green"}
+  ],
+  "cards": [
+    {"id": "c2", "name": "後のカード", "idList": "listTodo", "pos": 200, "idLabels": ["l2", "missing"],
+     "idMembers": ["m1"], "customFieldItems": [{"id": "f1"}], "badges": {"attachments": 2}},
+    {"id": "c1", "name": "先のカード", "desc": "説明", "idList": "listTodo", "pos": 100, "idLabels": ["l1"],
+     "start": "2024-10-01T00:00:00.000Z", "due": "2024-10-20T09:00:00.000Z", "badges": {}},
+    {"id": "c3", "name": "完了したカード", "idList": "listDone", "pos": 50, "dueComplete": true, "due": "not a date", "badges": {}},
+    {"id": "c4", "name": "アーカイブ済み", "idList": "listTodo", "closed": true, "badges": {}},
+    {"id": "c5", "name": "古いリスト", "idList": "listOld", "badges": {}}
+  ],
+  "checklists": [
+    {"id": "cl2", "idCard": "c2", "name": "後のチェックリスト", "pos": 2, "checkItems": [
+      {"id": "i3", "name": "項目3", "state": "incomplete", "pos": 1}
+    ]},
+    {"id": "cl1", "idCard": "c2", "name": "先のチェックリスト", "pos": 1, "checkItems": [
+      {"id": "i2", "name": "項目2", "state": "complete", "pos": 2},
+      {"id": "i1", "name": "項目1", "state": "incomplete", "pos": 1, "due": "2024-10-15T00:00:00.000Z"}
+    ]},
+    {"id": "cl3", "idCard": "c1", "name": "単独", "pos": 1, "checkItems": [
+      {"id": "i4", "name": "項目4", "state": "complete", "pos": 1}
+    ]}
+  ],
+  "actions": [
+    {"type": "commentCard", "date": "2024-10-03T00:00:00.000Z", "data": {"text": "新しいコメント", "card": {"id": "c1"}}},
+    {"type": "updateCard", "date": "2024-10-02T00:00:00.000Z", "data": {"card": {"id": "c1"}}},
+    {"type": "commentCard", "date": "2024-10-01T00:00:00.000Z", "data": {"text": "古いコメント", "card": {"id": "c1"}}}
+  ]
+}`
+
+func TestParseTrelloJSON(t *testing.T) {
+	plan, err := Parse(SourceTrello, FormatJSON, []byte(trelloJSON), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(plan.Projects, []Project{{Ref: "board:board1", Name: "開発"}}) {
+		t.Errorf("projects = %+v", plan.Projects)
+	}
+
+	// リストの順・リスト内の位置の順に並び、親は子より前に来る
+	// チェックリストが1つのカードは項目を直接サブタスクにし、複数ならチェックリストごとのサブタスクの下にする
+	wantOrder := []string{
+		"card:c3", "card:c1", "card:c2",
+		"checkitem:i4", "checklist:cl1", "checklist:cl2",
+		"checkitem:i1", "checkitem:i2", "checkitem:i3",
+	}
+	if refs := taskRefs(plan); !reflect.DeepEqual(refs, wantOrder) {
+		t.Errorf("task order = %v\nwant %v", refs, wantOrder)
+	}
+
+	first := findTask(t, plan, "card:c1")
+	if first.Title != "先のカード" || first.ProjectRef != "board:board1" || first.Completed {
+		t.Errorf("card = %+v", first)
+	}
+	if !reflect.DeepEqual(first.Labels, []string{"ToDo", "bug"}) {
+		t.Errorf("labels = %v", first.Labels)
+	}
+	if !reflect.DeepEqual(first.Comments, []string{"説明", "古いコメント", "新しいコメント"}) {
+		t.Errorf("comments = %v", first.Comments)
+	}
+	if first.DueAt == nil || !first.DueAt.Equal(time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("due = %v", first.DueAt)
+	}
+	if first.StartAt == nil || !first.StartAt.Equal(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("start = %v", first.StartAt)
+	}
+
+	// 名前のないラベルは色の名前にする
+	if second := findTask(t, plan, "card:c2"); !reflect.DeepEqual(second.Labels, []string{"ToDo", "green"}) {
+		t.Errorf("labels = %v", second.Labels)
+	}
+	if done := findTask(t, plan, "card:c3"); !done.Completed || done.DueAt != nil {
+		t.Errorf("done = %+v", done)
+	}
+
+	if item := findTask(t, plan, "checkitem:i4"); item.ParentRef != "card:c1" || !item.Completed {
+		t.Errorf("single checklist item = %+v", item)
+	}
+	if checklist := findTask(t, plan, "checklist:cl1"); checklist.ParentRef != "card:c2" || checklist.Title != "先のチェックリスト" {
+		t.Errorf("checklist = %+v", checklist)
+	}
+	item := findTask(t, plan, "checkitem:i1")
+	if item.ParentRef != "checklist:cl1" || item.Completed || item.DueAt == nil {
+		t.Errorf("checklist item = %+v", item)
+	}
+
+	issues := issueMessages(plan)
+	want := map[string][]string{
+		"card:c2": {"2 attachments not imported", "members not imported", "custom fields not imported"},
+		"card:c3": {"due date not imported: not a date"},
+		"card:c4": {"archived card not imported"},
+		"card:c5": {`card in archived list "Old" not imported`},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %v\nwant %v", issues, want)
+	}
+}
+
+func TestParseTrelloJSONErrors(t *testing.T) {
+	for _, data := range []string{`not json`, `{}`, `{"id": "b"}`, `{"lists": []}`} {
+		if _, err := Parse(SourceTrello, FormatJSON, []byte(data), Options{}); err == nil {
+			t.Errorf("Parse(%s) should fail", data)
+		}
+	}
+
+	// 名前のないボードは既定の名前にする
+	plan, err := Parse(SourceTrello, FormatJSON, []byte(`{"id": "b", "lists": []}`), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Projects[0].Name != "Trello" || len(plan.Tasks) != 0 {
+		t.Errorf("plan = %+v", plan)
+	}
+}
+
+const trelloCSV = "Card ID,Card Name,Card Description,Labels,Members,List Name,Board Name,Due Date,Start Date,Due Complete,Archived,Checklist Item Total Count,Comment Count,Attachment Count\n" +
+	"c1,設計,説明,\"bug (red), (green)\",,ToDo,開発,2024-10-20T09:00:00.000Z,2024-10-01,false,false,3,2,1\n" +
+	"c2,完了,,,alice,Done,開発,,,true,false,0,0,0\n" +
+	"c3,アーカイブ,,,,Done,開発,,,false,true,0,0,0\n" +
+	",名前だけ,,,,,,bad,bad,,,,,\n" +
+	"c5,,,,,,,,,,,,,\n"
+
+func TestParseTrelloCSV(t *testing.T) {
+	plan, err := Parse(SourceTrello, FormatCSV, []byte(trelloCSV), Options{ProjectName: "既定", Location: time.UTC})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantProjects := []Project{{Ref: "board:開発", Name: "開発"}, {Ref: "board:既定", Name: "既定"}}
+	if !reflect.DeepEqual(plan.Projects, wantProjects) {
+		t.Errorf("projects = %+v", plan.Projects)
+	}
+	if refs := taskRefs(plan); !reflect.DeepEqual(refs, []string{"card:c1", "card:c2", "row:5"}) {
+		t.Errorf("task order = %v", refs)
+	}
+
+	task := findTask(t, plan, "card:c1")
+	if !reflect.DeepEqual(task.Labels, []string{"ToDo", "bug", "green"}) || !reflect.DeepEqual(task.Comments, []string{"説明"}) {
+		t.Errorf("labels = %v, comments = %v", task.Labels, task.Comments)
+	}
+	if task.DueAt == nil || !task.DueAt.Equal(time.Date(2024, 10, 20, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("due = %v", task.DueAt)
+	}
+	if task.StartAt == nil || !task.StartAt.Equal(time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("start = %v", task.StartAt)
+	}
+	if done := findTask(t, plan, "card:c2"); !done.Completed {
+		t.Errorf("done = %+v", done)
+	}
+	if fallback := findTask(t, plan, "row:5"); fallback.ProjectRef != "board:既定" {
+		t.Errorf("fallback = %+v", fallback)
+	}
+
+	issues := issueMessages(plan)
+	want := map[string][]string{
+		"card:c1": {
+			"3 checklist items not imported (not included in CSV exports)",
+			"2 comments not imported (not included in CSV exports)",
+			"1 attachments not imported",
+		},
+		"card:c2": {"members not imported"},
+		"card:c3": {"archived card not imported"},
+		"row:5":   {"due date not imported: bad", "start date not imported: bad"},
+		"card:c5": {"card without a name not imported"},
+	}
+	if !reflect.DeepEqual(issues, want) {
+		t.Errorf("issues = %v\nwant %v", issues, want)
+	}
+}
+
+func TestParseTrelloCSVErrors(t *testing.T) {
+	_, err := Parse(SourceTrello, FormatCSV, []byte("Name,List\nx,y\n"), Options{})
+	if err == nil || !strings.Contains(err.Error(), "missing Card Name column") {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestSplitTrelloCSVLabels(t *testing.T) {
+	tests := map[string][]string{
+		"":                       nil,
+		"bug (red)":              {"bug"},
+		"(green), bug (red)":     {"green", "bug"},
+		"a (b) c (red), plain":   {"a (b) c", "plain"},
+		" spaced ( yellow ) , ,": {"spaced"},
+	}
+	for value, want := range tests {
+		if got := splitTrelloCSVLabels(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitTrelloCSVLabels(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go-gin-todo-api/handlers"
+)
+
+// importPollInterval 待機中のインポートジョブを確認する間隔を取得します
+func importPollInterval() time.Duration {
+	seconds := 5 // デフォルト値
+	if secStr := os.Getenv("IMPORT_POLL_INTERVAL_SEC"); secStr != "" {
+		if parsed, err := strconv.Atoi(secStr); err == nil && parsed > 0 {
+			seconds = parsed
+		}
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// StartImportRunner TodoistやTrelloのインポートジョブを順に処理するバックグラウンドジョブを開始します
+// 再起動などで中断したジョブは、占有期間が切れたあと処理済みの位置から再開されます
+func StartImportRunner() {
+	interval := importPollInterval()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			// 待機中のジョブがなくなるまで続けて処理する
+			for {
+				ran, err := handlers.RunNextImportJob()
+				if err != nil {
+					log.Printf("Failed to run import job: %v", err)
+				}
+				if !ran {
+					break
+				}
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Import runner started (interval: %s)", interval)
+}
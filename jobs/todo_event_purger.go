@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/services"
+)
+
+// todoEventPurgeInterval 保持期間を過ぎたtodoの変更イベントを削除する間隔
+const todoEventPurgeInterval = time.Minute
+
+// StartTodoEventPurger 再送用に保持しているtodoの変更イベントを、保持期間を過ぎたものから削除するバックグラウンドジョブを開始します
+func StartTodoEventPurger() {
+	retention := time.Duration(envPositiveInt("EVENTS_RETENTION_MIN", 60)) * time.Minute
+
+	go func() {
+		ticker := time.NewTicker(todoEventPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := services.PurgeTodoEvents(database.DB, retention); err != nil {
+				log.Printf("Failed to purge todo events: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Todo event purger started (retention: %s)", retention)
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/services"
+)
+
+// tombstonePurgeInterval 保持期間を過ぎた墓標を削除する間隔
+const tombstonePurgeInterval = time.Hour
+
+// StartTombstonePurger 差分同期のために残している完全に削除したtodoの墓標を、保持期間を過ぎたものから削除するバックグラウンドジョブを開始します
+func StartTombstonePurger() {
+	retention := services.TombstoneRetention()
+
+	go func() {
+		ticker := time.NewTicker(tombstonePurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := services.PurgeTombstones(database.DB, retention)
+			if err != nil {
+				log.Printf("Failed to purge tombstones: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d tombstones", purged)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Tombstone purger started (retention: %s)", retention)
+}
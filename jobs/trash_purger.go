@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/services"
+)
+
+// trashRetention ゴミ箱の保持期間を取得します
+func trashRetention() time.Duration {
+	days := 30 // デフォルト値
+	if daysStr := os.Getenv("TRASH_RETENTION_DAYS"); daysStr != "" {
+		if parsed, err := strconv.Atoi(daysStr); err == nil && parsed >= 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// trashPurgeInterval 期限切れのゴミ箱を削除する間隔を取得します
+func trashPurgeInterval() time.Duration {
+	minutes := 60 // デフォルト値
+	if minStr := os.Getenv("TRASH_PURGE_INTERVAL_MIN"); minStr != "" {
+		if parsed, err := strconv.Atoi(minStr); err == nil && parsed > 0 {
+			minutes = parsed
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// StartTrashPurger 保持期間を過ぎたゴミ箱のtodoを定期的に完全削除するバックグラウンドジョブを開始します
+func StartTrashPurger() {
+	retention := trashRetention()
+	interval := trashPurgeInterval()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := services.PurgeExpiredTrash(database.DB, retention)
+			if err != nil {
+				log.Printf("Failed to purge trash: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d todos from trash", purged)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Trash purger started (retention: %s, interval: %s)", retention, interval)
+}
@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/services"
+)
+
+// webhookDeliveryPurgeInterval 保持期間を過ぎたwebhookの配信ログを削除する間隔
+const webhookDeliveryPurgeInterval = time.Hour
+
+// StartWebhookDeliveryPurger 配信を終えたwebhookの配信ログを、保持期間を過ぎたものから削除するバックグラウンドジョブを開始します
+func StartWebhookDeliveryPurger() {
+	retention := time.Duration(envPositiveInt("WEBHOOK_DELIVERY_RETENTION_DAYS", 30)) * 24 * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(webhookDeliveryPurgeInterval)
+		defer ticker.Stop()
+
+		for {
+			purged, err := services.PurgeWebhookDeliveries(database.DB, retention)
+			if err != nil {
+				log.Printf("Failed to purge webhook deliveries: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d webhook deliveries", purged)
+			}
+			<-ticker.C
+		}
+	}()
+
+	log.Printf("Webhook delivery purger started (retention: %s)", retention)
+}
@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/services"
+)
+
+// envPositiveInt 環境変数を正の整数として読み込みます（未設定・不正な値ならデフォルト値）
+func envPositiveInt(name string, defaultValue int) int {
+	if str := os.Getenv(name); str != "" {
+		if parsed, err := strconv.Atoi(str); err == nil && parsed > 0 {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// StartWebhookDispatcher キューに追加されたwebhookの配信を送信するバックグラウンドジョブを開始します
+// WEBHOOK_WORKERS個のワーカーが並行して送信するため、応答の遅い送信先があっても他の配信は待たされません
+// 送信に失敗した配信は待ち時間を倍にしながら再送し、WEBHOOK_MAX_ATTEMPTS回失敗するとfailedになります
+func StartWebhookDispatcher() {
+	interval := time.Duration(envPositiveInt("WEBHOOK_POLL_INTERVAL_SEC", 5)) * time.Second
+	timeout := time.Duration(envPositiveInt("WEBHOOK_TIMEOUT_SEC", 10)) * time.Second
+	maxAttempts := envPositiveInt("WEBHOOK_MAX_ATTEMPTS", 10)
+	workers := envPositiveInt("WEBHOOK_WORKERS", 4)
+	client := services.NewWebhookClient(timeout)
+
+	// 配信の占有はSKIP LOCKEDで行うため、ワーカー同士が同じ配信を取り合うことはない
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				// 送信時刻になった配信がなくなるまで続けて送る
+				for {
+					sent, err := services.DeliverNextWebhook(database.DB, client, maxAttempts)
+					if err != nil {
+						log.Printf("Failed to deliver webhook: %v", err)
+					}
+					if !sent {
+						break
+					}
+				}
+				<-ticker.C
+			}
+		}()
+	}
+
+	log.Printf("Webhook dispatcher started (workers: %d, interval: %s, timeout: %s, max attempts: %d)", workers, interval, timeout, maxAttempts)
+}
@@ -3,13 +3,19 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	_ "time/tzdata" // Alpineイメージにはタイムゾーンデータがないため埋め込む
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 
 	"go-gin-todo-api/database"
+	"go-gin-todo-api/grpcserver"
 	"go-gin-todo-api/handlers"
+	"go-gin-todo-api/jobs"
 	"go-gin-todo-api/middleware"
+	"go-gin-todo-api/realtime"
+	"go-gin-todo-api/storage"
 )
 
 func main() {
@@ -22,9 +28,23 @@ func main() {
 	}
 
 	database.InitDB()
+	storage.InitStorage()
+	jobs.StartTrashPurger()
+	jobs.StartImportRunner()
+	jobs.StartWebhookDispatcher()
+	jobs.StartWebhookDeliveryPurger()
+	jobs.StartTodoEventPurger()
+	jobs.StartTombstonePurger()
+	realtime.StartListener()
+	grpcserver.Start()
 	
 	r := gin.Default()
 
+	// 開発時にリクエスト・レスポンスをOpenAPIのドキュメントに照らして検証する
+	if os.Getenv("OPENAPI_VALIDATION") == "true" {
+		r.Use(middleware.OpenAPIValidator(handlers.OpenAPIDocument))
+	}
+
 	// ヘルスチェック
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -32,6 +52,10 @@ func main() {
 		})
 	})
 
+	// OpenAPIドキュメントとSwagger UI
+	r.GET("/openapi.json", handlers.GetOpenAPISpec)
+	r.GET("/docs/*filepath", handlers.SwaggerUI)
+
 	// 認証エンドポイント（認証不要）
 	auth := r.Group("/auth")
 	{
@@ -41,19 +65,112 @@ func main() {
 		auth.POST("/logout", handlers.Logout)
 	}
 
+	// iCalendar購読フィード（URLのトークンで認証するためJWT不要）
+	r.GET("/feeds/:token/todos.ics", handlers.GetCalendarFeed)
+
+	// 共有リストの共同編集（WebSocketはヘッダーを付けられないため、接続後のメッセージでも認証する）
+	r.GET("/ws", handlers.ServeWebSocket)
+
 	// 認証必須エンドポイント
 	api := r.Group("/")
 	api.Use(middleware.AuthMiddleware())
 	{
 		// ユーザー確認
 		api.GET("/me", handlers.GetMe)
+		api.PATCH("/me", handlers.UpdateMe)
+		api.POST("/me/calendar-token", handlers.CreateCalendarToken)
+		api.DELETE("/me/calendar-token", handlers.DeleteCalendarToken)
+
+		// todoの変更のリアルタイム配信（Server-Sent Events）
+		api.GET("/events", handlers.StreamEvents)
+
+		// GraphQL（RESTと同じ認証）
+		api.POST("/graphql", handlers.GraphQL)
+
+		// 差分同期（オフライン対応クライアント向け）
+		api.GET("/sync", handlers.GetSync)
+		api.POST("/sync", handlers.PushSync)
 
 		// Todoエンドポイント
 		api.GET("/todos", handlers.GetTodos)
 		api.POST("/todos", handlers.CreateTodo)
+		api.GET("/todos.ics", handlers.ExportTodosICS)
+		api.POST("/todos/import/ics", handlers.ImportTodosICS)
+		api.GET("/todos.txt", handlers.ExportTodosTxt)
+		api.POST("/todos/import/todotxt", handlers.ImportTodosTxt)
+		api.POST("/imports", handlers.CreateImportJob)
+		api.GET("/imports", handlers.GetImportJobs)
+		api.GET("/imports/:id", handlers.GetImportJob)
+		api.GET("/imports/:id/report", handlers.GetImportReport)
+		api.POST("/imports/:id/resume", handlers.ResumeImportJob)
+		api.POST("/todos/batch", handlers.BatchTodos)
+		api.POST("/todos/quick", handlers.CreateQuickTodo)
+		api.GET("/todos/search", handlers.SearchTodos)
+		api.GET("/todos/overdue", handlers.GetOverdueTodos)
+		api.GET("/todos/today", handlers.GetTodayTodos)
+		api.GET("/todos/upcoming", handlers.GetUpcomingTodos)
 		api.GET("/todos/:id", handlers.GetTodo)
 		api.PATCH("/todos/:id", handlers.UpdateTodo)
 		api.DELETE("/todos/:id", handlers.DeleteTodo)
+		api.GET("/todos/:id/tree", handlers.GetTodoTree)
+		api.GET("/todos/:id/occurrences", handlers.GetTodoOccurrences)
+		api.POST("/todos/:id/skip", handlers.SkipTodoOccurrence)
+		api.POST("/todos/:id/end-series", handlers.EndTodoSeries)
+		api.POST("/todos/:id/restore", handlers.RestoreTodo)
+		api.GET("/todos/:id/history", handlers.GetTodoHistory)
+		api.POST("/todos/:id/revert", handlers.RevertTodo)
+		api.GET("/todos/:id/shares", handlers.GetTodoShares)
+		api.POST("/todos/:id/shares", handlers.CreateTodoShare)
+		api.GET("/todos/:id/comments", handlers.GetComments)
+		api.POST("/todos/:id/comments", handlers.CreateComment)
+		api.PATCH("/todos/:id/comments/:comment_id", handlers.UpdateComment)
+		api.DELETE("/todos/:id/comments/:comment_id", handlers.DeleteComment)
+		api.GET("/todos/:id/comments/:comment_id/history", handlers.GetCommentHistory)
+		api.GET("/todos/:id/attachments", handlers.GetAttachments)
+		api.POST("/todos/:id/attachments", handlers.UploadAttachment)
+		api.GET("/todos/:id/attachments/:attachment_id", handlers.DownloadAttachment)
+		api.DELETE("/todos/:id/attachments/:attachment_id", handlers.DeleteAttachment)
+
+		// webhookエンドポイント
+		api.GET("/webhooks", handlers.GetWebhooks)
+		api.POST("/webhooks", handlers.CreateWebhook)
+		api.GET("/webhooks/:id", handlers.GetWebhook)
+		api.PATCH("/webhooks/:id", handlers.UpdateWebhook)
+		api.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+		api.GET("/webhooks/:id/deliveries", handlers.GetWebhookDeliveries)
+		api.GET("/webhooks/:id/deliveries/:delivery_id", handlers.GetWebhookDelivery)
+		api.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handlers.RedeliverWebhookDelivery)
+
+		// ゴミ箱エンドポイント
+		api.GET("/trash", handlers.GetTrash)
+		api.DELETE("/trash", handlers.EmptyTrash)
+		api.DELETE("/trash/:id", handlers.PurgeTodo)
+
+		// プロジェクトエンドポイント
+		api.GET("/projects", handlers.GetProjects)
+		api.POST("/projects", handlers.CreateProject)
+		api.GET("/projects/:id", handlers.GetProject)
+		api.PATCH("/projects/:id", handlers.UpdateProject)
+		api.DELETE("/projects/:id", handlers.DeleteProject)
+		api.POST("/projects/:id/archive", handlers.ArchiveProject)
+		api.POST("/projects/:id/unarchive", handlers.UnarchiveProject)
+		api.GET("/projects/:id/todos", handlers.GetProjectTodos)
+		api.GET("/projects/:id/shares", handlers.GetProjectShares)
+		api.POST("/projects/:id/shares", handlers.CreateProjectShare)
+
+		// 共有・招待エンドポイント
+		api.PATCH("/shares/:id", handlers.UpdateShare)
+		api.DELETE("/shares/:id", handlers.DeleteShare)
+		api.GET("/invitations", handlers.GetInvitations)
+		api.POST("/invitations/:id/accept", handlers.AcceptInvitation)
+		api.POST("/invitations/:id/decline", handlers.DeclineInvitation)
+
+		// ラベルエンドポイント
+		api.GET("/labels", handlers.GetLabels)
+		api.POST("/labels", handlers.CreateLabel)
+		api.PATCH("/labels/:id", handlers.UpdateLabel)
+		api.DELETE("/labels/:id", handlers.DeleteLabel)
+		api.POST("/labels/:id/merge", handlers.MergeLabel)
 	}
 
 	r.Run()
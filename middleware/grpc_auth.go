@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go-gin-todo-api/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcUserIDKey gRPCのcontextにuser_idを設定するキー
+type grpcUserIDKey struct{}
+
+// GRPCUserID AuthUnaryInterceptor・AuthStreamInterceptorがcontextに設定したuser_idを返します
+func GRPCUserID(ctx context.Context) (uint, bool) {
+	userID, ok := ctx.Value(grpcUserIDKey{}).(uint)
+	return userID, ok
+}
+
+// grpcTokenExpiresAtKey gRPCのcontextにアクセストークンの有効期限を設定するキー
+type grpcTokenExpiresAtKey struct{}
+
+// GRPCTokenExpiresAt 認証に使ったアクセストークンの有効期限を返します（ストリームを有効期限で終えるのに使う）
+func GRPCTokenExpiresAt(ctx context.Context) (time.Time, bool) {
+	expiresAt, ok := ctx.Value(grpcTokenExpiresAtKey{}).(time.Time)
+	return expiresAt, ok
+}
+
+// authenticateGRPC metadataのauthorization（Bearer <token>）を検証し、user_idとトークンの有効期限を設定したcontextを返します
+func authenticateGRPC(ctx context.Context) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Authorization metadata is required")
+	}
+
+	// "Bearer " プレフィックスをチェック
+	parts := strings.Split(values[0], " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid authorization metadata format")
+	}
+
+	userID, expiresAt, err := utils.ParseAccessToken(parts[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+	ctx = context.WithValue(ctx, grpcUserIDKey{}, userID)
+	return context.WithValue(ctx, grpcTokenExpiresAtKey{}, expiresAt), nil
+}
+
+// AuthUnaryInterceptor JWTトークンを検証し、user_idをcontextに設定するunaryのインターセプター
+// publicMethodsに含まれるメソッド（/todo.v1.AuthService/Loginなど）は検証しません
+func AuthUnaryInterceptor(publicMethods map[string]bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticateGRPC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authenticatedStream user_idを設定したcontextを返すServerStream
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// AuthStreamInterceptor JWTトークンを検証し、user_idをcontextに設定するstreamのインターセプター
+// トークンは接続時に1回だけ検証するため、長く続くストリームは有効期限（GRPCTokenExpiresAt）でハンドラーが終えます
+func AuthStreamInterceptor(publicMethods map[string]bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if publicMethods[info.FullMethod] {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticateGRPC(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go-gin-todo-api/openapi"
+	"go-gin-todo-api/utils"
+)
+
+// bufferedWriter 検証が終わるまでレスポンスを書き出さずに溜めるResponseWriter
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+func toErrorItems(errs []string) []utils.ErrorItem {
+	items := make([]utils.ErrorItem, len(errs))
+	for i, err := range errs {
+		items[i] = utils.ErrorItem{Message: err}
+	}
+	return items
+}
+
+// OpenAPIValidator リクエストとレスポンスをOpenAPIのドキュメントに照らして検証するミドルウェア（開発用）
+// ドキュメントにない操作はそのまま通します
+// 定義に合わないリクエストは400を返してハンドラーを実行せず、定義に合わないレスポンスはログに出して500に置き換えます
+// レスポンスを検証のためにすべて溜めるので、本番環境では使わないでください
+func OpenAPIValidator(doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		op := doc.Operation(c.Request.Method, c.FullPath())
+		if op == nil {
+			c.Next()
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				utils.RespondBadRequest(c, "Failed to read request body")
+				c.Abort()
+				return
+			}
+			// ハンドラーが読めるように戻す
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		if errs := doc.ValidateRequest(op, c.Param, c.Request.URL.Query(), body); len(errs) > 0 {
+			utils.RespondAPIError(c, utils.NewValidationError("Request does not match the API specification", toErrorItems(errs)))
+			c.Abort()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		if errs := doc.ValidateResponse(op, writer.status, writer.body.Bytes()); len(errs) > 0 {
+			log.Printf("OpenAPI: response of %s %s does not match the specification: %v", c.Request.Method, c.FullPath(), errs)
+			original.Header().Del("ETag")
+			original.Header().Del("Location")
+			c.JSON(http.StatusInternalServerError, utils.ErrorResponse{Error: utils.ErrorDetail{
+				Code:    string(utils.ErrorCodeInternal),
+				Message: "Response does not match the API specification",
+				Details: toErrorItems(errs),
+			}})
+			return
+		}
+		original.WriteHeader(writer.status)
+		original.Write(writer.body.Bytes())
+	}
+}
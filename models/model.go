@@ -1,27 +1,462 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type User struct {
-	ID           uint      `gorm:"primaryKey" json:"id"`
-	Email        string    `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash string    `gorm:"column:password_hash;not null" json:"-"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt    time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	Email              string     `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash       string     `gorm:"column:password_hash;not null" json:"-"`
+	Timezone           string     `gorm:"not null;default:'Asia/Tokyo'" json:"timezone"`
+	CascadeCompletion  bool       `gorm:"not null;default:false" json:"cascade_completion"`   // 親を完了すると子孫もすべて完了
+	AutoCompleteParent bool       `gorm:"not null;default:false" json:"auto_complete_parent"` // 子がすべて完了すると親も自動完了
+	CalendarTokenHash  *string    `gorm:"column:calendar_token_hash;uniqueIndex" json:"-"`    // iCalendar購読URLのトークン（SHA256）
+	SyncResetAt        *time.Time `gorm:"column:sync_reset_at" json:"-"`                      // 閲覧できるtodoが共有の変更で入れ替わった日時（同期のやり直しが必要）
+	CreatedAt          time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
 type Todo struct {
+	ID           uint           `gorm:"primaryKey;index:idx_todos_user_created_id,priority:3" json:"id"`
+	UserID       uint           `gorm:"column:user_id;not null;index;index:idx_todos_user_created_id,priority:1;uniqueIndex:idx_todos_user_ical_uid,priority:1" json:"user_id"`
+	Title        string         `gorm:"not null" json:"title"`
+	Completed    bool           `gorm:"default:false" json:"completed"`
+	ProjectID    *uint          `gorm:"column:project_id;index" json:"project_id"`
+	ParentID     *uint          `gorm:"column:parent_id;index" json:"parent_id"`
+	StartAt      *time.Time     `gorm:"column:start_at" json:"start_at"`
+	DueAt        *time.Time     `gorm:"column:due_at;index" json:"due_at"`
+	RRule        *string        `gorm:"column:rrule" json:"rrule"` // RFC 5545のRRULE（シリーズの最新回だけが持つ）
+	SeriesID     *uint          `gorm:"column:series_id;index" json:"series_id"`
+	SeriesStart  *time.Time     `gorm:"column:series_start" json:"-"` // COUNTを数える起点（シリーズ最初の期限）
+	ICalUID      *string        `gorm:"column:ical_uid;uniqueIndex:idx_todos_user_ical_uid,priority:2" json:"ical_uid,omitempty"`
+	Priority     *string        `gorm:"column:priority;size:1" json:"priority"`
+	CompletedAt  *time.Time     `gorm:"column:completed_at" json:"completed_at"`
+	Extras       TodoExtras     `gorm:"type:jsonb" json:"extras,omitempty"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime;index:idx_todos_user_created_id,priority:2" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`           // ゴミ箱に入れた日時（論理削除）
+	Version      uint           `gorm:"not null;default:1" json:"version"` // 更新のたびに増える（楽観的排他制御）
+	ETag         string         `gorm:"-" json:"etag"`
+	ChangeSeq    int64          `gorm:"column:change_seq;not null;default:nextval('todo_change_seq');index" json:"change_seq"`              // 作成・更新・削除のたびにトリガーで採番し直す（差分同期）
+	ClientID     *string        `gorm:"column:client_id;uniqueIndex:idx_todos_client_user_client_id,priority:2" json:"client_id,omitempty"` // オフラインのクライアントが作成時に付けたID（再送の重複防止）
+	ClientUserID *uint          `gorm:"column:client_user_id;uniqueIndex:idx_todos_client_user_client_id,priority:1" json:"-"`              // client_idを付けて作成したユーザー（共有プロジェクトでは持ち主と異なる）
+
+	// リレーション（オプション）
+	User    User     `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Project *Project `gorm:"foreignKey:ProjectID;constraint:OnDelete:SET NULL" json:"-"`
+	Parent  *Todo    `gorm:"foreignKey:ParentID;constraint:OnDelete:SET NULL" json:"-"`
+	Labels  []Label  `gorm:"many2many:todo_labels;constraint:OnDelete:CASCADE" json:"labels"`
+}
+
+// BeforeUpdate todoを更新するたびにバージョンを進め、完了状態の変更に合わせて完了日時を記録します
+// カラムを指定した更新（Update / Updatesにmapを渡した場合）が対象です
+func (t *Todo) BeforeUpdate(tx *gorm.DB) error {
+	if updates, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		updates["version"] = gorm.Expr("version + 1")
+		if completed, ok := updates["completed"].(bool); ok {
+			if _, ok := updates["completed_at"]; !ok {
+				if completed {
+					// 完了済みのものを完了にし直しても完了日時は変えない
+					updates["completed_at"] = gorm.Expr("CASE WHEN completed THEN completed_at ELSE ? END", time.Now())
+				} else {
+					updates["completed_at"] = nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// AfterFind 取得したtodoにETagを設定します
+func (t *Todo) AfterFind(tx *gorm.DB) error {
+	t.ETag = FormatETag(t.Version)
+	return nil
+}
+
+// AfterCreate 作成したtodoにETagを設定します
+func (t *Todo) AfterCreate(tx *gorm.DB) error {
+	t.ETag = FormatETag(t.Version)
+	return nil
+}
+
+// FormatETag todoのバージョンからETagを作成します
+func FormatETag(version uint) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// 変更履歴の操作種別
+const (
+	RevisionActionCreate  = "create"
+	RevisionActionUpdate  = "update"
+	RevisionActionDelete  = "delete"
+	RevisionActionRestore = "restore"
+	RevisionActionRevert  = "revert"
+)
+
+// TodoSnapshot 変更履歴に保存するtodoの状態（リバートに使用）
+type TodoSnapshot struct {
+	Title     string     `json:"title"`
+	Completed bool       `json:"completed"`
+	ProjectID *uint      `json:"project_id"`
+	ParentID  *uint      `json:"parent_id"`
+	StartAt   *time.Time `json:"start_at"`
+	DueAt     *time.Time `json:"due_at"`
+	RRule     *string    `json:"rrule"`
+	Priority  *string    `json:"priority,omitempty"`
+	LabelIDs  []uint     `json:"label_ids"`
+}
+
+// TodoExtras todo.txtのkey:value形式の追加情報のうち、対応するフィールドがないもの
+type TodoExtras map[string]string
+
+// Value jsonbカラムに保存する値を返します（空ならNULL）
+func (e TodoExtras) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	return marshalJSONValue(e)
+}
+
+// Scan jsonbカラムの値を読み込みます
+func (e *TodoExtras) Scan(value interface{}) error {
+	return scanJSONValue(value, e)
+}
+
+// Value jsonbカラムに保存する値を返します
+func (s TodoSnapshot) Value() (driver.Value, error) {
+	return marshalJSONValue(s)
+}
+
+// Scan jsonbカラムの値を読み込みます
+func (s *TodoSnapshot) Scan(value interface{}) error {
+	return scanJSONValue(value, s)
+}
+
+// FieldChange 1フィールドの変更前後の値
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// TodoChanges フィールド名ごとの変更内容
+type TodoChanges map[string]FieldChange
+
+// Value jsonbカラムに保存する値を返します
+func (c TodoChanges) Value() (driver.Value, error) {
+	return marshalJSONValue(c)
+}
+
+// Scan jsonbカラムの値を読み込みます
+func (c *TodoChanges) Scan(value interface{}) error {
+	return scanJSONValue(value, c)
+}
+
+// TodoRevision todoの変更履歴（誰が・いつ・何を変更したか）
+type TodoRevision struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	TodoID         uint         `gorm:"column:todo_id;not null;index" json:"todo_id"`
+	ActorID        uint         `gorm:"column:actor_id;not null" json:"actor_id"`
+	Action         string       `gorm:"not null" json:"action"`
+	Changes        TodoChanges  `gorm:"type:jsonb;not null" json:"changes"`
+	Snapshot       TodoSnapshot `gorm:"type:jsonb;not null" json:"snapshot"`                       // 変更後の状態（削除の場合は削除前の状態）
+	RevertedFromID *uint        `gorm:"column:reverted_from_id" json:"reverted_from_id,omitempty"` // リバート元の履歴
+	CreatedAt      time.Time    `gorm:"autoCreateTime" json:"created_at"`
+
+	// リレーション（オプション）
+	Todo *Todo `gorm:"foreignKey:TodoID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// marshalJSONValue 値をJSON文字列にしてjsonbカラムに保存します
+func marshalJSONValue(v interface{}) (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// scanJSONValue jsonbカラムの値をJSONとして読み込みます
+func scanJSONValue(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("unsupported jsonb value: %T", value)
+	}
+}
+
+// Label ユーザーごとのラベル（todo_labelsテーブルでtodoと多対多）
+type Label struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
-	UserID    uint      `gorm:"column:user_id;not null;index" json:"user_id"`
-	Title     string    `gorm:"not null" json:"title"`
-	Completed bool      `gorm:"default:false" json:"completed"`
+	UserID    uint      `gorm:"column:user_id;not null;uniqueIndex:idx_labels_user_name" json:"user_id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_labels_user_name" json:"name"`
+	Color     string    `gorm:"not null;default:'#9e9e9e'" json:"color"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
-	
+}
+
+// Project todoをまとめるリスト
+// アーカイブされたプロジェクトのtodoは一覧から除外されます
+type Project struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"column:user_id;not null;index" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	ArchivedAt *time.Time `gorm:"column:archived_at" json:"archived_at"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// 共有の対象
+const (
+	ShareResourceProject = "project"
+	ShareResourceTodo    = "todo"
+)
+
+// 共有（招待）の状態
+const (
+	ShareStatusPending  = "pending"
+	ShareStatusAccepted = "accepted"
+	ShareStatusDeclined = "declined"
+)
+
+// Share プロジェクトまたはtodoを他のユーザーと共有する招待
+// 招待されたユーザーが承諾すると、roleの権限（viewer / editor / owner）でアクセスできるようになります
+type Share struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	ResourceType string     `gorm:"column:resource_type;not null;uniqueIndex:idx_shares_resource_user" json:"resource_type"`
+	ResourceID   uint       `gorm:"column:resource_id;not null;uniqueIndex:idx_shares_resource_user" json:"resource_id"`
+	UserID       uint       `gorm:"column:user_id;not null;uniqueIndex:idx_shares_resource_user;index" json:"user_id"` // 招待されたユーザー
+	InvitedByID  uint       `gorm:"column:invited_by_id;not null" json:"invited_by_id"`
+	Role         string     `gorm:"not null" json:"role"`
+	Status       string     `gorm:"not null;default:'pending'" json:"status"`
+	RespondedAt  *time.Time `gorm:"column:responded_at" json:"responded_at"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+
 	// リレーション（オプション）
-	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	User      *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	InvitedBy *User `gorm:"foreignKey:InvitedByID;constraint:OnDelete:CASCADE" json:"invited_by,omitempty"`
+}
+
+// Comment todoへのコメント
+type Comment struct {
+	ID        uint       `gorm:"primaryKey;index:idx_comments_todo_id,priority:2" json:"id"`
+	TodoID    uint       `gorm:"column:todo_id;not null;index:idx_comments_todo_id,priority:1" json:"todo_id"`
+	UserID    uint       `gorm:"column:user_id;not null" json:"user_id"` // 投稿者
+	Body      string     `gorm:"type:text;not null" json:"body"`
+	EditedAt  *time.Time `gorm:"column:edited_at" json:"edited_at"` // 最後に編集した日時（未編集ならnull）
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// リレーション（オプション）
+	Todo     *Todo  `gorm:"foreignKey:TodoID;constraint:OnDelete:CASCADE" json:"-"`
+	User     *User  `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+	Mentions []User `gorm:"many2many:comment_mentions;constraint:OnDelete:CASCADE" json:"-"` // 本文の@メールアドレスで言及されたユーザー
+}
+
+// CommentRevision コメントの編集履歴（編集前の本文を保存）
+type CommentRevision struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CommentID uint      `gorm:"column:comment_id;not null;index" json:"comment_id"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"` // 編集された日時
+
+	// リレーション（オプション）
+	Comment *Comment `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Attachment todoの添付ファイル（ファイル本体はstorageに保存）
+type Attachment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	TodoID      uint      `gorm:"column:todo_id;not null;index" json:"todo_id"`
+	UserID      uint      `gorm:"column:user_id;not null;index" json:"user_id"` // アップロードしたユーザー（容量の集計対象）
+	Filename    string    `gorm:"not null" json:"filename"`
+	ContentType string    `gorm:"column:content_type;not null" json:"content_type"` // ファイルの内容から判定したContent-Type
+	Size        int64     `gorm:"not null" json:"size"`
+	Checksum    string    `gorm:"not null" json:"checksum"` // SHA-256（16進）
+	StorageKey  string    `gorm:"column:storage_key;not null;uniqueIndex" json:"-"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// リレーション（オプション）
+	Todo *Todo `gorm:"foreignKey:TodoID;constraint:OnDelete:CASCADE" json:"-"`
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// インポートジョブの状態
+const (
+	ImportStatusPending   = "pending"
+	ImportStatusRunning   = "running"
+	ImportStatusCompleted = "completed"
+	ImportStatusFailed    = "failed"
+)
+
+// ImportJob TodoistやTrelloのエクスポートファイルの取り込みジョブ
+// バックグラウンドで少しずつ処理し、中断しても処理済みの位置（Processed）から再開します
+type ImportJob struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	UserID      uint        `gorm:"column:user_id;not null;index" json:"user_id"`
+	Source      string      `gorm:"not null" json:"source"` // todoist / trello
+	Format      string      `gorm:"not null" json:"format"` // json / csv
+	Filename    string      `gorm:"not null" json:"filename"`
+	ProjectName string      `gorm:"column:project_name" json:"project_name,omitempty"`
+	Timezone    string      `gorm:"not null" json:"-"`   // 日付のみの値を解釈するタイムゾーン（作成時のユーザーの設定）
+	Payload     []byte      `gorm:"type:bytea" json:"-"` // アップロードされたファイル（完了後に削除）
+	Status      string      `gorm:"not null;default:'pending';index" json:"status"`
+	Total       int         `gorm:"not null;default:0" json:"total"`
+	Processed   int         `gorm:"not null;default:0" json:"processed"`
+	Imported    int         `gorm:"not null;default:0" json:"imported"`
+	IssueCount  int         `gorm:"column:issue_count;not null;default:0" json:"issue_count"`
+	State       ImportState `gorm:"type:jsonb" json:"-"`
+	Error       *string     `json:"error"`
+	LockedUntil *time.Time  `gorm:"column:locked_until" json:"-"` // 処理中のプロセスがこの日時まで占有する
+	StartedAt   *time.Time  `gorm:"column:started_at" json:"started_at"`
+	FinishedAt  *time.Time  `gorm:"column:finished_at" json:"finished_at"`
+	CreatedAt   time.Time   `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time   `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// リレーション（オプション）
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ImportState 取り込み元の参照と、作成したプロジェクト・ラベル・todoのIDの対応
+type ImportState struct {
+	Projects map[string]uint `json:"projects"`
+	Labels   map[string]uint `json:"labels"`
+	Todos    map[string]uint `json:"todos"`
+}
+
+// Value jsonbカラムに保存する値を返します
+func (s ImportState) Value() (driver.Value, error) {
+	return marshalJSONValue(s)
+}
+
+// Scan jsonbカラムの値を読み込みます
+func (s *ImportState) Scan(value interface{}) error {
+	return scanJSONValue(value, s)
+}
+
+// ImportIssue インポートで対応付けられなかった項目（レポート）
+type ImportIssue struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	JobID     uint      `gorm:"column:job_id;not null;index" json:"job_id"`
+	Ref       string    `json:"ref,omitempty"` // 取り込み元での参照（例: card:<id>）
+	Title     string    `json:"title,omitempty"`
+	Message   string    `gorm:"not null" json:"message"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// リレーション（オプション）
+	Job *ImportJob `gorm:"foreignKey:JobID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// webhookで通知するイベント
+const (
+	WebhookEventTodoCreated = "todo.created"
+	WebhookEventTodoUpdated = "todo.updated"
+	WebhookEventTodoDeleted = "todo.deleted"
+	WebhookEventTodoPurged  = "todo.purged"
+	WebhookEventUserLogin   = "user.login"
+)
+
+// WebhookEvents 購読できるイベントの一覧
+var WebhookEvents = []string{WebhookEventTodoCreated, WebhookEventTodoUpdated, WebhookEventTodoDeleted, WebhookEventTodoPurged, WebhookEventUserLogin}
+
+// Webhook ユーザーが登録した通知先のURL
+type Webhook struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	UserID    uint            `gorm:"column:user_id;not null;index" json:"user_id"`
+	URL       string          `gorm:"not null" json:"url"`
+	Secret    string          `gorm:"not null" json:"-"` // 署名の鍵（登録時にしか返さない）
+	Events    WebhookEventSet `gorm:"type:jsonb;not null" json:"events"`
+	Active    bool            `gorm:"not null" json:"active"`
+	CreatedAt time.Time       `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time       `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// リレーション（オプション）
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// WebhookEventSet webhookが購読するイベント
+type WebhookEventSet []string
+
+// Has イベントを購読しているか
+func (s WebhookEventSet) Has(event string) bool {
+	for _, e := range s {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Value jsonbカラムに保存する値を返します
+func (s WebhookEventSet) Value() (driver.Value, error) {
+	return marshalJSONValue(s)
+}
+
+// Scan jsonbカラムの値を読み込みます
+func (s *WebhookEventSet) Scan(value interface{}) error {
+	return scanJSONValue(value, s)
+}
+
+// webhookの配信の状態
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery webhookの配信（キューと配信ログを兼ねる）
+// 失敗した配信はNextAttemptAtまで待って再送し、上限回数に達するとfailedになります
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	WebhookID      uint       `gorm:"column:webhook_id;not null;index" json:"webhook_id"`
+	EventID        string     `gorm:"column:event_id;not null;index" json:"event_id"` // 再配信しても変わらない（受信側の重複排除用）
+	Event          string     `gorm:"not null" json:"event"`
+	Payload        string     `gorm:"type:text;not null" json:"-"` // 署名したリクエスト本文
+	Status         string     `gorm:"not null;default:'pending';index:idx_webhook_deliveries_queue,priority:1" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"column:next_attempt_at;index:idx_webhook_deliveries_queue,priority:2" json:"next_attempt_at"`
+	LockedUntil    *time.Time `gorm:"column:locked_until" json:"-"` // 送信中のプロセスがこの日時まで占有する
+	ResponseStatus *int       `gorm:"column:response_status" json:"response_status"`
+	Error          *string    `json:"error"`
+	DurationMs     *int       `gorm:"column:duration_ms" json:"duration_ms"`
+	RedeliveryOf   *uint      `gorm:"column:redelivery_of" json:"redelivery_of,omitempty"` // 手動で再配信した元の配信
+	DeliveredAt    *time.Time `gorm:"column:delivered_at" json:"delivered_at"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// リレーション（オプション）
+	Webhook *Webhook `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TodoEventRemoved todoが別のプロジェクトに移動した・プロジェクトから外れて閲覧できなくなったことを知らせるイベント（リアルタイム配信のみ）
+const TodoEventRemoved = "todo.removed"
+
+// TodoEvent リアルタイム配信（SSE）するtodoの変更イベント
+// 変更と同じトランザクションで閲覧できるユーザーごとに記録し、保持期間内なら再接続時に再送します
+type TodoEvent struct {
+	ID        uint      `gorm:"primaryKey;index:idx_todo_events_user_id,priority:2" json:"id"`
+	UserID    uint      `gorm:"column:user_id;not null;index:idx_todo_events_user_id,priority:1" json:"user_id"` // 通知先のユーザー
+	Event     string    `gorm:"not null" json:"event"`                                                           // todo.created / todo.updated / todo.deleted / todo.purged / todo.removed
+	TodoID    uint      `gorm:"column:todo_id;not null" json:"todo_id"`
+	ProjectID *uint     `gorm:"column:project_id" json:"project_id"` // WebSocketでプロジェクトごとに配るのに使う
+	Data      string    `gorm:"type:text;not null" json:"-"`         // 変更後のtodoのJSON
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"created_at"`
+	// Cursor 配信したときの再送の起点（DBには保存しない）
+	Cursor string `gorm:"-" json:"-"`
+
+	// リレーション（オプション）
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
 }
 
 type RefreshToken struct {
@@ -31,7 +466,21 @@ type RefreshToken struct {
 	ExpiresAt time.Time  `gorm:"column:expires_at;not null" json:"expires_at"`
 	RevokedAt *time.Time `gorm:"column:revoked_at" json:"revoked_at"`
 	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
-	
+
 	// リレーション（オプション）
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
+
+// TodoTombstone 完全に削除したtodoの墓標
+// ゴミ箱から消えたtodoも差分同期で削除として伝えられるよう、閲覧できたユーザーごとに保持期間まで残します
+type TodoTombstone struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"column:user_id;not null;index:idx_todo_tombstones_user_seq,priority:1" json:"-"` // 削除を伝えるユーザー
+	TodoID    uint      `gorm:"column:todo_id;not null" json:"todo_id"`
+	ChangeSeq int64     `gorm:"column:change_seq;not null;default:nextval('todo_change_seq');index:idx_todo_tombstones_user_seq,priority:2" json:"change_seq"` // todoと同じシーケンスで採番
+	DeletedAt time.Time `gorm:"column:deleted_at;not null" json:"deleted_at"`                                                                                  // ゴミ箱に入れた日時
+	CreatedAt time.Time `gorm:"autoCreateTime;index" json:"-"`                                                                                                 // 完全に削除した日時
+
+	// リレーション（オプション）
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
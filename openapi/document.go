@@ -0,0 +1,125 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Document OpenAPI 3.0のドキュメント
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+
+	// 型からスキーマを作る際の状態
+	names     map[reflect.Type]string
+	overrides map[reflect.Type]*Schema
+	// gin形式のパス（/todos/:id）とメソッドから操作を引く索引
+	routes map[string]*Operation
+}
+
+// Info ドキュメントの情報
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components 共通のスキーマと認証方式
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme 認証方式
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// PathItem パスごとの操作（キーは小文字のHTTPメソッド）
+type PathItem map[string]*Operation
+
+// Operation 1つのAPIの操作
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+}
+
+// Parameter パス・クエリ・ヘッダーのパラメータ
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody リクエストの本文
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response ステータスコードごとのレスポンス
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType 本文の形式とスキーマ
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// New 空のドキュメントを作成します
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]*PathItem),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+		names:     make(map[reflect.Type]string),
+		overrides: make(map[reflect.Type]*Schema),
+		routes:    make(map[string]*Operation),
+	}
+}
+
+// Add 操作を追加します（pathはginと同じ:id形式で、ドキュメントには{id}形式で出力します）
+func (d *Document) Add(method, path string, op *Operation) {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	openAPIPath := strings.Join(segments, "/")
+
+	item, ok := d.Paths[openAPIPath]
+	if !ok {
+		item = &PathItem{}
+		d.Paths[openAPIPath] = item
+	}
+	(*item)[strings.ToLower(method)] = op
+	d.routes[strings.ToUpper(method)+" "+path] = op
+}
+
+// Operation ginのルートのパス（c.FullPath()）とメソッドに対応する操作を返します（ドキュメントになければnil）
+func (d *Document) Operation(method, path string) *Operation {
+	return d.routes[strings.ToUpper(method)+" "+path]
+}
+
+// JSONBody application/jsonの本文
+func JSONBody(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
@@ -0,0 +1,225 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema OpenAPI 3.0のスキーマ（このAPIで使うキーワードだけ）
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Define 型のスキーマを明示的に指定します（独自のJSONの形式を持つ型に使う）
+func (d *Document) Define(v interface{}, schema *Schema) {
+	d.overrides[reflect.TypeOf(v)] = schema
+}
+
+// SchemaOf 値の型からスキーマを作成します
+// 名前のある構造体はcomponents/schemasに登録して$refを返します
+// フィールドはjsonタグの名前を使い、bindingタグ（required・min・max・oneof・email）を制約として反映します
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	return d.schemaOf(reflect.TypeOf(v))
+}
+
+// Nullable スキーマをnullも受け付けるようにします
+func Nullable(schema *Schema) *Schema {
+	if schema.Ref != "" {
+		// $refと同じ階層のキーワードは無視されるため、allOfで包む
+		return &Schema{AllOf: []*Schema{schema}, Nullable: true}
+	}
+	nullable := *schema
+	nullable.Nullable = true
+	return &nullable
+}
+
+// ArrayOf 要素のスキーマの配列
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+func (d *Document) schemaOf(t reflect.Type) *Schema {
+	if schema, ok := d.overrides[t]; ok {
+		return schema
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return Nullable(d.schemaOf(t.Elem()))
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		zero := 0.0
+		return &Schema{Type: "integer", Format: "int64", Minimum: &zero}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t == rawMessageType {
+			return &Schema{}
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		// nilのスライスはnullになる
+		return Nullable(ArrayOf(d.schemaOf(t.Elem())))
+	case reflect.Map:
+		return &Schema{Type: "object", Nullable: true, AdditionalProperties: d.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return d.namedStruct(t)
+	}
+	// interface{}など
+	return &Schema{}
+}
+
+// namedStruct 構造体をcomponents/schemasに登録し、$refを返します
+func (d *Document) namedStruct(t reflect.Type) *Schema {
+	name, ok := d.names[t]
+	if !ok {
+		name = t.Name()
+		// 別のパッケージの同じ名前の型と区別する
+		for i := 2; d.Components.Schemas[name] != nil; i++ {
+			name = t.Name() + strconv.Itoa(i)
+		}
+		d.names[t] = name
+		// 再帰する型のため、先に名前を登録してから中身を作る
+		d.Components.Schemas[name] = &Schema{}
+		*d.Components.Schemas[name] = *d.structSchema(t)
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(schema, t)
+	return schema
+}
+
+// addFields 構造体のフィールドをプロパティに追加します（埋め込みの構造体のフィールドは展開する）
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			d.addFields(schema, field.Type)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.schemaOf(field.Type)
+		required := applyBinding(&property, field.Tag.Get("binding"))
+		schema.Properties[name] = property
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+// applyBinding bindingタグの検証ルールをスキーマの制約にし、requiredが含まれるかを返します
+// diveより後のルールは要素に対するものなので反映しません
+func applyBinding(schema **Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+	constrained := **schema
+	target := &constrained
+	if len(constrained.AllOf) > 0 {
+		// $refの型には制約を付けない
+		target = &Schema{}
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			*schema = &constrained
+			return required
+		case "required":
+			required = true
+			if target.Type == "string" {
+				one := 1
+				target.MinLength = &one
+			}
+		case "email":
+			target.Format = "email"
+		case "url":
+			target.Format = "uri"
+		case "oneof":
+			target.Enum = strings.Fields(value)
+		case "min", "max", "gte", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			lower := key == "min" || key == "gte"
+			switch target.Type {
+			case "string":
+				length := int(n)
+				if lower {
+					target.MinLength = &length
+				} else {
+					target.MaxLength = &length
+				}
+			case "array":
+				length := int(n)
+				if lower {
+					target.MinItems = &length
+				} else {
+					target.MaxItems = &length
+				}
+			case "integer", "number":
+				if lower {
+					target.Minimum = &n
+				} else {
+					target.Maximum = &n
+				}
+			}
+		}
+	}
+	*schema = &constrained
+	return required
+}
@@ -0,0 +1,241 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidateRequest リクエストのパス・クエリパラメータと本文が操作の定義に合っているか検証し、違反の一覧を返します
+// pathParamsにはginのc.Paramの値を渡してください
+func (d *Document) ValidateRequest(op *Operation, pathParams func(string) string, query url.Values, body []byte) []string {
+	var errs []string
+	for _, param := range op.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value = pathParams(param.Name)
+			present = value != ""
+		case "query":
+			present = query.Has(param.Name)
+			value = query.Get(param.Name)
+		default:
+			continue
+		}
+		location := param.In + "." + param.Name
+		if !present {
+			if param.Required {
+				errs = append(errs, location+": is required")
+			}
+			continue
+		}
+		d.validate(param.Schema, parseParameter(param.Schema, value), location, &errs)
+	}
+
+	if op.RequestBody != nil {
+		media, ok := op.RequestBody.Content["application/json"]
+		if !ok {
+			return errs
+		}
+		if len(bytes.TrimSpace(body)) == 0 {
+			if op.RequestBody.Required {
+				errs = append(errs, "body: is required")
+			}
+			return errs
+		}
+		value, err := decodeJSON(body)
+		if err != nil {
+			return append(errs, "body: invalid JSON: "+err.Error())
+		}
+		d.validate(media.Schema, value, "body", &errs)
+	}
+	return errs
+}
+
+// ValidateResponse レスポンスのステータスコードと本文が操作の定義に合っているか検証し、違反の一覧を返します
+func (d *Document) ValidateResponse(op *Operation, status int, body []byte) []string {
+	response, ok := op.Responses[strconv.Itoa(status)]
+	if !ok {
+		if response, ok = op.Responses["default"]; !ok {
+			return []string{fmt.Sprintf("status: %d is not documented", status)}
+		}
+	}
+
+	media, ok := response.Content["application/json"]
+	if !ok {
+		if len(body) > 0 {
+			return []string{fmt.Sprintf("body: status %d must not have a body", status)}
+		}
+		return nil
+	}
+	value, err := decodeJSON(body)
+	if err != nil {
+		return []string{"body: invalid JSON: " + err.Error()}
+	}
+	var errs []string
+	d.validate(media.Schema, value, "body", &errs)
+	return errs
+}
+
+// decodeJSON 整数と小数を区別できるよう、数値をjson.Numberとして読み込みます
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parseParameter パラメータの文字列をスキーマの型の値に変換します（変換できなければ文字列のまま）
+func parseParameter(schema *Schema, value string) interface{} {
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// typeNames エラーメッセージでの型の名前
+var typeNames = map[string]string{"integer": "an integer", "number": "a number"}
+
+// resolve $refをcomponents/schemasのスキーマに置き換えます
+func (d *Document) resolve(schema *Schema) *Schema {
+	for schema.Ref != "" {
+		schema = d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+	}
+	return schema
+}
+
+// validate 値がスキーマに合っているか検証し、違反をerrsに追加します
+func (d *Document) validate(schema *Schema, value interface{}, path string, errs *[]string) {
+	schema = d.resolve(schema)
+	if value == nil {
+		if !schema.Nullable && (schema.Type != "" || len(schema.AllOf) > 0) {
+			*errs = append(*errs, path+": must not be null")
+		}
+		return
+	}
+	for _, sub := range schema.AllOf {
+		d.validate(sub, value, path, errs)
+	}
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	switch schema.Type {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok {
+			fail("must be %s", typeNames[schema.Type])
+			return
+		}
+		f, err := n.Float64()
+		if err != nil {
+			fail("must be %s", typeNames[schema.Type])
+			return
+		}
+		if schema.Type == "integer" {
+			if _, err := strconv.ParseInt(n.String(), 10, 64); err != nil {
+				fail("must be an integer")
+				return
+			}
+		}
+		if schema.Minimum != nil && f < *schema.Minimum {
+			fail("must be >= %v", *schema.Minimum)
+		}
+		if schema.Maximum != nil && f > *schema.Maximum {
+			fail("must be <= %v", *schema.Maximum)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		length := utf8.RuneCountInString(s)
+		if schema.MinLength != nil && length < *schema.MinLength {
+			fail("must be at least %d characters", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && length > *schema.MaxLength {
+			fail("must be at most %d characters", *schema.MaxLength)
+		}
+		if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+			fail("must be one of %s", strings.Join(schema.Enum, ", "))
+		}
+		switch schema.Format {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		case "email":
+			if address, err := mail.ParseAddress(s); err != nil || address.Address != s {
+				fail("must be an email address")
+			}
+		case "uri":
+			if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+				fail("must be an absolute URI")
+			}
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if schema.MinItems != nil && len(items) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && len(items) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+		if schema.Items != nil {
+			for i, item := range items {
+				d.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				*errs = append(*errs, path+"."+name+": is required")
+			}
+		}
+		// エラーの順番を一定にする
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if property, ok := schema.Properties[key]; ok {
+				d.validate(property, object[key], path+"."+key, errs)
+			} else if schema.AdditionalProperties != nil {
+				d.validate(schema.AdditionalProperties, object[key], path+"."+key, errs)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package realtime
+
+import (
+	"sync"
+
+	"go-gin-todo-api/models"
+)
+
+// subscriptionBuffer 1つの接続に溜めておけるイベントの数（溢れた接続は切断し、再接続時に再送させる）
+const subscriptionBuffer = 256
+
+// Subscription ユーザーのtodoの変更イベントの購読
+// Cが閉じられたら、送信が追いつかずに購読が打ち切られたことを表します
+type Subscription struct {
+	UserID uint
+	C      <-chan models.TodoEvent
+
+	ch     chan models.TodoEvent
+	hub    *Hub
+	closed bool
+}
+
+// Close 購読を終了します（何度呼び出してもよい）
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub このインスタンスに接続しているクライアントへイベントを配るハブ
+type Hub struct {
+	mu   sync.Mutex
+	subs map[uint]map[*Subscription]struct{}
+}
+
+// NewHub 空のハブを作成します
+func NewHub() *Hub {
+	return &Hub{subs: make(map[uint]map[*Subscription]struct{})}
+}
+
+// DefaultHub SSE・WebSocketの接続が共有するハブ
+var DefaultHub = NewHub()
+
+// Subscribe ユーザーのイベントの購読を開始します
+func (h *Hub) Subscribe(userID uint) *Subscription {
+	ch := make(chan models.TodoEvent, subscriptionBuffer)
+	sub := &Subscription{UserID: userID, C: ch, ch: ch, hub: h}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][sub] = struct{}{}
+	return sub
+}
+
+// remove 購読を外してチャンネルを閉じます
+func (h *Hub) remove(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(sub)
+}
+
+func (h *Hub) removeLocked(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	close(sub.ch)
+	delete(h.subs[sub.UserID], sub)
+	if len(h.subs[sub.UserID]) == 0 {
+		delete(h.subs, sub.UserID)
+	}
+}
+
+// HasSubscribers ユーザーの購読がこのインスタンスにあるか
+func (h *Hub) HasSubscribers(userID uint) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs[userID]) > 0
+}
+
+// UserIDs 購読しているユーザーの一覧を返します
+func (h *Hub) UserIDs() []uint {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]uint, 0, len(h.subs))
+	for id := range h.subs {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// Publish イベントを通知先のユーザーの購読すべてに送ります
+// 遅い接続のためにほかの接続を待たせないよう、バッファが一杯の購読は打ち切ります
+func (h *Hub) Publish(event models.TodoEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			h.removeLocked(sub)
+		}
+	}
+}
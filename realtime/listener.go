@@ -0,0 +1,159 @@
+package realtime
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go-gin-todo-api/database"
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/services"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+// listenRetryInterval LISTENの接続が切れたあと、再接続するまでの待ち時間
+const listenRetryInterval = 5 * time.Second
+
+// Listener Postgresのtodo_events・todo_presenceチャンネルをLISTENし、このインスタンスの購読者にイベントを配ります
+// どのインスタンスで変更されたイベントも届くため、APIを複数台で動かしても同じように配信されます
+type Listener struct {
+	hub      *Hub
+	presence *Presence
+	// cursor 読み込み済みのイベントの位置（通知や再接続のたびに、ここから後のイベントを読み込む）
+	cursor utils.EventCursor
+	// seen cursor.XMin以降のトランザクションで記録され、読み込み済みのイベント（IDと記録したトランザクション）
+	// 後からコミットされたイベントを拾うため、この範囲は毎回読み直すので、同じイベントを二度配らないよう控える
+	seen map[uint]uint64
+}
+
+// todoEventRef 読み込むイベントを選ぶための、イベントのIDと通知先・記録したトランザクション
+type todoEventRef struct {
+	ID        uint
+	UserID    uint
+	ChangeXID uint64
+}
+
+// StartListener DefaultHubにイベントを、DefaultPresenceに他のインスタンスの接続の出入りを配るバックグラウンドの接続を開始します
+func StartListener() {
+	l := &Listener{hub: DefaultHub, presence: DefaultPresence, seen: make(map[uint]uint64)}
+	l.presence.Start()
+	// 起動前のイベントは再送の対象なので、起動時点の位置から始める
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		xmin, err := services.SnapshotXMin(tx)
+		if err != nil {
+			return err
+		}
+		l.cursor.XMin = xmin
+		return tx.Model(&models.TodoEvent{}).Select("COALESCE(MAX(id), 0)").Scan(&l.cursor.ID).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}); err != nil {
+		log.Printf("Failed to load latest todo event: %v", err)
+	}
+
+	go func() {
+		for {
+			if err := l.listen(context.Background()); err != nil {
+				log.Printf("Todo event listener disconnected: %v", err)
+			}
+			time.Sleep(listenRetryInterval)
+		}
+	}()
+
+	log.Printf("Todo event listener started (channel: %s)", services.TodoEventChannel)
+}
+
+// listen LISTEN用の専用接続を開き、切断されるまで通知を処理します
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, database.DSN())
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	for _, channel := range []string{services.TodoEventChannel, PresenceChannel} {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	// 接続が切れている間に記録されたイベントを配る
+	if err := l.poll(); err != nil {
+		log.Printf("Failed to catch up todo events: %v", err)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if notification.Channel == PresenceChannel {
+			l.presence.handleNotification(notification.Payload)
+			continue
+		}
+		if err := l.poll(); err != nil {
+			log.Printf("Failed to dispatch todo events: %v", err)
+		}
+	}
+}
+
+// poll 前回読み込んだ位置より後に記録されたイベントのうち、このインスタンスで購読中のユーザーの分を読み込んで配ります
+// イベントIDは採番した順にコミットされるとは限らないため、通知の本文のIDは使わず、差分同期と同じく
+// 前回の読み込み時に実行中だったトランザクション（cursor.XMin以降）のイベントはIDが小さくても読み直します
+func (l *Listener) poll() error {
+	prev := l.cursor
+	next := prev
+	var refs []todoEventRef
+	var events []models.TodoEvent
+	// 位置と読み込むイベントが食い違わないよう、同じスナップショットで読む
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		xmin, err := services.SnapshotXMin(tx)
+		if err != nil {
+			return err
+		}
+		next.XMin = xmin
+		if err := tx.Model(&models.TodoEvent{}).Scopes(services.TodoEventsAfter(prev)).
+			Select("id, user_id, change_xid::text::bigint AS change_xid").
+			Order("id ASC").Scan(&refs).Error; err != nil {
+			return err
+		}
+
+		var ids []uint
+		for _, ref := range refs {
+			next.ID = max(next.ID, ref.ID)
+			if _, ok := l.seen[ref.ID]; ok {
+				continue
+			}
+			if l.hub.HasSubscribers(ref.UserID) {
+				ids = append(ids, ref.ID)
+			}
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Where("id IN ?", ids).Order("id ASC").Find(&events).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+
+	l.cursor = next
+	for id, xid := range l.seen {
+		if xid < next.XMin {
+			delete(l.seen, id)
+		}
+	}
+	for _, ref := range refs {
+		if ref.ChangeXID >= next.XMin {
+			l.seen[ref.ID] = ref.ChangeXID
+		}
+	}
+
+	// 前回の読み込みより前にコミットされたイベントはすべて配り終えているので、
+	// 受け取ったクライアントは前回のXMinを起点に再接続すれば続きを受け取れる
+	for _, event := range events {
+		event.Cursor = utils.EventCursor{ID: max(event.ID, prev.ID), XMin: prev.XMin}.String()
+		l.hub.Publish(event)
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"go-gin-todo-api/database"
+)
+
+const (
+	// PresenceChannel プロジェクトを見ているユーザーの出入りを知らせるPostgresのNOTIFYチャンネル
+	PresenceChannel = "todo_presence"
+	// presenceRefreshInterval このインスタンスの接続を他のインスタンスに知らせ直す間隔
+	presenceRefreshInterval = 30 * time.Second
+	// presenceTTL 知らせ直しがないまま、この期間を過ぎた他のインスタンスの接続は停止したものとみなす
+	presenceTTL = 3 * presenceRefreshInterval
+	// presenceNotifyBatch 1回のNOTIFYで送る接続の数（Postgresの上限8000バイトに収める）
+	presenceNotifyBatch = 20
+)
+
+// 接続の出入りの種類
+const (
+	presenceJoin    = "join"
+	presenceLeave   = "leave"
+	presenceRefresh = "refresh"
+)
+
+// Member プロジェクトを見ているユーザー
+type Member struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// presenceEntry 1つの接続が1つのプロジェクトを見ていること
+type presenceEntry struct {
+	ConnID    string `json:"conn_id"`
+	ProjectID uint   `json:"project_id"`
+	Member
+}
+
+// presenceMessage NOTIFYで送る接続の出入り
+type presenceMessage struct {
+	Action   string          `json:"action"`
+	Instance string          `json:"instance"`
+	Entries  []presenceEntry `json:"entries"`
+}
+
+// presenceState 各インスタンスから知らされた接続
+type presenceState struct {
+	entry    presenceEntry
+	instance string
+	seenAt   time.Time
+}
+
+// Presence どのユーザーがどのプロジェクトを見ているかを、すべてのAPIインスタンスの接続を合わせて管理します
+// 自分の接続の出入りはNOTIFYで他のインスタンスに知らせ、定期的に知らせ直して停止したインスタンスの接続を取り除きます
+type Presence struct {
+	mu       sync.Mutex
+	instance string
+	// states プロジェクトごとの"インスタンス/接続ID"をキーにした接続
+	states map[uint]map[string]presenceState
+	// local このインスタンスの接続
+	local    map[string]presenceEntry
+	watchers map[uint]map[*presenceWatcher]struct{}
+}
+
+type presenceWatcher struct {
+	fn func([]Member)
+}
+
+// DefaultPresence WebSocketの接続が共有するプレゼンス
+var DefaultPresence = NewPresence()
+
+// NewPresence 空のプレゼンスを作成します
+func NewPresence() *Presence {
+	bytes := make([]byte, 8)
+	rand.Read(bytes)
+	return &Presence{
+		instance: hex.EncodeToString(bytes),
+		states:   make(map[uint]map[string]presenceState),
+		local:    make(map[string]presenceEntry),
+		watchers: make(map[uint]map[*presenceWatcher]struct{}),
+	}
+}
+
+// Start 接続を定期的に知らせ直し、停止したインスタンスの接続を取り除くバックグラウンドジョブを開始します
+func (p *Presence) Start() {
+	go func() {
+		ticker := time.NewTicker(presenceRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			p.mu.Lock()
+			entries := make([]presenceEntry, 0, len(p.local))
+			for _, entry := range p.local {
+				entries = append(entries, entry)
+			}
+			p.mu.Unlock()
+			p.notify(presenceRefresh, entries)
+			p.expire(time.Now().Add(-presenceTTL))
+		}
+	}()
+}
+
+// Join 接続がプロジェクトを見始めたことを記録し、他のインスタンスに知らせます
+func (p *Presence) Join(connID string, projectID uint, member Member) {
+	entry := presenceEntry{ConnID: connID, ProjectID: projectID, Member: member}
+	p.mu.Lock()
+	p.local[localKey(connID, projectID)] = entry
+	p.mu.Unlock()
+
+	p.apply(presenceMessage{Action: presenceJoin, Instance: p.instance, Entries: []presenceEntry{entry}})
+	p.notify(presenceJoin, []presenceEntry{entry})
+}
+
+// Leave 接続がプロジェクトを見終えたことを記録し、他のインスタンスに知らせます
+func (p *Presence) Leave(connID string, projectID uint) {
+	p.mu.Lock()
+	entry, ok := p.local[localKey(connID, projectID)]
+	delete(p.local, localKey(connID, projectID))
+	p.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	p.apply(presenceMessage{Action: presenceLeave, Instance: p.instance, Entries: []presenceEntry{entry}})
+	p.notify(presenceLeave, []presenceEntry{entry})
+}
+
+// Members プロジェクトを見ているユーザーを返します（同じユーザーの複数の接続は1人として数える）
+func (p *Presence) Members(projectID uint) []Member {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.membersLocked(projectID)
+}
+
+func (p *Presence) membersLocked(projectID uint) []Member {
+	byUser := make(map[uint]Member)
+	for _, state := range p.states[projectID] {
+		byUser[state.entry.UserID] = state.entry.Member
+	}
+	members := make([]Member, 0, len(byUser))
+	for _, member := range byUser {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].UserID < members[j].UserID })
+	return members
+}
+
+// Watch プロジェクトを見ているユーザーが変わるたびにfnを呼び出します（戻り値で解除）
+// fnはロックの外で呼び出しますが、ブロックしないようにしてください
+func (p *Presence) Watch(projectID uint, fn func([]Member)) func() {
+	watcher := &presenceWatcher{fn: fn}
+	p.mu.Lock()
+	if p.watchers[projectID] == nil {
+		p.watchers[projectID] = make(map[*presenceWatcher]struct{})
+	}
+	p.watchers[projectID][watcher] = struct{}{}
+	p.mu.Unlock()
+
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.watchers[projectID], watcher)
+		if len(p.watchers[projectID]) == 0 {
+			delete(p.watchers, projectID)
+		}
+	}
+}
+
+// handleNotification 他のインスタンスからのNOTIFYを反映します
+// 自分の接続は出入りしたときに反映済みなので、自分のNOTIFYは無視します（遅れて届いた知らせ直しで出た接続が戻らないように）
+func (p *Presence) handleNotification(payload string) {
+	var msg presenceMessage
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		log.Printf("Invalid presence notification: %v", err)
+		return
+	}
+	if msg.Instance == p.instance {
+		return
+	}
+	p.apply(msg)
+}
+
+// apply 接続の出入りを反映し、見ているユーザーが変わったプロジェクトのwatcherに知らせます
+func (p *Presence) apply(msg presenceMessage) {
+	now := time.Now()
+	changed := make(map[uint]bool)
+
+	p.mu.Lock()
+	for _, entry := range msg.Entries {
+		key := msg.Instance + "/" + entry.ConnID
+		states := p.states[entry.ProjectID]
+		switch msg.Action {
+		case presenceJoin, presenceRefresh:
+			if states == nil {
+				states = make(map[string]presenceState)
+				p.states[entry.ProjectID] = states
+			}
+			if _, ok := states[key]; !ok {
+				changed[entry.ProjectID] = true
+			}
+			states[key] = presenceState{entry: entry, instance: msg.Instance, seenAt: now}
+		case presenceLeave:
+			if _, ok := states[key]; ok {
+				delete(states, key)
+				changed[entry.ProjectID] = true
+			}
+			if len(states) == 0 {
+				delete(p.states, entry.ProjectID)
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	for projectID := range changed {
+		p.broadcast(projectID)
+	}
+}
+
+// expire 知らせ直しが途絶えた他のインスタンスの接続を取り除きます
+func (p *Presence) expire(cutoff time.Time) {
+	changed := make(map[uint]bool)
+
+	p.mu.Lock()
+	for projectID, states := range p.states {
+		for key, state := range states {
+			if state.instance != p.instance && state.seenAt.Before(cutoff) {
+				delete(states, key)
+				changed[projectID] = true
+			}
+		}
+		if len(states) == 0 {
+			delete(p.states, projectID)
+		}
+	}
+	p.mu.Unlock()
+
+	for projectID := range changed {
+		p.broadcast(projectID)
+	}
+}
+
+// broadcast プロジェクトのwatcherに見ているユーザーを知らせます
+func (p *Presence) broadcast(projectID uint) {
+	p.mu.Lock()
+	members := p.membersLocked(projectID)
+	watchers := make([]*presenceWatcher, 0, len(p.watchers[projectID]))
+	for watcher := range p.watchers[projectID] {
+		watchers = append(watchers, watcher)
+	}
+	p.mu.Unlock()
+
+	for _, watcher := range watchers {
+		watcher.fn(members)
+	}
+}
+
+// notify 接続の出入りをNOTIFYで他のインスタンスに知らせます
+func (p *Presence) notify(action string, entries []presenceEntry) {
+	for start := 0; start < len(entries); start += presenceNotifyBatch {
+		batch := entries[start:min(start+presenceNotifyBatch, len(entries))]
+		payload, err := json.Marshal(presenceMessage{Action: action, Instance: p.instance, Entries: batch})
+		if err != nil {
+			log.Printf("Failed to encode presence: %v", err)
+			return
+		}
+		if err := database.DB.Exec("SELECT pg_notify(?, ?)", PresenceChannel, string(payload)).Error; err != nil {
+			log.Printf("Failed to notify presence: %v", err)
+		}
+	}
+}
+
+// localKey このインスタンスの接続とプロジェクトの組のキー
+func localKey(connID string, projectID uint) string {
+	return connID + "/" + strconv.FormatUint(uint64(projectID), 10)
+}
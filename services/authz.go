@@ -0,0 +1,165 @@
+package services
+
+import (
+	"go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+// Role 共有されたリソースに対する権限（大きいほど強い）
+type Role int
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleEditor
+	RoleOwner
+)
+
+var roleNames = map[Role]string{
+	RoleNone:   "none",
+	RoleViewer: "viewer",
+	RoleEditor: "editor",
+	RoleOwner:  "owner",
+}
+
+// ParseRole 権限名（viewer / editor / owner）をRoleに変換します
+func ParseRole(name string) (Role, bool) {
+	for role, n := range roleNames {
+		if role != RoleNone && n == name {
+			return role, true
+		}
+	}
+	return RoleNone, false
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// Allows requiredの操作を行える権限か判定します
+func (r Role) Allows(required Role) bool {
+	return r >= required
+}
+
+// acceptedShares ユーザーが承諾済みの共有を取得するクエリ
+func acceptedShares(tx *gorm.DB, userID uint, resourceType string) *gorm.DB {
+	return tx.Model(&models.Share{}).
+		Where("user_id = ? AND resource_type = ? AND status = ?", userID, resourceType, models.ShareStatusAccepted)
+}
+
+// maxShareRole 共有の中で最も強い権限を返します
+func maxShareRole(query *gorm.DB) (Role, error) {
+	var names []string
+	if err := query.Pluck("role", &names).Error; err != nil {
+		return RoleNone, err
+	}
+	best := RoleNone
+	for _, name := range names {
+		if role, ok := ParseRole(name); ok && role > best {
+			best = role
+		}
+	}
+	return best, nil
+}
+
+// ProjectRole ユーザーのプロジェクトに対する権限を返します（持ち主はowner）
+func ProjectRole(tx *gorm.DB, userID uint, project *models.Project) (Role, error) {
+	if project.UserID == userID {
+		return RoleOwner, nil
+	}
+	return maxShareRole(acceptedShares(tx, userID, models.ShareResourceProject).
+		Where("resource_id = ?", project.ID))
+}
+
+// TodoRole ユーザーのtodoに対する権限を返します
+// 持ち主はowner。それ以外はtodo自身・祖先のtodo・所属プロジェクトの共有のうち最も強い権限になります
+func TodoRole(tx *gorm.DB, userID uint, todo *models.Todo) (Role, error) {
+	if todo.UserID == userID {
+		return RoleOwner, nil
+	}
+
+	// todoを共有するとサブタスクも共有される
+	var ancestors []uint
+	if err := tx.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM todos WHERE id = ?
+			UNION
+			SELECT t.id, t.parent_id FROM todos t JOIN ancestors a ON t.id = a.parent_id
+		)
+		SELECT id FROM ancestors`, todo.ID).Scan(&ancestors).Error; err != nil {
+		return RoleNone, err
+	}
+
+	best, err := maxShareRole(acceptedShares(tx, userID, models.ShareResourceTodo).
+		Where("resource_id IN ?", ancestors))
+	if err != nil {
+		return RoleNone, err
+	}
+
+	if todo.ProjectID != nil {
+		role, err := maxShareRole(acceptedShares(tx, userID, models.ShareResourceProject).
+			Where("resource_id = ?", *todo.ProjectID))
+		if err != nil {
+			return RoleNone, err
+		}
+		if role > best {
+			best = role
+		}
+	}
+	return best, nil
+}
+
+// TodoViewerIDs todoを閲覧できるユーザー（持ち主と、todo自身・祖先のtodo・所属プロジェクトの共有を承諾したユーザー）を返します
+func TodoViewerIDs(tx *gorm.DB, todo *models.Todo) ([]uint, error) {
+	var shared []uint
+	if err := tx.Raw(`WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM todos WHERE id = ?
+			UNION
+			SELECT t.id, t.parent_id FROM todos t JOIN ancestors a ON t.id = a.parent_id
+		)
+		SELECT DISTINCT user_id FROM shares
+		WHERE status = ? AND (
+			(resource_type = ? AND resource_id IN (SELECT id FROM ancestors))
+			OR (resource_type = ? AND resource_id = ?)
+		)`,
+		todo.ID, models.ShareStatusAccepted,
+		models.ShareResourceTodo,
+		models.ShareResourceProject, todo.ProjectID).Scan(&shared).Error; err != nil {
+		return nil, err
+	}
+
+	ids := []uint{todo.UserID}
+	for _, id := range shared {
+		if id != todo.UserID {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// VisibleTodos 自分のtodoと、共有されたtodo（サブタスクを含む）・プロジェクトのtodoに絞り込むスコープ
+func VisibleTodos(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`(todos.user_id = ?
+			OR todos.project_id IN (SELECT resource_id FROM shares WHERE user_id = ? AND resource_type = ? AND status = ?)
+			OR todos.id IN (
+				WITH RECURSIVE shared AS (
+					SELECT resource_id AS id FROM shares WHERE user_id = ? AND resource_type = ? AND status = ?
+					UNION
+					SELECT t.id FROM todos t JOIN shared s ON t.parent_id = s.id
+				)
+				SELECT id FROM shared
+			))`,
+			userID,
+			userID, models.ShareResourceProject, models.ShareStatusAccepted,
+			userID, models.ShareResourceTodo, models.ShareStatusAccepted)
+	}
+}
+
+// VisibleProjects 自分のプロジェクトと共有されたプロジェクトに絞り込むスコープ
+func VisibleProjects(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(`(projects.user_id = ?
+			OR projects.id IN (SELECT resource_id FROM shares WHERE user_id = ? AND resource_type = ? AND status = ?))`,
+			userID, userID, models.ShareResourceProject, models.ShareStatusAccepted)
+	}
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"go-gin-todo-api/database/dbtest"
+	"go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+// authzFixture 持ち主のプロジェクト・todoと、共有の仕方の異なるユーザー
+type authzFixture struct {
+	owner, projectEditor, rootViewer, mixed, pending, declined, stranger models.User
+
+	project models.Project
+	// root（プロジェクト内）→ child → grandchild、loose（プロジェクトなし）→ looseChild、other（共有なし）
+	root, child, grandchild, loose, looseChild, other models.Todo
+}
+
+func newAuthzFixture(t *testing.T, db *gorm.DB) *authzFixture {
+	t.Helper()
+	f := &authzFixture{}
+	for _, user := range []*models.User{&f.owner, &f.projectEditor, &f.rootViewer, &f.mixed, &f.pending, &f.declined, &f.stranger} {
+		*user = dbtest.CreateUser(t, db)
+	}
+
+	f.project = models.Project{UserID: f.owner.ID, Name: "共有プロジェクト"}
+	dbtest.Create(t, db, &f.project)
+
+	f.root = models.Todo{UserID: f.owner.ID, Title: "root", ProjectID: &f.project.ID}
+	dbtest.Create(t, db, &f.root)
+	f.child = models.Todo{UserID: f.owner.ID, Title: "child", ProjectID: &f.project.ID, ParentID: &f.root.ID}
+	dbtest.Create(t, db, &f.child)
+	f.grandchild = models.Todo{UserID: f.owner.ID, Title: "grandchild", ProjectID: &f.project.ID, ParentID: &f.child.ID}
+	dbtest.Create(t, db, &f.grandchild)
+	f.loose = models.Todo{UserID: f.owner.ID, Title: "loose"}
+	dbtest.Create(t, db, &f.loose)
+	f.looseChild = models.Todo{UserID: f.owner.ID, Title: "loose child", ParentID: &f.loose.ID}
+	dbtest.Create(t, db, &f.looseChild)
+	f.other = models.Todo{UserID: f.owner.ID, Title: "other"}
+	dbtest.Create(t, db, &f.other)
+
+	share := func(resourceType string, resourceID uint, user models.User, role, status string) {
+		dbtest.Create(t, db, &models.Share{
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			UserID:       user.ID,
+			InvitedByID:  f.owner.ID,
+			Role:         role,
+			Status:       status,
+		})
+	}
+	share(models.ShareResourceProject, f.project.ID, f.projectEditor, "editor", models.ShareStatusAccepted)
+	share(models.ShareResourceTodo, f.root.ID, f.rootViewer, "viewer", models.ShareStatusAccepted)
+	share(models.ShareResourceTodo, f.loose.ID, f.rootViewer, "editor", models.ShareStatusAccepted)
+	// プロジェクトはviewer、途中のtodoはeditorで共有されたユーザー
+	share(models.ShareResourceProject, f.project.ID, f.mixed, "viewer", models.ShareStatusAccepted)
+	share(models.ShareResourceTodo, f.child.ID, f.mixed, "editor", models.ShareStatusAccepted)
+	// 承諾していない・断った招待は何の権限も与えない
+	share(models.ShareResourceProject, f.project.ID, f.pending, "editor", models.ShareStatusPending)
+	share(models.ShareResourceTodo, f.root.ID, f.pending, "owner", models.ShareStatusPending)
+	share(models.ShareResourceProject, f.project.ID, f.declined, "editor", models.ShareStatusDeclined)
+	share(models.ShareResourceTodo, f.other.ID, f.declined, "viewer", models.ShareStatusDeclined)
+	return f
+}
+
+func TestTodoRole(t *testing.T) {
+	db := dbtest.Open(t)
+	f := newAuthzFixture(t, db)
+
+	tests := []struct {
+		name string
+		user models.User
+		todo models.Todo
+		want Role
+	}{
+		{"owner", f.owner, f.grandchild, RoleOwner},
+		{"owner without shares", f.owner, f.other, RoleOwner},
+		{"project share", f.projectEditor, f.root, RoleEditor},
+		{"project share on subtask", f.projectEditor, f.grandchild, RoleEditor},
+		{"project share on todo outside project", f.projectEditor, f.loose, RoleNone},
+		{"todo share", f.rootViewer, f.root, RoleViewer},
+		{"todo share inherited by child", f.rootViewer, f.child, RoleViewer},
+		{"todo share inherited by grandchild", f.rootViewer, f.grandchild, RoleViewer},
+		{"todo share inherited outside project", f.rootViewer, f.looseChild, RoleEditor},
+		{"todo share does not reach unrelated todo", f.rootViewer, f.other, RoleNone},
+		{"strongest of project and ancestor shares", f.mixed, f.grandchild, RoleEditor},
+		{"ancestor share does not reach parent", f.mixed, f.root, RoleViewer},
+		{"pending project and todo invitations", f.pending, f.grandchild, RoleNone},
+		{"pending todo invitation", f.pending, f.root, RoleNone},
+		{"declined project invitation", f.declined, f.root, RoleNone},
+		{"declined todo invitation", f.declined, f.other, RoleNone},
+		{"stranger", f.stranger, f.root, RoleNone},
+	}
+	for _, tt := range tests {
+		got, err := TodoRole(db, tt.user.ID, &tt.todo)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: TodoRole = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProjectRole(t *testing.T) {
+	db := dbtest.Open(t)
+	f := newAuthzFixture(t, db)
+
+	tests := []struct {
+		name string
+		user models.User
+		want Role
+	}{
+		{"owner", f.owner, RoleOwner},
+		{"editor", f.projectEditor, RoleEditor},
+		{"viewer", f.mixed, RoleViewer},
+		// todoの共有はプロジェクトの権限にならない
+		{"todo share only", f.rootViewer, RoleNone},
+		{"pending", f.pending, RoleNone},
+		{"declined", f.declined, RoleNone},
+		{"stranger", f.stranger, RoleNone},
+	}
+	for _, tt := range tests {
+		got, err := ProjectRole(db, tt.user.ID, &f.project)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: ProjectRole = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTodoViewerIDs(t *testing.T) {
+	db := dbtest.Open(t)
+	f := newAuthzFixture(t, db)
+
+	tests := []struct {
+		name string
+		todo models.Todo
+		want []uint
+	}{
+		{"root", f.root, []uint{f.owner.ID, f.projectEditor.ID, f.rootViewer.ID, f.mixed.ID}},
+		{"grandchild", f.grandchild, []uint{f.owner.ID, f.projectEditor.ID, f.rootViewer.ID, f.mixed.ID}},
+		{"loose child", f.looseChild, []uint{f.owner.ID, f.rootViewer.ID}},
+		{"other", f.other, []uint{f.owner.ID}},
+	}
+	for _, tt := range tests {
+		got, err := TodoViewerIDs(db, &tt.todo)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		// 持ち主が先頭で、重複しない
+		if len(got) == 0 || got[0] != f.owner.ID {
+			t.Errorf("%s: TodoViewerIDs = %v, want the owner first", tt.name, got)
+		}
+		if !reflect.DeepEqual(sortedIDs(got), sortedIDs(tt.want)) {
+			t.Errorf("%s: TodoViewerIDs = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestVisibleTodos(t *testing.T) {
+	db := dbtest.Open(t)
+	f := newAuthzFixture(t, db)
+
+	tests := []struct {
+		name string
+		user models.User
+		want []uint
+	}{
+		{"owner", f.owner, []uint{f.root.ID, f.child.ID, f.grandchild.ID, f.loose.ID, f.looseChild.ID, f.other.ID}},
+		{"project share", f.projectEditor, []uint{f.root.ID, f.child.ID, f.grandchild.ID}},
+		{"todo shares with subtasks", f.rootViewer, []uint{f.root.ID, f.child.ID, f.grandchild.ID, f.loose.ID, f.looseChild.ID}},
+		{"project and todo share", f.mixed, []uint{f.root.ID, f.child.ID, f.grandchild.ID}},
+		{"pending", f.pending, nil},
+		{"declined", f.declined, nil},
+		{"stranger", f.stranger, nil},
+	}
+	for _, tt := range tests {
+		var got []uint
+		if err := db.Model(&models.Todo{}).Scopes(VisibleTodos(tt.user.ID)).Pluck("todos.id", &got).Error; err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(sortedIDs(got), sortedIDs(tt.want)) {
+			t.Errorf("%s: visible todos = %v, want %v", tt.name, sortedIDs(got), sortedIDs(tt.want))
+		}
+	}
+}
+
+func sortedIDs(ids []uint) []uint {
+	sorted := append([]uint{}, ids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
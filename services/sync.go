@@ -0,0 +1,74 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"go-gin-todo-api/models"
+	"gorm.io/gorm"
+)
+
+// RecordTombstones 完全に削除するtodoの墓標を、閲覧できるユーザーごとに記録します
+// 共有や親子関係から閲覧できるユーザーを求めるため、todoや共有を削除する前に呼び出してください
+func RecordTombstones(tx *gorm.DB, todos []models.Todo) error {
+	var tombstones []models.TodoTombstone
+	for _, todo := range todos {
+		userIDs, err := TodoViewerIDs(tx, &todo)
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			tombstones = append(tombstones, models.TodoTombstone{UserID: userID, TodoID: todo.ID, DeletedAt: todo.DeletedAt.Time})
+		}
+	}
+	if len(tombstones) == 0 {
+		return nil
+	}
+	return tx.Create(&tombstones).Error
+}
+
+// PurgeTombstones 保持期間を過ぎた墓標を削除し、削除件数を返します
+func PurgeTombstones(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.TodoTombstone{})
+	return result.RowsAffected, result.Error
+}
+
+// ResetSync 共有の変更で閲覧できるtodoが入れ替わったユーザーに、差分同期のやり直しを求めます
+// 変更番号の古いtodoが見えるようになったり、見えなくなったtodoを削除として伝えられなかったりするため、発行済みの変更トークンを使えなくします
+func ResetSync(tx *gorm.DB, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return tx.Model(&models.User{}).Where("id IN ?", userIDs).Update("sync_reset_at", time.Now()).Error
+}
+
+// ChangedViewerIDs 変更前後で閲覧できるユーザーの一覧を比べ、どちらか一方にだけ含まれるユーザーを返します
+func ChangedViewerIDs(before, after []uint) []uint {
+	counts := make(map[uint]int)
+	for _, id := range before {
+		counts[id]++
+	}
+	for _, id := range after {
+		counts[id]--
+	}
+	var changed []uint
+	for _, id := range append(before, after...) {
+		if counts[id] != 0 {
+			changed = append(changed, id)
+			counts[id] = 0
+		}
+	}
+	return changed
+}
+
+// TombstoneRetention 墓標の保持期間を取得します（これより前に発行した変更トークンは使えない）
+func TombstoneRetention() time.Duration {
+	days := 90 // デフォルト値
+	if daysStr := os.Getenv("SYNC_TOMBSTONE_RETENTION_DAYS"); daysStr != "" {
+		if parsed, err := strconv.Atoi(daysStr); err == nil && parsed > 0 {
+			days = parsed
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
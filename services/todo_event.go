@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// TodoEventChannel todoの変更イベントを通知するPostgresのNOTIFYチャンネル
+	TodoEventChannel = "todo_events"
+	// todoEventNotifyLimit 1回のNOTIFYの最大バイト数（Postgresの上限8000バイトより小さくする）
+	todoEventNotifyLimit = 7000
+)
+
+// EmitTodoEvent todoの変更を持ち主のwebhookのキューと、閲覧できるユーザーのリアルタイム配信に記録します
+func EmitTodoEvent(tx *gorm.DB, event string, todo *models.Todo) error {
+	if err := EnqueueWebhookEvent(tx, todo.UserID, event, todo); err != nil {
+		return err
+	}
+	return RecordTodoEvent(tx, event, todo)
+}
+
+// RecordTodoEvent todoの変更イベントを閲覧できるユーザーごとに記録し、コミット時に他のAPIインスタンスへNOTIFYします
+// NOTIFYの本文は"ユーザーID:イベントID"のカンマ区切りです。受け取ったインスタンスは本文のIDではなく、
+// 前回読み込んだ位置（utils.EventCursor）から後のイベントを読み込むため、後からコミットされたイベントも取りこぼしません
+func RecordTodoEvent(tx *gorm.DB, event string, todo *models.Todo) error {
+	userIDs, err := TodoViewerIDs(tx, todo)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return err
+	}
+	return recordTodoEvents(tx, event, todo.ID, todo.ProjectID, string(data), userIDs)
+}
+
+// todoRemovedData todo.removedのデータ（閲覧できなくなったユーザーにも届くため、移動後のtodoの内容は含めない）
+type todoRemovedData struct {
+	ID        uint `json:"id"`
+	ProjectID uint `json:"project_id"`
+}
+
+// RecordTodoRemoved 別のプロジェクトに移動した・プロジェクトから外れたtodoを、元のプロジェクト（projectID）を通して
+// 閲覧できなくなったユーザーにtodo.removedとして記録します（webhookには送らない）
+// 引き続き閲覧できるユーザーにはtodo.updatedが届くため、記録しません
+func RecordTodoRemoved(tx *gorm.DB, todo *models.Todo, projectID uint) error {
+	previous := *todo
+	previous.ProjectID = &projectID
+	before, err := TodoViewerIDs(tx, &previous)
+	if err != nil {
+		return err
+	}
+	after, err := TodoViewerIDs(tx, todo)
+	if err != nil {
+		return err
+	}
+	viewing := make(map[uint]bool, len(after))
+	for _, id := range after {
+		viewing[id] = true
+	}
+	var userIDs []uint
+	for _, id := range before {
+		if !viewing[id] {
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(todoRemovedData{ID: todo.ID, ProjectID: projectID})
+	if err != nil {
+		return err
+	}
+	return recordTodoEvents(tx, models.TodoEventRemoved, todo.ID, &projectID, string(data), userIDs)
+}
+
+// recordTodoEvents イベントをユーザーごとに記録し、NOTIFYします（projectIDは配るプロジェクト）
+func recordTodoEvents(tx *gorm.DB, event string, todoID uint, projectID *uint, data string, userIDs []uint) error {
+	events := make([]models.TodoEvent, len(userIDs))
+	for i, userID := range userIDs {
+		events[i] = models.TodoEvent{UserID: userID, Event: event, TodoID: todoID, ProjectID: projectID, Data: data}
+	}
+	if err := tx.Create(&events).Error; err != nil {
+		return err
+	}
+
+	// NOTIFYはトランザクションがコミットされたときにだけ届く
+	var payload strings.Builder
+	for _, e := range events {
+		entry := strconv.FormatUint(uint64(e.UserID), 10) + ":" + strconv.FormatUint(uint64(e.ID), 10)
+		if payload.Len() > 0 && payload.Len()+len(entry)+1 > todoEventNotifyLimit {
+			if err := tx.Exec("SELECT pg_notify(?, ?)", TodoEventChannel, payload.String()).Error; err != nil {
+				return err
+			}
+			payload.Reset()
+		}
+		if payload.Len() > 0 {
+			payload.WriteByte(',')
+		}
+		payload.WriteString(entry)
+	}
+	return tx.Exec("SELECT pg_notify(?, ?)", TodoEventChannel, payload.String()).Error
+}
+
+// SnapshotXMin トランザクションのスナップショットで実行中だった最古のトランザクションのIDを返します
+// これより前のトランザクションが記録したイベントは、すべてこのスナップショットから見えています
+func SnapshotXMin(tx *gorm.DB) (uint64, error) {
+	var xmin uint64
+	err := tx.Raw("SELECT pg_snapshot_xmin(pg_current_snapshot())::text::bigint").Scan(&xmin).Error
+	return xmin, err
+}
+
+// TodoEventsAfter 再送の起点より後のイベント（IDが大きい、または起点のXMin以降のトランザクションで記録された）に絞り込みます
+// 起点の前に受け取ったイベントが含まれることもあるので、受け取る側は同じイベントを二度受け取っても困らないようにしてください
+func TodoEventsAfter(cursor utils.EventCursor) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("todo_events.id > ? OR todo_events.change_xid >= ?::xid8",
+			cursor.ID, strconv.FormatUint(cursor.XMin, 10))
+	}
+}
+
+// PurgeTodoEvents 保持期間を過ぎたtodoの変更イベントを削除し、削除件数を返します
+func PurgeTodoEvents(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("created_at < ?", time.Now().Add(-retention)).Delete(&models.TodoEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go-gin-todo-api/models"
+	"go-gin-todo-api/storage"
+	"gorm.io/gorm"
+)
+
+// purgeBatchSize 期限切れのゴミ箱を削除する際に1トランザクションで扱う件数
+const purgeBatchSize = 500
+
+// PurgeTodos ゴミ箱のtodoを完全に削除します（ラベルの紐付け・変更履歴・コメント・添付ファイルの情報は外部キーで削除される）
+// 差分同期で削除を伝えられるよう、削除したtodoの墓標を残し、webhook・リアルタイム配信にtodo.purgedを通知します
+// 削除した添付ファイルのstorageのキーを返すので、コミット後にstorage.DeleteObjectsでファイル本体を削除してください
+func PurgeTodos(tx *gorm.DB, ids []uint) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	trashed := tx.Unscoped().Model(&models.Todo{}).Select("id").Where("id IN ? AND deleted_at IS NOT NULL", ids)
+
+	var todos []models.Todo
+	if err := tx.Unscoped().Preload("Labels").Where("id IN ? AND deleted_at IS NOT NULL", ids).Order("id ASC").Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	if err := RecordTombstones(tx, todos); err != nil {
+		return nil, err
+	}
+	// 閲覧できるユーザーは共有から求めるため、共有を削除する前に通知する
+	for i := range todos {
+		if err := EmitTodoEvent(tx, models.WebhookEventTodoPurged, &todos[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var keys []string
+	if err := tx.Model(&models.Attachment{}).Where("todo_id IN (?)", trashed).Pluck("storage_key", &keys).Error; err != nil {
+		return nil, err
+	}
+	// 共有は外部キーを持たないため明示的に削除する
+	if err := tx.Where("resource_type = ? AND resource_id IN (?)", models.ShareResourceTodo, trashed).
+		Delete(&models.Share{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Unscoped().Where("id IN ? AND deleted_at IS NOT NULL", ids).Delete(&models.Todo{}).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// PurgeExpiredTrash ゴミ箱に入れてから保持期間を過ぎたtodoを完全に削除し、削除件数を返します
+func PurgeExpiredTrash(db *gorm.DB, retention time.Duration) (int, error) {
+	cutoff := time.Now().Add(-retention)
+	purged := 0
+	for {
+		var ids []uint
+		if err := db.Unscoped().Model(&models.Todo{}).
+			Where("deleted_at < ?", cutoff).
+			Order("id").
+			Limit(purgeBatchSize).
+			Pluck("id", &ids).Error; err != nil {
+			return purged, err
+		}
+		if len(ids) == 0 {
+			return purged, nil
+		}
+
+		var keys []string
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			keys, err = PurgeTodos(tx, ids)
+			return err
+		}); err != nil {
+			return purged, err
+		}
+		storage.DeleteObjects(context.Background(), keys)
+		purged += len(ids)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-gin-todo-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// webhookRetryBase 1回目の失敗から再送までの待ち時間（失敗するたびに2倍になる）
+	webhookRetryBase = 30 * time.Second
+	// webhookRetryMax 再送までの待ち時間の上限
+	webhookRetryMax = 6 * time.Hour
+	// webhookUserAgent 配信リクエストのUser-Agent
+	webhookUserAgent = "go-gin-todo-api-webhook/1.0"
+)
+
+// WebhookPayload webhookで送るリクエスト本文
+type WebhookPayload struct {
+	ID        string      `json:"id"` // イベントID（再送・再配信しても変わらない）
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// GenerateWebhookSecret webhookの署名に使う鍵を生成します
+func GenerateWebhookSecret() (string, error) {
+	bytes := make([]byte, 32) // 256ビット
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+// newWebhookEventID イベントIDを生成します
+func newWebhookEventID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "evt_" + hex.EncodeToString(bytes), nil
+}
+
+// SignWebhookPayload "タイムスタンプ.本文"のHMAC-SHA256を16進数で返します
+// 受信側は同じ計算をしてX-Webhook-Signatureと比較し、タイムスタンプが古すぎるリクエストを拒否してください
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EnqueueWebhookEvent ユーザーの有効なwebhookのうち、イベントを購読しているものへの配信をキューに追加します
+// 変更と同じトランザクションで呼び出すため、ロールバックされた変更は通知されず、コミットされた変更は必ず通知されます
+func EnqueueWebhookEvent(tx *gorm.DB, userID uint, event string, data interface{}) error {
+	var webhooks []models.Webhook
+	if err := tx.Where("user_id = ? AND active = ?", userID, true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+	var subscribed []models.Webhook
+	for _, webhook := range webhooks {
+		if webhook.Events.Has(event) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	eventID, err := newWebhookEventID()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(WebhookPayload{ID: eventID, Event: event, CreatedAt: time.Now().UTC(), Data: data})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	deliveries := make([]models.WebhookDelivery, len(subscribed))
+	for i, webhook := range subscribed {
+		deliveries[i] = models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       eventID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+		}
+	}
+	return tx.Create(&deliveries).Error
+}
+
+// webhookRetryDelay attempts回目の送信に失敗したあと、次に送るまでの待ち時間を返します
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// claimWebhookDelivery 送信時刻になった配信を1件占有し、送信回数を増やします（なければnil）
+func claimWebhookDelivery(db *gorm.DB, lease time.Duration) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 複数のプロセスで動かしても同じ配信を取り合わないよう、ロック中の行は飛ばす
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)",
+				models.WebhookDeliveryPending, now, now).
+			Order("next_attempt_at ASC, id ASC").First(&delivery).Error
+		if err != nil {
+			return err
+		}
+
+		// DBと同じマイクロ秒単位にして、自分の占有かどうかを比較できるようにする
+		lockedUntil := now.Add(lease).Truncate(time.Microsecond)
+		if err := tx.Model(&delivery).Updates(map[string]interface{}{
+			"attempts":     gorm.Expr("attempts + 1"),
+			"locked_until": lockedUntil,
+		}).Error; err != nil {
+			return err
+		}
+		delivery.Attempts++
+		delivery.LockedUntil = &lockedUntil
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// webhookAttempt 1回の送信の結果
+type webhookAttempt struct {
+	status   *int
+	err      error
+	duration time.Duration
+}
+
+// postWebhook 署名を付けて配信を送信します
+func postWebhook(client *http.Client, webhook *models.Webhook, delivery *models.WebhookDelivery) webhookAttempt {
+	started := time.Now()
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return webhookAttempt{err: err}
+	}
+	timestamp := started.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookUserAgent)
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Event-ID", delivery.EventID)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhookPayload(webhook.Secret, timestamp, []byte(delivery.Payload)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return webhookAttempt{err: err, duration: time.Since(started)}
+	}
+	defer resp.Body.Close()
+
+	// 本文は内部のサービスの応答を読み出す手段にならないよう保存せず、接続を再利用できるよう読み捨てる
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+	attempt := webhookAttempt{status: &resp.StatusCode, duration: time.Since(started)}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		attempt.err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
+
+// DeliverNextWebhook 送信時刻になった配信を1件送信します（送信した配信がなければfalse）
+// 2xx以外の応答や接続エラーは待ち時間を倍にしながら再送し、maxAttempts回失敗するとfailedにします
+func DeliverNextWebhook(db *gorm.DB, client *http.Client, maxAttempts int) (bool, error) {
+	delivery, err := claimWebhookDelivery(db, client.Timeout+30*time.Second)
+	if err != nil || delivery == nil {
+		return false, err
+	}
+
+	var webhook models.Webhook
+	var attempt webhookAttempt
+	if err := db.First(&webhook, delivery.WebhookID).Error; err != nil {
+		return true, err
+	}
+	if webhook.Active {
+		attempt = postWebhook(client, &webhook, delivery)
+	} else {
+		// 無効にしたwebhookには送らない
+		attempt = webhookAttempt{err: errors.New("webhook is disabled")}
+		maxAttempts = delivery.Attempts
+	}
+
+	now := time.Now()
+	durationMs := int(attempt.duration / time.Millisecond)
+	updates := map[string]interface{}{
+		"locked_until":    nil,
+		"response_status": attempt.status,
+		"duration_ms":     &durationMs,
+		"error":           nil,
+	}
+	switch {
+	case attempt.err == nil:
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["delivered_at"] = now
+		updates["next_attempt_at"] = nil
+	case delivery.Attempts >= maxAttempts:
+		updates["status"] = models.WebhookDeliveryFailed
+		updates["error"] = attempt.err.Error()
+		updates["next_attempt_at"] = nil
+	default:
+		updates["error"] = attempt.err.Error()
+		updates["next_attempt_at"] = now.Add(webhookRetryDelay(delivery.Attempts))
+	}
+
+	// 占有期間が切れて他のプロセスが送り直している場合は、そちらの結果を優先する
+	if err := db.Model(delivery).Where("locked_until = ?", delivery.LockedUntil).Updates(updates).Error; err != nil {
+		return true, err
+	}
+	if attempt.err != nil {
+		return true, fmt.Errorf("webhook delivery %d to %s failed (attempt %d): %w", delivery.ID, webhook.URL, delivery.Attempts, attempt.err)
+	}
+	return true, nil
+}
+
+// RedeliverWebhook 配信を同じ本文（同じイベントID）で新しい配信としてキューに追加します
+func RedeliverWebhook(db *gorm.DB, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		WebhookID:     original.WebhookID,
+		EventID:       original.EventID,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// PurgeWebhookDeliveries 配信を終えて（succeeded / failed）から保持期間を過ぎた配信ログを削除し、削除件数を返します
+// 送信待ち・再送待ちの配信は残します
+func PurgeWebhookDeliveries(db *gorm.DB, retention time.Duration) (int64, error) {
+	result := db.Where("status IN ? AND updated_at < ?",
+		[]string{models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed}, time.Now().Add(-retention)).
+		Delete(&models.WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrWebhookAddressNotAllowed 通知先がループバック・プライベート・リンクローカルなどの内部向けのアドレス
+var ErrWebhookAddressNotAllowed = errors.New("url must not point to a loopback, private, link-local or multicast address")
+
+// webhookBlockedPrefixes IsPrivateなどで判定できない、外部から届かないアドレスの範囲
+var webhookBlockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // このネットワーク
+	netip.MustParsePrefix("100.64.0.0/10"), // キャリアグレードNAT（クラウドのメタデータに使われることがある）
+}
+
+// webhookAddressAllowed webhookの送信先にしてよいアドレスかどうかを返します
+func webhookAddressAllowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range webhookBlockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateWebhookURL 通知先のURLがhttpまたはhttpsの絶対URLで、ホストが内部向けのアドレスに解決されないか検証します
+// DNSの応答が登録後に変わる場合に備え、送信時にも接続先のアドレスを検証します（NewWebhookClient）
+func ValidateWebhookURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if !webhookAddressAllowed(addr) {
+			return ErrWebhookAddressNotAllowed
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("url host could not be resolved: %s", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !webhookAddressAllowed(addr) {
+			return ErrWebhookAddressNotAllowed
+		}
+	}
+	return nil
+}
+
+// webhookDialControl 名前解決後の接続先のアドレスを検証します（DNSリバインディング対策）
+func webhookDialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !webhookAddressAllowed(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrWebhookAddressNotAllowed, addrPort.Addr())
+	}
+	return nil
+}
+
+// NewWebhookClient webhookの送信に使うHTTPクライアントを作成します
+// 接続のたびに接続先のアドレスを検証し、リダイレクトはたどりません
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: webhookDialControl}
+	transport := &http.Transport{
+		// 環境変数のプロキシを経由すると接続先を検証できないため使わない
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   timeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		// リダイレクト先には署名付きの本文を送らない
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestWebhookAddressAllowed(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.0.0.5", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"224.0.0.1", false},
+		{"ff02::1", false},
+		{"100.100.100.200", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := webhookAddressAllowed(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("webhookAddressAllowed(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://93.184.216.34/hooks", false},
+		{"http://[2606:2800:220:1:248:1893:25c8:1946]:8080/hooks", false},
+		{"ftp://93.184.216.34/hooks", true},
+		{"/hooks", true},
+		{"http://localhost:8080/hooks", true},
+		{"http://127.0.0.1:9000/", true},
+		{"http://169.254.169.254/latest/meta-data/", true},
+		{"http://10.1.2.3/", true},
+		{"http://[::1]/", true},
+	}
+	for _, tt := range tests {
+		err := ValidateWebhookURL(context.Background(), tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateWebhookURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+// 登録時の検証を通った後でループバックに解決されるようになった場合（DNSリバインディング）も、接続時に拒否する
+func TestNewWebhookClientRejectsLoopbackAtConnect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the loopback server")
+	}))
+	defer server.Close()
+
+	client := NewWebhookClient(5 * time.Second)
+	resp, err := client.Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the connection to be rejected")
+	}
+	if !errors.Is(err, ErrWebhookAddressNotAllowed) {
+		t.Errorf("error = %v, want ErrWebhookAddressNotAllowed", err)
+	}
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// ErrNotFound 指定したキーのオブジェクトが存在しないことを示します
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage 添付ファイルの保存先
+type Storage interface {
+	// Put rからsizeバイトを読み出してkeyに保存します
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open keyの内容を読み出します。Range指定のダウンロードに対応するためSeekできます
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete keyを削除します（存在しない場合もエラーにしません）
+	Delete(ctx context.Context, key string) error
+}
+
+// Files 添付ファイルの保存先（InitStorageで初期化）
+var Files Storage
+
+// InitStorage 環境変数STORAGE_DRIVER（local / s3）に応じて保存先を初期化します
+func InitStorage() {
+	driver := os.Getenv("STORAGE_DRIVER")
+	if driver == "" {
+		driver = "local" // デフォルト値
+	}
+
+	var err error
+	switch driver {
+	case "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads" // デフォルト値
+		}
+		Files, err = NewLocalStorage(dir)
+	case "s3":
+		Files, err = NewS3Storage(S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       !strings.EqualFold(os.Getenv("S3_USE_PATH_STYLE"), "false"),
+		})
+	default:
+		log.Fatalf("Unknown STORAGE_DRIVER: %s", driver)
+	}
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	log.Printf("Attachment storage initialized (driver: %s)", driver)
+}
+
+// DeleteObjects 複数のオブジェクトを削除します
+// DBの削除を確定した後に呼ぶため、失敗してもログに残すだけにします
+func DeleteObjects(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := Files.Delete(ctx, key); err != nil {
+			log.Printf("Failed to delete attachment object %s: %v", key, err)
+		}
+	}
+}
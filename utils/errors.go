@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -15,30 +16,141 @@ const (
 	ErrorCodeForbidden      ErrorCode = "forbidden"
 	ErrorCodeNotFound       ErrorCode = "not_found"
 	ErrorCodeConflict       ErrorCode = "conflict"
+	ErrorCodePrecondition   ErrorCode = "precondition_failed"
+	ErrorCodeTooLarge       ErrorCode = "payload_too_large"
+	ErrorCodeQuotaExceeded  ErrorCode = "quota_exceeded"
+	ErrorCodeResyncRequired ErrorCode = "resync_required"
 	ErrorCodeInternal       ErrorCode = "internal"
 )
 
+// ErrorDetail エラーの内容
+type ErrorDetail struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details []ErrorItem `json:"details,omitempty"`
+}
+
+// ErrorItem 入力のどの部分が不正かを示す個別のエラー（ファイルの行番号など）
+type ErrorItem struct {
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
 // ErrorResponse エラーレスポンス構造体
 type ErrorResponse struct {
-	Error struct {
-		Code    string `json:"code"`
-		Message string `json:"message"`
-	} `json:"error"`
+	Error ErrorDetail `json:"error"`
+}
+
+// APIError ハンドラーの外の処理からHTTPエラーを返すためのエラー
+type APIError struct {
+	Status  int
+	Code    ErrorCode
+	Message string
+	Details []ErrorItem
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Detail レスポンスに含めるエラーの内容を返します
+func (e *APIError) Detail() ErrorDetail {
+	return ErrorDetail{Code: string(e.Code), Message: e.Message, Details: e.Details}
+}
+
+// NewBadRequestError 400 Bad Requestのエラーを作成
+func NewBadRequestError(message string) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: ErrorCodeInvalidRequest, Message: message}
+}
+
+// NewValidationError 個別のエラーを含む400 Bad Requestのエラーを作成
+func NewValidationError(message string, details []ErrorItem) *APIError {
+	return &APIError{Status: http.StatusBadRequest, Code: ErrorCodeInvalidRequest, Message: message, Details: details}
+}
+
+// NewUnauthorizedError 401 Unauthorizedのエラーを作成
+func NewUnauthorizedError(message string) *APIError {
+	return &APIError{Status: http.StatusUnauthorized, Code: ErrorCodeUnauthorized, Message: message}
+}
+
+// NewForbiddenError 403 Forbiddenのエラーを作成
+func NewForbiddenError(message string) *APIError {
+	return &APIError{Status: http.StatusForbidden, Code: ErrorCodeForbidden, Message: message}
+}
+
+// NewNotFoundError 404 Not Foundのエラーを作成
+func NewNotFoundError(message string) *APIError {
+	return &APIError{Status: http.StatusNotFound, Code: ErrorCodeNotFound, Message: message}
+}
+
+// NewConflictError 409 Conflictのエラーを作成
+func NewConflictError(message string) *APIError {
+	return &APIError{Status: http.StatusConflict, Code: ErrorCodeConflict, Message: message}
+}
+
+// NewPreconditionFailedError 412 Precondition Failedのエラーを作成
+func NewPreconditionFailedError(message string) *APIError {
+	return &APIError{Status: http.StatusPreconditionFailed, Code: ErrorCodePrecondition, Message: message}
+}
+
+// NewPayloadTooLargeError 413 Payload Too Largeのエラーを作成
+func NewPayloadTooLargeError(message string) *APIError {
+	return &APIError{Status: http.StatusRequestEntityTooLarge, Code: ErrorCodeTooLarge, Message: message}
+}
+
+// NewQuotaExceededError 容量の上限を超える場合の413エラーを作成
+func NewQuotaExceededError(message string) *APIError {
+	return &APIError{Status: http.StatusRequestEntityTooLarge, Code: ErrorCodeQuotaExceeded, Message: message}
+}
+
+// NewResyncRequiredError 差分同期のトークンが使えず、最初から同期し直す必要がある場合の410 Goneのエラーを作成
+func NewResyncRequiredError(message string) *APIError {
+	return &APIError{Status: http.StatusGone, Code: ErrorCodeResyncRequired, Message: message}
+}
+
+// NewInternalError 500 Internal Server Errorのエラーを作成
+func NewInternalError(message string) *APIError {
+	return &APIError{Status: http.StatusInternalServerError, Code: ErrorCodeInternal, Message: message}
+}
+
+// ToAPIError エラーをAPIErrorに変換します（APIError以外はDBエラーとして扱う）
+func ToAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	statusCode, message := HandleDBError(err)
+	code := ErrorCodeInternal
+	switch statusCode {
+	case http.StatusBadRequest:
+		code = ErrorCodeInvalidRequest
+	case http.StatusForbidden:
+		code = ErrorCodeForbidden
+	case http.StatusNotFound:
+		code = ErrorCodeNotFound
+	case http.StatusConflict:
+		code = ErrorCodeConflict
+	}
+	return &APIError{Status: statusCode, Code: code, Message: message}
 }
 
 // RespondError 統一されたエラーレスポンスを返す
 func RespondError(c *gin.Context, statusCode int, code ErrorCode, message string) {
 	c.JSON(statusCode, ErrorResponse{
-		Error: struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
-		}{
+		Error: ErrorDetail{
 			Code:    string(code),
 			Message: message,
 		},
 	})
 }
 
+// RespondAPIError エラーをAPIErrorに変換してレスポンスを返す
+func RespondAPIError(c *gin.Context, err error) {
+	apiErr := ToAPIError(err)
+	c.JSON(apiErr.Status, ErrorResponse{Error: apiErr.Detail()})
+}
+
 // RespondBadRequest 400 Bad Requestを返す
 func RespondBadRequest(c *gin.Context, message string) {
 	RespondError(c, http.StatusBadRequest, ErrorCodeInvalidRequest, message)
@@ -64,6 +176,11 @@ func RespondConflict(c *gin.Context, message string) {
 	RespondError(c, http.StatusConflict, ErrorCodeConflict, message)
 }
 
+// RespondPreconditionFailed 412 Precondition Failedを返す
+func RespondPreconditionFailed(c *gin.Context, message string) {
+	RespondError(c, http.StatusPreconditionFailed, ErrorCodePrecondition, message)
+}
+
 // RespondInternalError 500 Internal Server Errorを返す
 func RespondInternalError(c *gin.Context, message string) {
 	RespondError(c, http.StatusInternalServerError, ErrorCodeInternal, message)
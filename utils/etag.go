@@ -0,0 +1,40 @@
+package utils
+
+import "strings"
+
+// matchETag If-Match / If-None-Match ヘッダーの値（カンマ区切り、"*"可）がETagに一致するか判定します
+// weakがfalseの場合はRFC 9110の強い比較を行い、W/付きのETagは一致しないものとして扱います
+func matchETag(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// IfMatch If-Matchヘッダーの条件を満たすか判定します（ヘッダーがなければ常に満たす）
+func IfMatch(header, etag string) bool {
+	if header == "" {
+		return true
+	}
+	return matchETag(header, etag, false)
+}
+
+// IfNoneMatch If-None-Matchヘッダーに一致し、304 Not Modifiedを返せるか判定します
+func IfNoneMatch(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	return matchETag(header, etag, true)
+}
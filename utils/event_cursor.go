@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EventCursor リアルタイム配信の再送の起点（SSEのid）
+// IDまでのイベントのうち、XMin（配信時に実行中だった最古のトランザクションのID）より前のトランザクションのものは受け取り済みであることを表します
+// イベントIDは採番した順にコミットされるとは限らないため、XMin以降のトランザクションのイベントはIDが小さくても再送します
+type EventCursor struct {
+	ID   uint
+	XMin uint64
+}
+
+// String "イベントID-XMin"の形式で返します
+func (c EventCursor) String() string {
+	return strconv.FormatUint(uint64(c.ID), 10) + "-" + strconv.FormatUint(c.XMin, 10)
+}
+
+// ParseEventCursor 再送の起点をパースします
+// 以前のバージョンが送っていたイベントIDだけのidは、XMinが0（再送できない）として扱います
+func ParseEventCursor(s string) (EventCursor, error) {
+	var cursor EventCursor
+	idPart, xminPart, hasXMin := strings.Cut(s, "-")
+	id, err := strconv.ParseUint(idPart, 10, 32)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid event cursor")
+	}
+	cursor.ID = uint(id)
+	if hasXMin {
+		if cursor.XMin, err = strconv.ParseUint(xminPart, 10, 64); err != nil || cursor.XMin == 0 {
+			return cursor, fmt.Errorf("invalid event cursor")
+		}
+	}
+	return cursor, nil
+}
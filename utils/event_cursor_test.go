@@ -0,0 +1,39 @@
+package utils
+
+import "testing"
+
+func TestParseEventCursor(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    EventCursor
+		wantErr bool
+	}{
+		{"1024-5871", EventCursor{ID: 1024, XMin: 5871}, false},
+		{"0-3", EventCursor{ID: 0, XMin: 3}, false},
+		// 以前のバージョンのidは位置がわからないのでXMinを0にする
+		{"1024", EventCursor{ID: 1024}, false},
+		{"1024-0", EventCursor{}, true},
+		{"1024-", EventCursor{}, true},
+		{"-5871", EventCursor{}, true},
+		{"abc", EventCursor{}, true},
+		{"", EventCursor{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseEventCursor(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseEventCursor(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("ParseEventCursor(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEventCursorRoundTrip(t *testing.T) {
+	cursor := EventCursor{ID: 42, XMin: 1 << 40}
+	got, err := ParseEventCursor(cursor.String())
+	if err != nil || got != cursor {
+		t.Errorf("ParseEventCursor(%q) = %+v, %v, want %+v", cursor.String(), got, err, cursor)
+	}
+}
@@ -0,0 +1,319 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ICalProperty iCalendarのプロパティ（例: DUE;TZID=Asia/Tokyo:20240101T090000）
+type ICalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// ICalComponent iCalendarのコンポーネント（VTODOなど）
+// Lineはコンポーネントが始まる行番号です
+type ICalComponent struct {
+	Name       string
+	Line       int
+	Properties []ICalProperty
+}
+
+// Get 指定した名前の最初のプロパティを返します
+func (c *ICalComponent) Get(name string) (ICalProperty, bool) {
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			return prop, true
+		}
+	}
+	return ICalProperty{}, false
+}
+
+// maxICalLineLength 1行の最大長（折り返し前）
+const maxICalLineLength = 1 << 20
+
+// ParseICal iCalendarのテキストを解析し、VCALENDAR直下のコンポーネントを返します
+// VTODOの中のVALARMのような入れ子のコンポーネントは読み飛ばします
+func ParseICal(r io.Reader) ([]ICalComponent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		components []ICalComponent
+		stack      []string
+		current    *ICalComponent
+		inCalendar bool
+	)
+	for _, line := range lines {
+		if strings.TrimSpace(line.text) == "" {
+			continue
+		}
+		prop, err := parseICalProperty(line.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line.number, err)
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			name := strings.ToUpper(prop.Value)
+			switch {
+			case !inCalendar:
+				if name != "VCALENDAR" {
+					return nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR", line.number)
+				}
+				inCalendar = true
+			case len(stack) == 0:
+				current = &ICalComponent{Name: name, Line: line.number}
+				stack = append(stack, name)
+			default:
+				stack = append(stack, name)
+			}
+		case "END":
+			name := strings.ToUpper(prop.Value)
+			if len(stack) == 0 {
+				if !inCalendar || name != "VCALENDAR" {
+					return nil, fmt.Errorf("line %d: unexpected END:%s", line.number, name)
+				}
+				inCalendar = false
+				continue
+			}
+			if stack[len(stack)-1] != name {
+				return nil, fmt.Errorf("line %d: expected END:%s", line.number, stack[len(stack)-1])
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				components = append(components, *current)
+				current = nil
+			}
+		default:
+			if !inCalendar {
+				return nil, fmt.Errorf("line %d: expected BEGIN:VCALENDAR", line.number)
+			}
+			if len(stack) == 1 {
+				current.Properties = append(current.Properties, prop)
+			}
+		}
+	}
+	if inCalendar || len(stack) > 0 {
+		return nil, fmt.Errorf("unexpected end of calendar")
+	}
+	if components == nil {
+		components = []ICalComponent{}
+	}
+	return components, nil
+}
+
+type icalLine struct {
+	number int
+	text   string
+}
+
+// unfoldICalLines 折り返された行（次の行が空白またはタブで始まる）を1行に戻します
+func unfoldICalLines(r io.Reader) ([]icalLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxICalLineLength)
+
+	var lines []icalLine
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff") // BOM
+		}
+		if (strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1].text += text[1:]
+			continue
+		}
+		lines = append(lines, icalLine{number: number, text: text})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parseICalProperty 「名前;パラメータ=値:値」の形式の行を解析します
+func parseICalProperty(line string) (ICalProperty, error) {
+	prop := ICalProperty{Params: map[string]string{}}
+
+	// パラメータの値はダブルクォートで囲まれている場合があり、その中の:と;は区切りではない
+	inQuote := false
+	nameEnd, valueStart := -1, -1
+	for i, r := range line {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == ';' && nameEnd < 0:
+			nameEnd = i
+		case r == ':':
+			valueStart = i
+		}
+		if valueStart >= 0 {
+			break
+		}
+	}
+	if valueStart < 0 {
+		return prop, fmt.Errorf("missing ':' in %q", line)
+	}
+	if nameEnd < 0 {
+		nameEnd = valueStart
+	}
+
+	prop.Name = strings.ToUpper(line[:nameEnd])
+	prop.Value = line[valueStart+1:]
+	if prop.Name == "" {
+		return prop, fmt.Errorf("missing property name in %q", line)
+	}
+
+	if nameEnd < valueStart {
+		for _, param := range splitICalParams(line[nameEnd+1 : valueStart]) {
+			key, value, ok := strings.Cut(param, "=")
+			if !ok {
+				return prop, fmt.Errorf("invalid parameter %q", param)
+			}
+			prop.Params[strings.ToUpper(key)] = strings.Trim(value, "\"")
+		}
+	}
+	return prop, nil
+}
+
+// splitICalParams ;区切りのパラメータを分割します（ダブルクォート内の;は区切らない）
+func splitICalParams(s string) []string {
+	var params []string
+	inQuote := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == ';' && !inQuote:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+// EscapeICalText TEXT型の値をエスケープします
+func EscapeICalText(s string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\",
+		";", "\\;",
+		",", "\\,",
+		"\r\n", "\\n",
+		"\n", "\\n",
+	).Replace(s)
+}
+
+// UnescapeICalText TEXT型の値のエスケープを元に戻します
+func UnescapeICalText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// ICalWriter iCalendarのテキストを書き出します（CRLF改行・75オクテットでの折り返し）
+type ICalWriter struct {
+	w   io.Writer
+	err error
+}
+
+// NewICalWriter ICalWriterを作成します
+func NewICalWriter(w io.Writer) *ICalWriter {
+	return &ICalWriter{w: w}
+}
+
+// Line 「名前:値」の行を書き出します（値はエスケープ済みであること）
+func (w *ICalWriter) Line(name, value string) {
+	if w.err != nil {
+		return
+	}
+	line := name + ":" + value
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		// マルチバイト文字の途中で折り返さない
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	b.WriteString("\r\n")
+	_, w.err = io.WriteString(w.w, b.String())
+}
+
+// Text TEXT型の値をエスケープして書き出します
+func (w *ICalWriter) Text(name, value string) {
+	w.Line(name, EscapeICalText(value))
+}
+
+// Time 日時をUTCのDATE-TIME型で書き出します
+func (w *ICalWriter) Time(name string, t time.Time) {
+	w.Line(name, FormatICalTime(t))
+}
+
+// Err 書き出し中に発生したエラーを返します
+func (w *ICalWriter) Err() error {
+	return w.err
+}
+
+// FormatICalTime 日時をUTCのDATE-TIME型（20060102T150405Z）にします
+func FormatICalTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// ParseICalTime DATE-TIME型・DATE型の値を解釈します
+// TZIDパラメータがあればそのタイムゾーン、フローティング時刻とDATE型はlocで解釈します
+func ParseICalTime(prop ICalProperty, loc *time.Location) (time.Time, error) {
+	value := strings.TrimSpace(prop.Value)
+	if tzid, ok := prop.Params["TZID"]; ok {
+		tz, err := LoadICalLocation(tzid)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("unknown TZID: %s", tzid)
+		}
+		loc = tz
+	}
+
+	if strings.EqualFold(prop.Params["VALUE"], "DATE") || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date: %s", value)
+		}
+		return t, nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date-time: %s", value)
+		}
+		return t, nil
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date-time: %s", value)
+	}
+	return t, nil
+}
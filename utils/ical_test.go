@@ -0,0 +1,196 @@
+package utils
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseICal(t *testing.T) {
+	text := "\ufeffBEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VTIMEZONE\r\n" +
+		"TZID:Tokyo Standard Time\r\n" +
+		"BEGIN:STANDARD\r\n" +
+		"TZOFFSETTO:+0900\r\n" +
+		"END:STANDARD\r\n" +
+		"END:VTIMEZONE\r\n" +
+		"BEGIN:VTODO\r\n" +
+		"UID:todo-1@example.com\r\n" +
+		"SUMMARY:企画書を書く\\, 提出する\r\n" +
+		"DESCRIPTION:1行目\\n2行目は折り返\r\n" +
+		" されている\r\n" +
+		"DUE;TZID=\"Tokyo Standard Time\";VALUE=DATE-TIME:20241001T180000\r\n" +
+		"X-NOTE;LABEL=\"a:b;c\":value:with:colons\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"TRIGGER:-PT15M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VTODO\r\n" +
+		"\r\n" +
+		"END:VCALENDAR\r\n"
+
+	components, err := ParseICal(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(components) != 2 {
+		t.Fatalf("components = %d, want 2", len(components))
+	}
+	if components[0].Name != "VTIMEZONE" || components[1].Name != "VTODO" || components[1].Line != 9 {
+		t.Errorf("components = %s(line %d), %s(line %d)", components[0].Name, components[0].Line, components[1].Name, components[1].Line)
+	}
+
+	todo := components[1]
+	want := []ICalProperty{
+		{Name: "UID", Params: map[string]string{}, Value: "todo-1@example.com"},
+		{Name: "SUMMARY", Params: map[string]string{}, Value: "企画書を書く\\, 提出する"},
+		{Name: "DESCRIPTION", Params: map[string]string{}, Value: "1行目\\n2行目は折り返されている"},
+		{Name: "DUE", Params: map[string]string{"TZID": "Tokyo Standard Time", "VALUE": "DATE-TIME"}, Value: "20241001T180000"},
+		{Name: "X-NOTE", Params: map[string]string{"LABEL": "a:b;c"}, Value: "value:with:colons"},
+	}
+	if !reflect.DeepEqual(todo.Properties, want) {
+		t.Errorf("properties = %+v\nwant %+v", todo.Properties, want)
+	}
+	if summary, _ := todo.Get("SUMMARY"); UnescapeICalText(summary.Value) != "企画書を書く, 提出する" {
+		t.Errorf("summary = %q", UnescapeICalText(summary.Value))
+	}
+	if _, ok := todo.Get("TRIGGER"); ok {
+		t.Error("properties of nested VALARM should be skipped")
+	}
+}
+
+func TestParseICalErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "no calendar", text: "BEGIN:VTODO\nEND:VTODO\n", want: "line 1: expected BEGIN:VCALENDAR"},
+		{name: "property outside calendar", text: "SUMMARY:x\n", want: "line 1: expected BEGIN:VCALENDAR"},
+		{name: "missing colon", text: "BEGIN:VCALENDAR\nSUMMARY\nEND:VCALENDAR\n", want: "line 2: missing ':'"},
+		{name: "invalid parameter", text: "BEGIN:VCALENDAR\nDUE;TZID:20240101\nEND:VCALENDAR\n", want: "line 2: invalid parameter"},
+		{name: "mismatched end", text: "BEGIN:VCALENDAR\nBEGIN:VTODO\nEND:VEVENT\nEND:VCALENDAR\n", want: "line 3: expected END:VTODO"},
+		{name: "unexpected end", text: "BEGIN:VCALENDAR\nEND:VTODO\n", want: "line 2: unexpected END:VTODO"},
+		{name: "unterminated", text: "BEGIN:VCALENDAR\nBEGIN:VTODO\n", want: "unexpected end of calendar"},
+	}
+	for _, tt := range tests {
+		_, err := ParseICal(strings.NewReader(tt.text))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestParseICalTime(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "utc", value: "20241001T090000Z", want: utc(2024, 10, 1, 9, 0)},
+		{name: "floating", value: "20241001T180000", want: utc(2024, 10, 1, 9, 0)},
+		{name: "date", value: "20241001", want: utc(2024, 9, 30, 15, 0)},
+		{name: "value=date", params: map[string]string{"VALUE": "date"}, value: "20241001", want: utc(2024, 9, 30, 15, 0)},
+		{name: "iana tzid", params: map[string]string{"TZID": "America/New_York"}, value: "20240701T090000", want: utc(2024, 7, 1, 13, 0)},
+		{name: "iana tzid winter", params: map[string]string{"TZID": "America/New_York"}, value: "20240115T090000", want: utc(2024, 1, 15, 14, 0)},
+		{name: "tzid with z", params: map[string]string{"TZID": "America/New_York"}, value: "20240701T090000Z", want: utc(2024, 7, 1, 9, 0)},
+		{name: "tzid date", params: map[string]string{"TZID": "Europe/London", "VALUE": "DATE"}, value: "20240701", want: utc(2024, 6, 30, 23, 0)},
+		// Outlook・ExchangeのWindowsのタイムゾーン名
+		{name: "windows tokyo", params: map[string]string{"TZID": "Tokyo Standard Time"}, value: "20241001T180000", want: utc(2024, 10, 1, 9, 0)},
+		{name: "windows eastern summer", params: map[string]string{"TZID": "Eastern Standard Time"}, value: "20240701T090000", want: utc(2024, 7, 1, 13, 0)},
+		{name: "windows w. europe summer", params: map[string]string{"TZID": "W. Europe Standard Time"}, value: "20240701T090000", want: utc(2024, 7, 1, 7, 0)},
+		{name: "windows w. europe winter", params: map[string]string{"TZID": "W. Europe Standard Time"}, value: "20240115T090000", want: utc(2024, 1, 15, 8, 0)},
+		{name: "windows india", params: map[string]string{"TZID": "India Standard Time"}, value: "20240115T090000", want: utc(2024, 1, 15, 3, 30)},
+		{name: "windows utc", params: map[string]string{"TZID": "UTC"}, value: "20240115T090000", want: utc(2024, 1, 15, 9, 0)},
+		// 接頭辞付きの名前（Lightning・Evolution）
+		{name: "mozilla prefix", params: map[string]string{"TZID": "/mozilla.org/20050126_1/Europe/Berlin"}, value: "20240701T090000", want: utc(2024, 7, 1, 7, 0)},
+		{name: "freeassociation prefix", params: map[string]string{"TZID": "/freeassociation.sourceforge.net/Tzfile/Asia/Tokyo"}, value: "20241001T180000", want: utc(2024, 10, 1, 9, 0)},
+		{name: "unknown tzid", params: map[string]string{"TZID": "Customized Time Zone"}, value: "20241001T180000", wantErr: true},
+		{name: "empty tzid", params: map[string]string{"TZID": ""}, value: "20241001T180000", wantErr: true},
+		{name: "local tzid", params: map[string]string{"TZID": "Local"}, value: "20241001T180000", wantErr: true},
+		{name: "invalid date-time", value: "20241301T180000", wantErr: true},
+		{name: "invalid date", value: "2024-10-01", wantErr: true},
+		{name: "invalid utc", value: "20241001T1800Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		params := tt.params
+		if params == nil {
+			params = map[string]string{}
+		}
+		got, err := ParseICalTime(ICalProperty{Name: "DUE", Params: params, Value: tt.value}, tokyo)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: ParseICalTime(%q) = %v, want error", tt.name, tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ParseICalTime(%q) error = %v", tt.name, tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: ParseICalTime(%q) = %v, want %v", tt.name, tt.value, got.UTC(), tt.want)
+		}
+	}
+}
+
+// 対応表のタイムゾーンがすべて読み込めること
+func TestWindowsTimeZones(t *testing.T) {
+	for windows, iana := range windowsTimeZones {
+		if _, err := LoadICalLocation(windows); err != nil {
+			t.Errorf("LoadICalLocation(%q) -> %s: %v", windows, iana, err)
+		}
+	}
+}
+
+func TestICalTextEscape(t *testing.T) {
+	texts := []string{"plain", "a, b; c", `back\slash`, "multi\nline", "crlf\r\nline", "末尾\\"}
+	for _, text := range texts {
+		want := strings.ReplaceAll(text, "\r\n", "\n")
+		if got := UnescapeICalText(EscapeICalText(text)); got != want {
+			t.Errorf("round trip of %q = %q", text, got)
+		}
+	}
+}
+
+func TestICalWriterFolding(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewICalWriter(&buf)
+	summary := strings.Repeat("あ", 40)
+	w.Text("SUMMARY", summary)
+	w.Time("DUE", time.Date(2024, 10, 1, 18, 0, 0, 0, time.FixedZone("JST", 9*3600)))
+	if err := w.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	components, err := ParseICal(strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\n" + buf.String() + "END:VTODO\r\nEND:VCALENDAR\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prop, _ := components[0].Get("SUMMARY"); prop.Value != summary {
+		t.Errorf("unfolded summary = %q", prop.Value)
+	}
+	if prop, _ := components[0].Get("DUE"); prop.Value != "20241001T090000Z" {
+		t.Errorf("due = %q", prop.Value)
+	}
+}
@@ -0,0 +1,21 @@
+package utils
+
+import "regexp"
+
+// mentionPattern 本文中の「@メールアドレス」形式のメンション（行頭または空白・括弧の直後のみ）
+var mentionPattern = regexp.MustCompile(`(?:^|[\s(（])@([A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,})`)
+
+// ParseMentions 本文からメンションされたメールアドレスを出現順に重複なく取り出します
+func ParseMentions(body string) []string {
+	matches := mentionPattern.FindAllStringSubmatch(body, -1)
+	emails := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		email := match[1]
+		if !seen[email] {
+			seen[email] = true
+			emails = append(emails, email)
+		}
+	}
+	return emails
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"encoding/json"
+)
+
+// Optional JSONで「未指定」と「null」を区別するための値
+// フィールドが存在すればSetがtrueになり、nullの場合はValueがnilになります
+type Optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+// UnmarshalJSON フィールドが存在する場合のみ呼ばれるためSetをtrueにします
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	var v T
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	o.Value = &v
+	return nil
+}
+
+// MarshalJSON 値またはnullを出力します
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if o.Value == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(*o.Value)
+}
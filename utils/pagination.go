@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+const (
+	DefaultPageLimit = 50  // limit未指定時の件数
+	MaxPageLimit     = 200 // limitの上限
+)
+
+// Cursor キーセットページネーション用のカーソル
+// Sortには発行時のソート指定、Valueには最終行のソートキー、IDには最終行のIDを保持します
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"i"`
+}
+
+// EncodeCursor カーソルを不透明な文字列にエンコードします
+func EncodeCursor(cursor Cursor) string {
+	bytes, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeCursor 文字列からカーソルをデコードします
+func DecodeCursor(s string) (Cursor, error) {
+	var cursor Cursor
+	bytes, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	if err := json.Unmarshal(bytes, &cursor); err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+// ParseLimit limitクエリパラメータを解釈します（未指定時はデフォルト値）
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultPageLimit, nil
+	}
+	limit, err := strconv.Atoi(s)
+	if err != nil || limit < 1 || limit > MaxPageLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxPageLimit)
+	}
+	return limit, nil
+}
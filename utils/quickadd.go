@@ -0,0 +1,643 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// QuickAdd クイック追加の入力（自由文）を解析した結果
+type QuickAdd struct {
+	Title    string
+	DueAt    *time.Time
+	RRule    *RRule
+	Priority string // A〜C（なければ空）
+	Tags     []string
+}
+
+// quickAddRemoved 解析済みの部分を置き換える印（タイトルを組み立てる際に取り除く）
+const quickAddRemoved = '\x00'
+
+// 日付だけを指定した場合の期限の時刻（その日のうちは期限切れにしない）
+const (
+	quickAddEndOfDayHour   = 23
+	quickAddEndOfDayMinute = 59
+)
+
+// quickAddPriorities !の後に書く優先度
+var quickAddPriorities = map[string]string{
+	"high": "A", "h": "A", "1": "A", "urgent": "A", "高": "A",
+	"medium": "B", "med": "B", "m": "B", "2": "B", "中": "B",
+	"low": "C", "l": "C", "3": "C", "低": "C",
+}
+
+var quickAddWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"日": time.Sunday, "月": time.Monday, "火": time.Tuesday, "水": time.Wednesday,
+	"木": time.Thursday, "金": time.Friday, "土": time.Saturday,
+}
+
+var quickAddNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+var quickAddFrequencies = map[string]Frequency{
+	"day": FreqDaily, "daily": FreqDaily, "日": FreqDaily,
+	"week": FreqWeekly, "weekly": FreqWeekly, "週": FreqWeekly, "週間": FreqWeekly,
+	"month": FreqMonthly, "monthly": FreqMonthly, "月": FreqMonthly, "か月": FreqMonthly, "ヶ月": FreqMonthly, "カ月": FreqMonthly, "ヵ月": FreqMonthly,
+	"year": FreqYearly, "yearly": FreqYearly, "annually": FreqYearly, "年": FreqYearly,
+}
+
+const (
+	// 英語の曜日（略称はon・next・this・everyの後でだけ認識する）
+	enWeekday     = `mon(?:day)?|tue(?:s(?:day)?)?|wed(?:nesday)?|thu(?:r(?:s(?:day)?)?)?|fri(?:day)?|sat(?:urday)?|sun(?:day)?`
+	enWeekdayFull = `monday|tuesday|wednesday|thursday|friday|saturday|sunday`
+	enMonth       = `jan(?:uary)?|feb(?:ruary)?|mar(?:ch)?|apr(?:il)?|may|june?|july?|aug(?:ust)?|sep(?:t(?:ember)?)?|oct(?:ober)?|nov(?:ember)?|dec(?:ember)?`
+	enNumber      = `\d+|an?|one|two|three|four|five|six|seven|eight|nine|ten`
+	enUnit        = `day|week|month|year`
+	// 日付の前置詞（on friday・due tomorrowなど）
+	enDatePrefix = `(?:\b(?:on|by|due)\s+)?`
+	jaWeekday    = `[月火水木金土日]曜日?`
+	jaMonths     = `か月|ヶ月|カ月|ヵ月`
+	// 日付・時刻の後の助詞（明日の・9時に・金曜までになど）
+	jaParticle = `(?:の|に|から|まで(?:に)?)?`
+)
+
+var (
+	quickAddTagPattern      = regexp.MustCompile(`(?:^|\s)#([^\s#]+)`)
+	quickAddPriorityPattern = regexp.MustCompile(`(?:^|\s)!(\S+)`)
+	quickAddJaWeekdayChar   = regexp.MustCompile(`([月火水木金土日])曜`)
+	quickAddEnWeekdayWord   = regexp.MustCompile(`(?i)` + enWeekday)
+)
+
+// quickAddRule 正規表現と、一致した部分を解釈する関数（解釈できなければfalseを返し、次の一致を試す）
+type quickAddRule struct {
+	pattern *regexp.Regexp
+	apply   func(p *quickAddParser, m []string) bool
+}
+
+func newQuickAddRule(pattern string, apply func(p *quickAddParser, m []string) bool) quickAddRule {
+	return quickAddRule{pattern: regexp.MustCompile(`(?i)` + pattern), apply: apply}
+}
+
+// quickAddParser 解析中の状態
+type quickAddParser struct {
+	text  string
+	now   time.Time
+	today time.Time
+	loc   *time.Location
+
+	date          *time.Time // 日付（0時）
+	exact         *time.Time // 「2時間後」のような日時
+	hour          int
+	minute        int
+	hasTime       bool
+	defaultHour   int // 時刻の指定がない場合の時刻（その日の終わり。今夜は20時）
+	defaultMinute int
+	rule          *RRule
+}
+
+// ParseQuickAdd 自由文からタイトル・期限・繰り返し・優先度・タグを取り出します
+// 日付・時刻はlocのタイムゾーンでnowを基準に解釈します。英語と日本語の表現に対応します。
+//   - タグ: #home
+//   - 優先度: !high / !medium / !low（!h・!m・!l、!1〜!3、!高・!中・!低も可）→ A / B / C
+//   - 日付: today, tomorrow, next friday, in 3 days, jan 5, 2024-01-05, on 1/5, 1/5/2025, 明日, 来週金曜, 3日後, 1月5日, 1/5まで
+//   - 時刻: 9am, 9:30pm, at 14:00, noon, tonight, 午後3時, 9時半, 正午, 今夜
+//   - 繰り返し: every day, every other week, every monday, every 15th, monthly, 毎日, 毎週月曜, 毎月15日, 隔週, 3日ごと
+//
+// 「3/4」のような分数と区別するため、年のない「月/日」はon・by・dueの後か、まで・に・からの前に書いた場合だけ日付とします。
+// 時刻だけの場合は今日（過ぎていれば明日）、日付だけの場合はその日の23:59（今夜は20時）を期限とします。
+// 繰り返しだけの場合は今日以降の最初の回を期限とします。
+func ParseQuickAdd(text string, now time.Time, loc *time.Location) (*QuickAdd, error) {
+	now = now.In(loc)
+	p := &quickAddParser{
+		text:  normalizeQuickAddText(text),
+		now:   now,
+		today: StartOfDay(now, loc),
+		loc:   loc,
+
+		defaultHour:   quickAddEndOfDayHour,
+		defaultMinute: quickAddEndOfDayMinute,
+	}
+	result := &QuickAdd{}
+
+	seen := make(map[string]bool)
+	p.each(quickAddTagPattern, func(m []string) bool {
+		tag := strings.TrimRight(m[1], ",.、。")
+		if tag == "" {
+			return false
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			result.Tags = append(result.Tags, tag)
+		}
+		return true
+	}, false)
+	p.each(quickAddPriorityPattern, func(m []string) bool {
+		priority, ok := quickAddPriorities[strings.ToLower(m[1])]
+		if ok {
+			result.Priority = priority
+		}
+		return ok
+	}, true)
+
+	// 「every monday」の曜日を日付として読まないよう、繰り返し→日付→時間帯→時刻の順に解析する
+	for _, rules := range [][]quickAddRule{quickAddRecurrenceRules, quickAddDateRules, quickAddPartOfDayRules, quickAddTimeRules} {
+		for _, rule := range rules {
+			if p.each(rule.pattern, func(m []string) bool { return rule.apply(p, m) }, true) {
+				break
+			}
+		}
+	}
+
+	result.Title = p.title()
+	if result.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	result.DueAt = p.dueAt()
+	result.RRule = p.rule
+	return result, nil
+}
+
+// normalizeQuickAddText 全角の英数字・記号・空白を半角にします
+func normalizeQuickAddText(text string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			return r - '！' + '!'
+		case r == quickAddRemoved:
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// each 一致した部分をapplyで解釈し、解釈できた部分を取り除きます
+// onceがtrueなら最初に解釈できた1か所だけを扱います。1か所でも解釈できればtrueを返します。
+func (p *quickAddParser) each(pattern *regexp.Regexp, apply func(m []string) bool, once bool) bool {
+	found := false
+	for _, loc := range pattern.FindAllStringSubmatchIndex(p.text, -1) {
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			if loc[2*i] >= 0 {
+				m[i] = p.text[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		if !apply(m) {
+			continue
+		}
+		// 後の一致の位置がずれないよう、同じバイト数の印で置き換える
+		p.text = p.text[:loc[0]] + strings.Repeat(string(quickAddRemoved), loc[1]-loc[0]) + p.text[loc[1]:]
+		found = true
+		if once {
+			break
+		}
+	}
+	return found
+}
+
+// title 解析済みの部分を取り除いた残りをタイトルにします
+// 取り除いた部分の前後がどちらも日本語なら詰め、それ以外は空白にします
+func (p *quickAddParser) title() string {
+	runes := []rune(p.text)
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != quickAddRemoved {
+			b.WriteRune(runes[i])
+			continue
+		}
+		j := i
+		for j < len(runes) && runes[j] == quickAddRemoved {
+			j++
+		}
+		if i == 0 || j == len(runes) || runes[i-1] < utf8.RuneSelf || runes[j] < utf8.RuneSelf {
+			b.WriteRune(' ')
+		}
+		i = j - 1
+	}
+	return strings.Trim(strings.Join(strings.Fields(b.String()), " "), " ,、")
+}
+
+// dueAt 解析した日付・時刻・繰り返しから期限を決めます（どれもなければnil）
+func (p *quickAddParser) dueAt() *time.Time {
+	if p.exact != nil {
+		return p.exact
+	}
+	if p.date == nil && !p.hasTime && p.rule == nil {
+		return nil
+	}
+	hour, minute := p.defaultHour, p.defaultMinute
+	if p.hasTime {
+		hour, minute = p.hour, p.minute
+	}
+	at := func(day time.Time) time.Time {
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, p.loc)
+	}
+	if p.date != nil {
+		due := at(*p.date)
+		return &due
+	}
+
+	// 日付の指定がなければ今日以降の最初の回（今日の時刻が過ぎていれば明日以降）
+	passed := p.hasTime && at(p.today).Before(p.now)
+	if p.rule != nil && len(p.rule.ByMonthDay) > 0 {
+		day := p.rule.ByMonthDay[0]
+		for i := 0; i <= 12; i++ {
+			candidate := time.Date(p.today.Year(), p.today.Month()+time.Month(i), day, 0, 0, 0, 0, p.loc)
+			if candidate.Day() != day || candidate.Before(p.today) || (candidate.Equal(p.today) && passed) {
+				continue
+			}
+			due := at(candidate)
+			return &due
+		}
+	}
+	for i := 0; i < 7; i++ {
+		if i == 0 && passed {
+			continue
+		}
+		candidate := AddDays(p.today, i, p.loc)
+		if p.rule == nil || len(p.rule.ByDay) == 0 || p.rule.matchesWeekday(candidate.Weekday()) {
+			due := at(candidate)
+			return &due
+		}
+	}
+	due := at(AddDays(p.today, 1, p.loc))
+	return &due
+}
+
+// setDate 日付を設定します（存在しない日付ならfalse）
+func (p *quickAddParser) setDate(year int, month time.Month, day int) bool {
+	date := time.Date(year, month, day, 0, 0, 0, 0, p.loc)
+	if date.Day() != day || date.Month() != month {
+		return false
+	}
+	p.date = &date
+	return true
+}
+
+// setMonthDay 年のない月日を設定します（今年の日付が過ぎていれば来年）
+func (p *quickAddParser) setMonthDay(month time.Month, day int) bool {
+	year := p.today.Year()
+	if time.Date(year, month, day, 0, 0, 0, 0, p.loc).Before(p.today) {
+		year++
+	}
+	return p.setDate(year, month, day)
+}
+
+// setDays 今日からn日後を設定します
+func (p *quickAddParser) setDays(n int) bool {
+	date := AddDays(p.today, n, p.loc)
+	p.date = &date
+	return true
+}
+
+// setTime 時刻を設定します（範囲外ならfalse）
+func (p *quickAddParser) setTime(hour, minute int) bool {
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return false
+	}
+	p.hour, p.minute, p.hasTime = hour, minute, true
+	return true
+}
+
+// weekdayOf 今日から見て、今週（月曜始まり）からweeks週後のwdの日数
+func (p *quickAddParser) weekdayOf(weeks int, wd time.Weekday) int {
+	monday := -((int(p.today.Weekday()) + 6) % 7)
+	return monday + 7*weeks + (int(wd)+6)%7
+}
+
+// upcoming 今日以降で最初のwdまでの日数
+func (p *quickAddParser) upcoming(wd time.Weekday) int {
+	return (int(wd) - int(p.today.Weekday()) + 7) % 7
+}
+
+// quickAddInt 数字の文字列を数にします（空なら0）
+func quickAddInt(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// quickAddNumber 数字または英語の数詞を数にします
+func quickAddNumber(s string) int {
+	if n, ok := quickAddNumbers[strings.ToLower(s)]; ok {
+		return n
+	}
+	return quickAddInt(s)
+}
+
+// quickAddWeekday 曜日の名前（英語は先頭3文字、日本語は1文字目）から曜日を返します
+func quickAddWeekday(s string) time.Weekday {
+	s = strings.ToLower(s)
+	if len(s) >= 3 && s[0] < utf8.RuneSelf {
+		return quickAddWeekdays[s[:3]]
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return quickAddWeekdays[string(r)]
+}
+
+// quickAddMonth 英語の月の名前から月を返します
+func quickAddMonth(s string) time.Month {
+	prefix := strings.ToLower(s)[:3]
+	for m := time.January; m <= time.December; m++ {
+		if strings.ToLower(m.String()[:3]) == prefix {
+			return m
+		}
+	}
+	return 0
+}
+
+func newQuickAddRRule(freq Frequency, interval int) *RRule {
+	return &RRule{Freq: freq, Interval: interval, WeekStart: time.Monday}
+}
+
+// setWeekdays 曜日を指定した毎週の繰り返しを設定します
+func (p *quickAddParser) setWeekdays(interval int, weekdays []time.Weekday) bool {
+	rule := newQuickAddRRule(FreqWeekly, interval)
+	seen := make(map[time.Weekday]bool)
+	for _, wd := range weekdays {
+		if !seen[wd] {
+			seen[wd] = true
+			rule.ByDay = append(rule.ByDay, WeekdayNum{Weekday: wd})
+		}
+	}
+	p.rule = rule
+	return true
+}
+
+var workdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// quickAddRecurrenceRules 繰り返しの表現（具体的なものから順に試す）
+var quickAddRecurrenceRules = []quickAddRule{
+	newQuickAddRule(`\bevery\s+other\s+(`+enUnit+`)\b`, func(p *quickAddParser, m []string) bool {
+		p.rule = newQuickAddRRule(quickAddFrequencies[strings.ToLower(m[1])], 2)
+		return true
+	}),
+	newQuickAddRule(`\bevery\s+(`+enNumber+`)\s+(`+enUnit+`)s?\b`, func(p *quickAddParser, m []string) bool {
+		n := quickAddNumber(m[1])
+		if n < 1 {
+			return false
+		}
+		p.rule = newQuickAddRRule(quickAddFrequencies[strings.ToLower(m[2])], n)
+		return true
+	}),
+	newQuickAddRule(`\bevery\s+(?:weekday|workday)s?\b`, func(p *quickAddParser, m []string) bool {
+		return p.setWeekdays(1, workdays)
+	}),
+	newQuickAddRule(`\bevery\s+((?:`+enWeekday+`)\b(?:\s*(?:,|and|&)\s*(?:`+enWeekday+`)\b)*)`, func(p *quickAddParser, m []string) bool {
+		var weekdays []time.Weekday
+		for _, name := range quickAddEnWeekdayWord.FindAllString(m[1], -1) {
+			weekdays = append(weekdays, quickAddWeekday(name))
+		}
+		return p.setWeekdays(1, weekdays)
+	}),
+	newQuickAddRule(`\bevery\s+(\d{1,2})(?:st|nd|rd|th)\b(?:\s+of\s+the\s+month\b)?`, func(p *quickAddParser, m []string) bool {
+		day := quickAddInt(m[1])
+		if day < 1 || day > 31 {
+			return false
+		}
+		p.rule = newQuickAddRRule(FreqMonthly, 1)
+		p.rule.ByMonthDay = []int{day}
+		return true
+	}),
+	newQuickAddRule(`\bevery\s+(`+enUnit+`)\b`, func(p *quickAddParser, m []string) bool {
+		p.rule = newQuickAddRRule(quickAddFrequencies[strings.ToLower(m[1])], 1)
+		return true
+	}),
+	newQuickAddRule(`\b(daily|weekly|monthly|yearly|annually)\b`, func(p *quickAddParser, m []string) bool {
+		p.rule = newQuickAddRRule(quickAddFrequencies[strings.ToLower(m[1])], 1)
+		return true
+	}),
+	newQuickAddRule(`(隔週|毎週)((?:`+jaWeekday+`[、,・と]?)+)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		var weekdays []time.Weekday
+		for _, wm := range quickAddJaWeekdayChar.FindAllStringSubmatch(m[2], -1) {
+			weekdays = append(weekdays, quickAddWeekday(wm[1]))
+		}
+		interval := 1
+		if m[1] == "隔週" {
+			interval = 2
+		}
+		return p.setWeekdays(interval, weekdays)
+	}),
+	newQuickAddRule(`毎月(\d{1,2})日`+jaParticle, func(p *quickAddParser, m []string) bool {
+		day := quickAddInt(m[1])
+		if day < 1 || day > 31 {
+			return false
+		}
+		p.rule = newQuickAddRRule(FreqMonthly, 1)
+		p.rule.ByMonthDay = []int{day}
+		return true
+	}),
+	newQuickAddRule(`(?:毎週)?平日(?:毎日)?`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setWeekdays(1, workdays)
+	}),
+	newQuickAddRule(`(\d+)(日|週間|週|`+jaMonths+`|年)(ごと|毎|おき)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		n := quickAddInt(m[1])
+		if n < 1 {
+			return false
+		}
+		// 「1日おき」は1日空けるので2日ごと
+		if m[3] == "おき" {
+			n++
+		}
+		p.rule = newQuickAddRRule(quickAddFrequencies[m[2]], n)
+		return true
+	}),
+	newQuickAddRule(`隔(日|週|月|年)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		p.rule = newQuickAddRRule(quickAddFrequencies[m[1]], 2)
+		return true
+	}),
+	newQuickAddRule(`毎(日|週|月|年)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		p.rule = newQuickAddRRule(quickAddFrequencies[m[1]], 1)
+		return true
+	}),
+}
+
+// quickAddDateRules 日付の表現（具体的なものから順に試す）
+var quickAddDateRules = []quickAddRule{
+	newQuickAddRule(enDatePrefix+`\b(\d{4})[-/](\d{1,2})[-/](\d{1,2})\b`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setDate(quickAddInt(m[1]), time.Month(quickAddInt(m[2])), quickAddInt(m[3]))
+	}),
+	newQuickAddRule(`(\d{4})年(\d{1,2})月(\d{1,2})日`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setDate(quickAddInt(m[1]), time.Month(quickAddInt(m[2])), quickAddInt(m[3]))
+	}),
+	newQuickAddRule(`(\d{1,2})月(\d{1,2})日`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setMonthDay(time.Month(quickAddInt(m[1])), quickAddInt(m[2]))
+	}),
+	newQuickAddRule(enDatePrefix+`\b(`+enMonth+`)\.?\s+(\d{1,2})(?:st|nd|rd|th)?\b(?:,?\s+(\d{4})\b)?`, func(p *quickAddParser, m []string) bool {
+		if m[3] != "" {
+			return p.setDate(quickAddInt(m[3]), quickAddMonth(m[1]), quickAddInt(m[2]))
+		}
+		return p.setMonthDay(quickAddMonth(m[1]), quickAddInt(m[2]))
+	}),
+	newQuickAddRule(enDatePrefix+`\b(\d{1,2})(?:st|nd|rd|th)?\s+(`+enMonth+`)\b(?:,?\s+(\d{4})\b)?`, func(p *quickAddParser, m []string) bool {
+		if m[3] != "" {
+			return p.setDate(quickAddInt(m[3]), quickAddMonth(m[2]), quickAddInt(m[1]))
+		}
+		return p.setMonthDay(quickAddMonth(m[2]), quickAddInt(m[1]))
+	}),
+	newQuickAddRule(enDatePrefix+`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`+jaParticle, (*quickAddParser).setSlashDate),
+	// 年のない「月/日」は分数（pay 3/4 of invoice）と区別できるよう、前置詞か助詞が付いた場合だけ日付とする
+	newQuickAddRule(`\b(?:on|by|due)\s+(\d{1,2})/(\d{1,2})()\b`+jaParticle, (*quickAddParser).setSlashDate),
+	newQuickAddRule(`\b(\d{1,2})/(\d{1,2})()(?:まで(?:に)?|に|から)`, (*quickAddParser).setSlashDate),
+	newQuickAddRule(enDatePrefix+`\b(?:the\s+)?day\s+after\s+tomorrow\b|(?:明後日|あさって)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setDays(2)
+	}),
+	newQuickAddRule(enDatePrefix+`\b(?:tomorrow|tmrw|tmr)\b|(?:明日|あした)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setDays(1)
+	}),
+	newQuickAddRule(enDatePrefix+`\btoday\b|(?:今日|本日|きょう)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setDays(0)
+	}),
+	newQuickAddRule(`\bin\s+(`+enNumber+`)\s+(minute|min|hour|hr|day|week|month|year)s?\b`, func(p *quickAddParser, m []string) bool {
+		return p.addRelative(quickAddNumber(m[1]), strings.ToLower(m[2]))
+	}),
+	newQuickAddRule(`(\d+)(分|時間|日|週間|`+jaMonths+`|年)後`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.addRelative(quickAddInt(m[1]), m[2])
+	}),
+	newQuickAddRule(`(今週|来週|再来週)の?(`+jaWeekday+`)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		weeks := map[string]int{"今週": 0, "来週": 1, "再来週": 2}[m[1]]
+		return p.setDays(p.weekdayOf(weeks, quickAddWeekday(m[2])))
+	}),
+	newQuickAddRule(enDatePrefix+`\bnext\s+(`+enWeekday+`)\b`, func(p *quickAddParser, m []string) bool {
+		return p.setDays(p.weekdayOf(1, quickAddWeekday(m[1])))
+	}),
+	newQuickAddRule(`(?:\b(?:on|by|due|this)\s+)(`+enWeekday+`)\b|\b(`+enWeekdayFull+`)\b|(`+jaWeekday+`)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setDays(p.upcoming(quickAddWeekday(m[1] + m[2] + m[3])))
+	}),
+	newQuickAddRule(enDatePrefix+`\bnext\s+(week|month|year)\b|(再来週|来週|来月|来年)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		switch strings.ToLower(m[1]) + m[2] {
+		case "week", "来週":
+			return p.setDays(p.weekdayOf(1, time.Monday))
+		case "再来週":
+			return p.setDays(p.weekdayOf(2, time.Monday))
+		case "month", "来月":
+			return p.setDate(p.today.Year(), p.today.Month()+1, 1) || p.setDate(p.today.Year()+1, time.January, 1)
+		default:
+			return p.setDate(p.today.Year()+1, time.January, 1)
+		}
+	}),
+	newQuickAddRule(enDatePrefix+`\bend\s+of\s+(?:the\s+)?month\b|月末`+jaParticle, func(p *quickAddParser, m []string) bool {
+		last := time.Date(p.today.Year(), p.today.Month()+1, 0, 0, 0, 0, 0, p.loc)
+		p.date = &last
+		return true
+	}),
+	newQuickAddRule(enDatePrefix+`\b(?:this\s+)?weekend\b|(?:今週末|週末)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		if p.today.Weekday() == time.Sunday {
+			return p.setDays(0)
+		}
+		return p.setDays(p.upcoming(time.Saturday))
+	}),
+}
+
+// quickAddPartOfDayRules 時間帯の表現（日付の表現と一緒に使える）
+var quickAddPartOfDayRules = []quickAddRule{
+	newQuickAddRule(`\btonight\b|(?:今夜|今晩)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		p.defaultHour, p.defaultMinute = 20, 0
+		if p.date == nil && p.exact == nil {
+			p.setDays(0)
+		}
+		return true
+	}),
+}
+
+// setSlashDate 「月/日」「月/日/年」の日付を設定します（m[3]が年）
+func (p *quickAddParser) setSlashDate(m []string) bool {
+	if m[3] != "" {
+		return p.setDate(quickAddInt(m[3]), time.Month(quickAddInt(m[1])), quickAddInt(m[2]))
+	}
+	return p.setMonthDay(time.Month(quickAddInt(m[1])), quickAddInt(m[2]))
+}
+
+// addRelative 今からn単位後を設定します（分・時間は時刻まで、それ以外は日付だけ）
+func (p *quickAddParser) addRelative(n int, unit string) bool {
+	if n < 0 {
+		return false
+	}
+	switch unit {
+	case "minute", "min", "分":
+		exact := p.now.Add(time.Duration(n) * time.Minute)
+		p.exact = &exact
+		return true
+	case "hour", "hr", "時間":
+		exact := p.now.Add(time.Duration(n) * time.Hour)
+		p.exact = &exact
+		return true
+	}
+	switch quickAddFrequencies[unit] {
+	case FreqDaily:
+		return p.setDays(n)
+	case FreqWeekly:
+		return p.setDays(7 * n)
+	case FreqMonthly:
+		date := p.today.AddDate(0, n, 0)
+		p.date = &date
+		return true
+	case FreqYearly:
+		date := p.today.AddDate(n, 0, 0)
+		p.date = &date
+		return true
+	}
+	return false
+}
+
+// quickAddTimeRules 時刻の表現（具体的なものから順に試す）
+var quickAddTimeRules = []quickAddRule{
+	newQuickAddRule(`(?:\bat\s+)?\b(\d{1,2})(?::(\d{2}))?\s*([ap])\.?m\b\.?`, func(p *quickAddParser, m []string) bool {
+		hour := quickAddInt(m[1])
+		if hour < 1 || hour > 12 {
+			return false
+		}
+		hour %= 12
+		if strings.ToLower(m[3]) == "p" {
+			hour += 12
+		}
+		return p.setTime(hour, quickAddInt(m[2]))
+	}),
+	newQuickAddRule(`(?:\bat\s+)?\b(noon|midnight)\b|(正午)`+jaParticle, func(p *quickAddParser, m []string) bool {
+		if strings.ToLower(m[1]) == "midnight" {
+			return p.setTime(0, 0)
+		}
+		return p.setTime(12, 0)
+	}),
+	newQuickAddRule(`(午前|午後)?\s*(\d{1,2})時(間)?(?:(半)|(\d{1,2})分)?`+jaParticle, func(p *quickAddParser, m []string) bool {
+		// 「2時間」は時刻ではない
+		if m[3] != "" {
+			return false
+		}
+		minute := quickAddInt(m[5])
+		if m[4] != "" {
+			minute = 30
+		}
+		return p.setJaTime(m[1], quickAddInt(m[2]), minute)
+	}),
+	newQuickAddRule(`(午前|午後)?\s*(?:\bat\s+)?\b(\d{1,2}):(\d{2})\b`+jaParticle, func(p *quickAddParser, m []string) bool {
+		return p.setJaTime(m[1], quickAddInt(m[2]), quickAddInt(m[3]))
+	}),
+	newQuickAddRule(`\bat\s+(\d{1,2})\b`, func(p *quickAddParser, m []string) bool {
+		return p.setTime(quickAddInt(m[1]), 0)
+	}),
+}
+
+// setJaTime 午前・午後の付いた時刻を設定します（午後3時は15時、午前12時は0時）
+func (p *quickAddParser) setJaTime(meridiem string, hour, minute int) bool {
+	switch meridiem {
+	case "午前":
+		if hour > 12 {
+			return false
+		}
+		hour %= 12
+	case "午後":
+		if hour > 12 {
+			return false
+		}
+		hour = hour%12 + 12
+	}
+	return p.setTime(hour, minute)
+}
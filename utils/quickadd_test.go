@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2025-01-08（水）10:00
+	now := time.Date(2025, 1, 8, 10, 0, 0, 0, loc)
+	at := func(year int, month time.Month, day, hour, minute int) *time.Time {
+		due := time.Date(year, month, day, hour, minute, 0, 0, loc)
+		return &due
+	}
+
+	tests := []struct {
+		text     string
+		title    string
+		due      *time.Time
+		rrule    string
+		priority string
+		tags     []string
+	}{
+		// 日付（日付だけの場合はその日の23:59）
+		{text: "buy milk today", title: "buy milk", due: at(2025, 1, 8, 23, 59)},
+		{text: "call mom tomorrow", title: "call mom", due: at(2025, 1, 9, 23, 59)},
+		{text: "report next friday", title: "report", due: at(2025, 1, 17, 23, 59)},
+		{text: "dentist in 3 days", title: "dentist", due: at(2025, 1, 11, 23, 59)},
+		{text: "party jan 5", title: "party", due: at(2026, 1, 5, 23, 59)},
+		{text: "tax return 2024-01-05", title: "tax return", due: at(2024, 1, 5, 23, 59)},
+		{text: "meeting on 1/10", title: "meeting", due: at(2025, 1, 10, 23, 59)},
+		{text: "meeting on 1/5", title: "meeting", due: at(2026, 1, 5, 23, 59)},
+		{text: "renew passport 1/5/2025", title: "renew passport", due: at(2025, 1, 5, 23, 59)},
+		{text: "資料提出 明日", title: "資料提出", due: at(2025, 1, 9, 23, 59)},
+		{text: "来週金曜に定例", title: "定例", due: at(2025, 1, 17, 23, 59)},
+		{text: "本を返す 3日後", title: "本を返す", due: at(2025, 1, 11, 23, 59)},
+		{text: "誕生日 1月5日", title: "誕生日", due: at(2026, 1, 5, 23, 59)},
+		{text: "1/10までに資料を送る", title: "資料を送る", due: at(2025, 1, 10, 23, 59)},
+
+		// 時刻（過ぎていれば明日）
+		{text: "standup 9am", title: "standup", due: at(2025, 1, 9, 9, 0)},
+		{text: "call bob 9:30pm", title: "call bob", due: at(2025, 1, 8, 21, 30)},
+		{text: "sync at 14:00", title: "sync", due: at(2025, 1, 8, 14, 0)},
+		{text: "lunch noon", title: "lunch", due: at(2025, 1, 8, 12, 0)},
+		{text: "watch movie tonight", title: "watch movie", due: at(2025, 1, 8, 20, 0)},
+		{text: "会議 午後3時", title: "会議", due: at(2025, 1, 8, 15, 0)},
+		{text: "朝会 9時半", title: "朝会", due: at(2025, 1, 9, 9, 30)},
+		{text: "正午にランチ", title: "ランチ", due: at(2025, 1, 8, 12, 0)},
+		{text: "今夜 映画", title: "映画", due: at(2025, 1, 8, 20, 0)},
+		{text: "dinner tomorrow tonight", title: "dinner", due: at(2025, 1, 9, 20, 0)},
+
+		// 分数は日付として読まない
+		{text: "pay 3/4 of invoice", title: "pay 3/4 of invoice"},
+		{text: "pay 3/4 of invoice by friday", title: "pay 3/4 of invoice", due: at(2025, 1, 10, 23, 59)},
+		{text: "read chapter 1/2 tonight", title: "read chapter 1/2", due: at(2025, 1, 8, 20, 0)},
+
+		// 繰り返し（今日以降の最初の回を期限にする）
+		{text: "water plants every day", title: "water plants", due: at(2025, 1, 8, 23, 59), rrule: "FREQ=DAILY"},
+		{text: "review every other week", title: "review", due: at(2025, 1, 8, 23, 59), rrule: "FREQ=WEEKLY;INTERVAL=2"},
+		{text: "gym every monday", title: "gym", due: at(2025, 1, 13, 23, 59), rrule: "FREQ=WEEKLY;BYDAY=MO"},
+		{text: "rent every 15th", title: "rent", due: at(2025, 1, 15, 23, 59), rrule: "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{text: "report monthly", title: "report", due: at(2025, 1, 8, 23, 59), rrule: "FREQ=MONTHLY"},
+		{text: "日報 毎日", title: "日報", due: at(2025, 1, 8, 23, 59), rrule: "FREQ=DAILY"},
+		{text: "毎週月曜にゴミ出し", title: "ゴミ出し", due: at(2025, 1, 13, 23, 59), rrule: "FREQ=WEEKLY;BYDAY=MO"},
+		{text: "家賃 毎月15日", title: "家賃", due: at(2025, 1, 15, 23, 59), rrule: "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{text: "隔週 定例", title: "定例", due: at(2025, 1, 8, 23, 59), rrule: "FREQ=WEEKLY;INTERVAL=2"},
+		{text: "水やり 3日ごと", title: "水やり", due: at(2025, 1, 8, 23, 59), rrule: "FREQ=DAILY;INTERVAL=3"},
+
+		// 優先度・タグ
+		{text: "pay rent tomorrow 9am #home !high every month", title: "pay rent", due: at(2025, 1, 9, 9, 0), rrule: "FREQ=MONTHLY", priority: "A", tags: []string{"home"}},
+		{text: "毎週月曜と木曜 午後3時 定例会議 #仕事 !中", title: "定例会議", due: at(2025, 1, 9, 15, 0), rrule: "FREQ=WEEKLY;BYDAY=MO,TH", priority: "B", tags: []string{"仕事"}},
+		{text: "牛乳を買う #買い物 !低", title: "牛乳を買う", priority: "C", tags: []string{"買い物"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := ParseQuickAdd(tt.text, now, loc)
+			if err != nil {
+				t.Fatalf("ParseQuickAdd() error = %v", err)
+			}
+			if got.Title != tt.title {
+				t.Errorf("Title = %q, want %q", got.Title, tt.title)
+			}
+			switch {
+			case got.DueAt == nil && tt.due != nil, got.DueAt != nil && tt.due == nil:
+				t.Errorf("DueAt = %v, want %v", got.DueAt, tt.due)
+			case got.DueAt != nil && !got.DueAt.Equal(*tt.due):
+				t.Errorf("DueAt = %v, want %v", *got.DueAt, *tt.due)
+			}
+			var rrule string
+			if got.RRule != nil {
+				rrule = got.RRule.String()
+			}
+			if rrule != tt.rrule {
+				t.Errorf("RRule = %q, want %q", rrule, tt.rrule)
+			}
+			if got.Priority != tt.priority {
+				t.Errorf("Priority = %q, want %q", got.Priority, tt.priority)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.tags) {
+				t.Errorf("Tags = %v, want %v", got.Tags, tt.tags)
+			}
+		})
+	}
+}
+
+func TestParseQuickAddEmptyTitle(t *testing.T) {
+	if _, err := ParseQuickAdd("tomorrow 9am #home", time.Now(), time.UTC); err == nil {
+		t.Error("expected an error when nothing is left for the title")
+	}
+}
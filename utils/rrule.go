@@ -0,0 +1,590 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency RRULEの繰り返し単位
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+	FreqYearly  Frequency = "YEARLY"
+)
+
+// maxRRulePeriods 発生日時を探す期間の数の上限（DAILYで約27年分）
+// 一度も発生しないルールは解析時に拒否するので、DTSTARTによってはまれにしか発生しないルールのための余裕です
+const maxRRulePeriods = 10000
+
+// rruleHorizonYears 解析時に、一度は発生するか確かめる年数（曜日と日付の組み合わせは28年で一巡する）
+const rruleHorizonYears = 28
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeekdayNum BYDAYの要素（Nが0以外なら「第N○曜日」、負数は末尾から数える）
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// RRule RFC 5545の繰り返しルール
+// FREQ（DAILY/WEEKLY/MONTHLY/YEARLY）・INTERVAL・COUNT・UNTIL・BYDAY・BYMONTHDAY・BYMONTH・BYSETPOS・WKSTに対応します
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	// UNTILがタイムゾーンなし（フローティング）の場合はDTSTARTのタイムゾーンで解釈する
+	untilFloating bool
+}
+
+// ParseRRule RRULE文字列（"RRULE:"プレフィックスは省略可、大文字小文字は区別しない）を解析します
+// 一度も発生しないルール（2月30日など）はエラーにします
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	rule := &RRule{Interval: 1, WeekStart: time.Monday}
+	if s == "" {
+		return nil, fmt.Errorf("rrule is empty")
+	}
+
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid rrule part: %q", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate rrule part: %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch Frequency(value) {
+			case FreqDaily, FreqWeekly, FreqMonthly, FreqYearly:
+				rule.Freq = Frequency(value)
+			default:
+				err = fmt.Errorf("unsupported FREQ: %s", value)
+			}
+		case "INTERVAL":
+			rule.Interval, err = parseRRuleInt(value, 1, 1000)
+		case "COUNT":
+			rule.Count, err = parseRRuleInt(value, 1, 10000)
+		case "UNTIL":
+			err = rule.parseUntil(value)
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wn, perr := parseWeekdayNum(v)
+				if perr != nil {
+					err = perr
+					break
+				}
+				rule.ByDay = append(rule.ByDay, wn)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, perr := parseRRuleInt(v, -31, 31)
+				if perr != nil || day == 0 {
+					err = fmt.Errorf("invalid BYMONTHDAY: %s", v)
+					break
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, day)
+			}
+		case "BYMONTH":
+			for _, v := range strings.Split(value, ",") {
+				month, perr := parseRRuleInt(v, 1, 12)
+				if perr != nil {
+					err = fmt.Errorf("invalid BYMONTH: %s", v)
+					break
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(month))
+			}
+		case "BYSETPOS":
+			for _, v := range strings.Split(value, ",") {
+				pos, perr := parseRRuleInt(v, -366, 366)
+				if perr != nil || pos == 0 {
+					err = fmt.Errorf("invalid BYSETPOS: %s", v)
+					break
+				}
+				rule.BySetPos = append(rule.BySetPos, pos)
+			}
+		case "WKST":
+			wd, ok := weekdayCodes[value]
+			if !ok {
+				err = fmt.Errorf("invalid WKST: %s", value)
+			}
+			rule.WeekStart = wd
+		default:
+			err = fmt.Errorf("unsupported rrule part: %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL must not be used together")
+	}
+	if rule.Freq == FreqWeekly && len(rule.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY is not allowed with FREQ=WEEKLY")
+	}
+	for _, wn := range rule.ByDay {
+		if wn.N != 0 && rule.Freq != FreqMonthly && rule.Freq != FreqYearly {
+			return nil, fmt.Errorf("BYDAY with a number is only allowed with FREQ=MONTHLY or YEARLY")
+		}
+	}
+	if len(rule.BySetPos) > 0 && len(rule.ByDay) == 0 && len(rule.ByMonthDay) == 0 && len(rule.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYSETPOS requires BYDAY, BYMONTHDAY or BYMONTH")
+	}
+	if !rule.occursWithin(rruleHorizonYears) {
+		return nil, fmt.Errorf("rrule never occurs")
+	}
+
+	return rule, nil
+}
+
+// occursWithin BY〜の組み合わせが、years年の間に一度でも一致する日があるか確かめます
+// DTSTARTに関係なく判定できるよう、INTERVAL・COUNT・UNTILは無視し、うるう年の1月1日から数えます
+func (r *RRule) occursWithin(years int) bool {
+	if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && len(r.BySetPos) == 0 {
+		// 月の指定だけなら、DTSTARTと同じ日か毎日のどちらかで必ず一致する
+		return true
+	}
+	probe := *r
+	probe.Interval = 1
+	start := time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(years, 0, 0)
+	for period := 0; ; period++ {
+		days := probe.expand(start, period)
+		if len(days) > 0 {
+			return true
+		}
+		if !probe.periodStart(start, period).Before(end) {
+			return false
+		}
+	}
+}
+
+// periodStart period番目の期間（INTERVAL考慮済み）の始まりの日を返します
+func (r *RRule) periodStart(startDate time.Time, period int) time.Time {
+	step := period * r.Interval
+	switch r.Freq {
+	case FreqDaily:
+		return startDate.AddDate(0, 0, step)
+	case FreqWeekly:
+		return startDate.AddDate(0, 0, step*7)
+	case FreqMonthly:
+		return startDate.AddDate(0, step, 0)
+	default:
+		return startDate.AddDate(step, 0, 0)
+	}
+}
+
+func parseRRuleInt(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("value out of range: %s", s)
+	}
+	return n, nil
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY: %s", s)
+	}
+	wd, ok := weekdayCodes[s[len(s)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY: %s", s)
+	}
+	wn := WeekdayNum{Weekday: wd}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY: %s", s)
+		}
+		wn.N = n
+	}
+	return wn, nil
+}
+
+// parseUntil UNTILを解析します（UTC・フローティングの日時、または日付のみ）
+func (r *RRule) parseUntil(value string) error {
+	layouts := []struct {
+		layout   string
+		floating bool
+	}{
+		{"20060102T150405Z", false},
+		{"20060102T150405", true},
+		{"20060102", true},
+	}
+	for _, l := range layouts {
+		t, err := time.Parse(l.layout, value)
+		if err != nil {
+			continue
+		}
+		if l.layout == "20060102" {
+			// 日付のみの場合はその日の終わりまでを含む
+			t = t.Add(24*time.Hour - time.Second)
+		}
+		r.Until = &t
+		r.untilFloating = l.floating
+		return nil
+	}
+	return fmt.Errorf("invalid UNTIL: %s", value)
+}
+
+// String ルールを正規化したRRULE文字列に変換します（"RRULE:"プレフィックスなし）
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		if r.untilFloating {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, wn := range r.ByDay {
+			days[i] = weekdayNames[wn.Weekday]
+			if wn.N != 0 {
+				days[i] = strconv.Itoa(wn.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		positions := make([]string, len(r.BySetPos))
+		for i, pos := range r.BySetPos {
+			positions[i] = strconv.Itoa(pos)
+		}
+		parts = append(parts, "BYSETPOS="+strings.Join(positions, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterate DTSTARTから順に発生日時を列挙します（yieldがfalseを返すと終了）
+// 発生日時はDTSTARTのタイムゾーンでの壁時計時刻を保つため、夏時間の切り替えをまたいでも同じ時刻になります。
+// 存在しない日付（2月30日や31日のない月の31日など）はRFC 5545に従いスキップします。
+func (r *RRule) Iterate(dtstart time.Time, yield func(time.Time) bool) {
+	loc := dtstart.Location()
+	var until *time.Time
+	if r.Until != nil {
+		u := *r.Until
+		if r.untilFloating {
+			u = time.Date(u.Year(), u.Month(), u.Day(), u.Hour(), u.Minute(), u.Second(), 0, loc)
+		}
+		until = &u
+	}
+
+	// DTSTART自体が最初の発生日時
+	if until != nil && dtstart.After(*until) {
+		return
+	}
+	count := 1
+	if !yield(dtstart) || (r.Count > 0 && count >= r.Count) {
+		return
+	}
+
+	h, mi, sec := dtstart.Clock()
+	for period := 0; period < maxRRulePeriods; period++ {
+		for _, d := range r.expand(dtstart, period) {
+			t := time.Date(d.Year(), d.Month(), d.Day(), h, mi, sec, dtstart.Nanosecond(), loc)
+			if !t.After(dtstart) {
+				continue
+			}
+			if until != nil && t.After(*until) {
+				return
+			}
+			count++
+			if !yield(t) || (r.Count > 0 && count >= r.Count) {
+				return
+			}
+		}
+	}
+}
+
+// Next afterより後の最初の発生日時を返します
+func (r *RRule) Next(dtstart, after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.Iterate(dtstart, func(t time.Time) bool {
+		if t.After(after) {
+			next, found = t, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Upcoming from以降の発生日時を最大n件返します
+func (r *RRule) Upcoming(dtstart, from time.Time, n int) []time.Time {
+	occurrences := make([]time.Time, 0, n)
+	if n <= 0 {
+		return occurrences
+	}
+	r.Iterate(dtstart, func(t time.Time) bool {
+		if !t.Before(from) {
+			occurrences = append(occurrences, t)
+		}
+		return len(occurrences) < n
+	})
+	return occurrences
+}
+
+// expand period番目の期間（INTERVAL考慮済み）に含まれる候補日を昇順で返します（BYSETPOSがあれば、その位置の日だけ）
+// 日付計算は夏時間の影響を受けないようUTCの0時で行います
+func (r *RRule) expand(dtstart time.Time, period int) []time.Time {
+	days := r.expandPeriod(dtstart, period)
+	if len(r.BySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+	selected := make(map[time.Time]bool)
+	for _, pos := range r.BySetPos {
+		switch {
+		case pos > 0 && pos <= len(days):
+			selected[days[pos-1]] = true
+		case pos < 0 && -pos <= len(days):
+			selected[days[len(days)+pos]] = true
+		}
+	}
+	filtered := days[:0]
+	for _, d := range days {
+		if selected[d] {
+			filtered = append(filtered, d)
+		}
+	}
+	return filtered
+}
+
+// expandPeriod period番目の期間に含まれる、BY〜に一致する候補日を昇順で返します
+func (r *RRule) expandPeriod(dtstart time.Time, period int) []time.Time {
+	startDate := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 0, 0, 0, 0, time.UTC)
+	step := period * r.Interval
+
+	switch r.Freq {
+	case FreqDaily:
+		day := startDate.AddDate(0, 0, step)
+		if r.matchesMonth(day.Month()) && r.matchesMonthDay(day) && r.matchesWeekday(day.Weekday()) {
+			return []time.Time{day}
+		}
+		return nil
+
+	case FreqWeekly:
+		offset := (int(startDate.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := startDate.AddDate(0, 0, step*7-offset)
+		var days []time.Time
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if !r.matchesMonth(day.Month()) {
+				continue
+			}
+			if len(r.ByDay) == 0 && day.Weekday() != startDate.Weekday() {
+				continue
+			}
+			if len(r.ByDay) > 0 && !r.matchesWeekday(day.Weekday()) {
+				continue
+			}
+			days = append(days, day)
+		}
+		return days
+
+	case FreqMonthly:
+		month := time.Date(startDate.Year(), startDate.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+		if !r.matchesMonth(month.Month()) {
+			return nil
+		}
+		return r.monthDays(month.Year(), month.Month(), startDate.Day())
+
+	case FreqYearly:
+		year := startDate.Year() + step
+		switch {
+		case len(r.ByMonth) > 0:
+			months := append([]time.Month(nil), r.ByMonth...)
+			sort.Slice(months, func(i, j int) bool { return months[i] < months[j] })
+			var days []time.Time
+			for _, m := range months {
+				days = append(days, r.monthDays(year, m, startDate.Day())...)
+			}
+			return days
+		case len(r.ByMonthDay) > 0:
+			var days []time.Time
+			for m := time.January; m <= time.December; m++ {
+				days = append(days, r.monthDays(year, m, startDate.Day())...)
+			}
+			return days
+		case len(r.ByDay) > 0:
+			first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+			return r.weekdaysInRange(first, first.AddDate(1, 0, 0))
+		default:
+			// 2月29日開始の場合、うるう年以外はスキップ
+			return r.monthDays(year, startDate.Month(), startDate.Day())
+		}
+	}
+	return nil
+}
+
+// monthDays 指定した月の候補日を返します（BYMONTHDAY/BYDAYがなければdefaultDay）
+func (r *RRule) monthDays(year int, month time.Month, defaultDay int) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	next := first.AddDate(0, 1, 0)
+	daysIn := next.AddDate(0, 0, -1).Day()
+
+	if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+		if defaultDay > daysIn {
+			return nil
+		}
+		return []time.Time{time.Date(year, month, defaultDay, 0, 0, 0, 0, time.UTC)}
+	}
+
+	var days []time.Time
+	if len(r.ByDay) > 0 {
+		days = r.weekdaysInRange(first, next)
+		if len(r.ByMonthDay) > 0 {
+			filtered := days[:0]
+			for _, d := range days {
+				if r.matchesMonthDay(d) {
+					filtered = append(filtered, d)
+				}
+			}
+			days = filtered
+		}
+		return days
+	}
+
+	seen := make(map[int]bool)
+	for _, md := range r.ByMonthDay {
+		day := md
+		if md < 0 {
+			day = daysIn + md + 1
+		}
+		if day >= 1 && day <= daysIn && !seen[day] {
+			seen[day] = true
+			days = append(days, time.Date(year, month, day, 0, 0, 0, 0, time.UTC))
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+// weekdaysInRange [start, end)のうちBYDAYに一致する日を返します（第N指定は範囲内で数える）
+func (r *RRule) weekdaysInRange(start, end time.Time) []time.Time {
+	byWeekday := make(map[time.Weekday][]time.Time)
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		byWeekday[d.Weekday()] = append(byWeekday[d.Weekday()], d)
+	}
+
+	selected := make(map[time.Time]bool)
+	for _, wn := range r.ByDay {
+		candidates := byWeekday[wn.Weekday]
+		switch {
+		case wn.N == 0:
+			for _, d := range candidates {
+				selected[d] = true
+			}
+		case wn.N > 0 && wn.N <= len(candidates):
+			selected[candidates[wn.N-1]] = true
+		case wn.N < 0 && -wn.N <= len(candidates):
+			selected[candidates[len(candidates)+wn.N]] = true
+		}
+	}
+
+	days := make([]time.Time, 0, len(selected))
+	for d := range selected {
+		days = append(days, d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days
+}
+
+func (r *RRule) matchesMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, bm := range r.ByMonth {
+		if bm == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysIn := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, md := range r.ByMonthDay {
+		if md == day.Day() || (md < 0 && daysIn+md+1 == day.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchesWeekday(wd time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wn := range r.ByDay {
+		if wn.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
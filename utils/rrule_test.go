@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{in: "RRULE:FREQ=WEEKLY;BYDAY=MO,TH", want: "FREQ=WEEKLY;BYDAY=MO,TH"},
+		{in: "rrule:freq=weekly;byday=mo", want: "FREQ=WEEKLY;BYDAY=MO"},
+		{in: "Rrule:FREQ=MONTHLY;BYMONTHDAY=-1", want: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{in: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", want: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1"},
+		{in: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29", want: "FREQ=YEARLY;BYMONTHDAY=29;BYMONTH=2"},
+		{in: "FREQ=DAILY;COUNT=3", want: "FREQ=DAILY;COUNT=3"},
+		{in: "FREQ=DAILY;UNTIL=20250105", want: "FREQ=DAILY;UNTIL=20250105T235959"},
+		{in: "FREQ=WEEKLY;INTERVAL=2;WKST=SU", want: "FREQ=WEEKLY;INTERVAL=2;WKST=SU"},
+
+		{in: "", wantErr: true},
+		{in: "RRULE:", wantErr: true},
+		{in: "FREQ=HOURLY", wantErr: true},
+		{in: "FREQ=DAILY;COUNT=3;UNTIL=20250105", wantErr: true},
+		{in: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{in: "FREQ=WEEKLY;BYDAY=2MO", wantErr: true},
+		{in: "FREQ=MONTHLY;BYSETPOS=1", wantErr: true},
+		{in: "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=0", wantErr: true},
+		// 一度も発生しないルール
+		{in: "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", wantErr: true},
+		{in: "FREQ=MONTHLY;BYDAY=5MO;BYMONTHDAY=1", wantErr: true},
+		{in: "FREQ=MONTHLY;BYMONTH=4,6,9,11;BYMONTHDAY=31", wantErr: true},
+		{in: "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=-30", wantErr: true},
+		{in: "FREQ=MONTHLY;BYDAY=MO;BYSETPOS=6", wantErr: true},
+	}
+	for _, tt := range tests {
+		rule, err := ParseRRule(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseRRule(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && rule.String() != tt.want {
+			t.Errorf("ParseRRule(%q).String() = %q, want %q", tt.in, rule.String(), tt.want)
+		}
+	}
+}
+
+func TestRRuleUpcoming(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(loc *time.Location, year int, month time.Month, day, hour int) time.Time {
+		return time.Date(year, month, day, hour, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name    string
+		rrule   string
+		dtstart time.Time
+		n       int
+		want    []time.Time
+	}{
+		{
+			// 夏時間の開始（3/9）をまたいでも9時のまま
+			name: "DST start", rrule: "FREQ=DAILY", dtstart: date(newYork, 2025, 3, 8, 9), n: 3,
+			want: []time.Time{date(newYork, 2025, 3, 8, 9), date(newYork, 2025, 3, 9, 9), date(newYork, 2025, 3, 10, 9)},
+		},
+		{
+			// 夏時間の終了（11/2）をまたいでも9時のまま
+			name: "DST end", rrule: "FREQ=WEEKLY", dtstart: date(newYork, 2025, 10, 26, 9), n: 3,
+			want: []time.Time{date(newYork, 2025, 10, 26, 9), date(newYork, 2025, 11, 2, 9), date(newYork, 2025, 11, 9, 9)},
+		},
+		{
+			name: "BYMONTHDAY=31 skips short months", rrule: "FREQ=MONTHLY;BYMONTHDAY=31", dtstart: date(tokyo, 2025, 1, 31, 9), n: 4,
+			want: []time.Time{date(tokyo, 2025, 1, 31, 9), date(tokyo, 2025, 3, 31, 9), date(tokyo, 2025, 5, 31, 9), date(tokyo, 2025, 7, 31, 9)},
+		},
+		{
+			name: "monthly on the 31st without BYMONTHDAY", rrule: "FREQ=MONTHLY", dtstart: date(tokyo, 2025, 1, 31, 9), n: 3,
+			want: []time.Time{date(tokyo, 2025, 1, 31, 9), date(tokyo, 2025, 3, 31, 9), date(tokyo, 2025, 5, 31, 9)},
+		},
+		{
+			name: "BYMONTHDAY=-1", rrule: "FREQ=MONTHLY;BYMONTHDAY=-1", dtstart: date(tokyo, 2024, 1, 31, 9), n: 4,
+			want: []time.Time{date(tokyo, 2024, 1, 31, 9), date(tokyo, 2024, 2, 29, 9), date(tokyo, 2024, 3, 31, 9), date(tokyo, 2024, 4, 30, 9)},
+		},
+		{
+			name: "BYSETPOS=-1 last weekday of the month", rrule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", dtstart: date(tokyo, 2025, 1, 31, 9), n: 4,
+			want: []time.Time{date(tokyo, 2025, 1, 31, 9), date(tokyo, 2025, 2, 28, 9), date(tokyo, 2025, 3, 31, 9), date(tokyo, 2025, 4, 30, 9)},
+		},
+		{
+			name: "BYSETPOS=-1 falls back from a weekend", rrule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", dtstart: date(tokyo, 2025, 4, 30, 9), n: 3,
+			want: []time.Time{date(tokyo, 2025, 4, 30, 9), date(tokyo, 2025, 5, 30, 9), date(tokyo, 2025, 6, 30, 9)},
+		},
+		{
+			name: "BYSETPOS=2 second Tuesday or Thursday", rrule: "FREQ=MONTHLY;BYDAY=TU,TH;BYSETPOS=2", dtstart: date(tokyo, 2025, 1, 7, 9), n: 3,
+			want: []time.Time{date(tokyo, 2025, 1, 7, 9), date(tokyo, 2025, 2, 6, 9), date(tokyo, 2025, 3, 6, 9)},
+		},
+		{
+			name: "COUNT", rrule: "FREQ=DAILY;COUNT=3", dtstart: date(tokyo, 2025, 1, 1, 9), n: 10,
+			want: []time.Time{date(tokyo, 2025, 1, 1, 9), date(tokyo, 2025, 1, 2, 9), date(tokyo, 2025, 1, 3, 9)},
+		},
+		{
+			name: "UNTIL date includes the whole day", rrule: "FREQ=DAILY;UNTIL=20250103", dtstart: date(tokyo, 2025, 1, 1, 9), n: 10,
+			want: []time.Time{date(tokyo, 2025, 1, 1, 9), date(tokyo, 2025, 1, 2, 9), date(tokyo, 2025, 1, 3, 9)},
+		},
+		{
+			// 2025-01-03 00:00 UTCは東京の9時なので、その回まで含む
+			name: "UNTIL in UTC", rrule: "FREQ=DAILY;UNTIL=20250103T000000Z", dtstart: date(tokyo, 2025, 1, 1, 9), n: 10,
+			want: []time.Time{date(tokyo, 2025, 1, 1, 9), date(tokyo, 2025, 1, 2, 9), date(tokyo, 2025, 1, 3, 9)},
+		},
+		{
+			name: "leap day yearly", rrule: "FREQ=YEARLY", dtstart: date(tokyo, 2024, 2, 29, 9), n: 2,
+			want: []time.Time{date(tokyo, 2024, 2, 29, 9), date(tokyo, 2028, 2, 29, 9)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRRule(tt.rrule)
+			if err != nil {
+				t.Fatalf("ParseRRule(%q) error = %v", tt.rrule, err)
+			}
+			got := rule.Upcoming(tt.dtstart, tt.dtstart, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Upcoming() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("Upcoming()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
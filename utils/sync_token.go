@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// SyncToken 差分同期の変更トークン
+// Seqには返した変更の最大の変更番号、XMinには発行時に実行中だった最古のトランザクションのID、
+// ResetAtには発行時点のユーザーの同期リセット日時（UnixMicro、なければ0）、IssuedAtには発行日時（Unix秒）を保持します
+type SyncToken struct {
+	Seq      int64  `json:"s"`
+	XMin     uint64 `json:"x"`
+	ResetAt  int64  `json:"r,omitempty"`
+	IssuedAt int64  `json:"t"`
+}
+
+// EncodeSyncToken 変更トークンを不透明な文字列にエンコードします
+func EncodeSyncToken(token SyncToken) string {
+	bytes, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+// DecodeSyncToken 文字列から変更トークンをデコードします
+func DecodeSyncToken(s string) (SyncToken, error) {
+	var token SyncToken
+	bytes, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return token, fmt.Errorf("invalid sync token")
+	}
+	if err := json.Unmarshal(bytes, &token); err != nil || token.IssuedAt == 0 {
+		return token, fmt.Errorf("invalid sync token")
+	}
+	return token, nil
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultTimezone ユーザーのタイムゾーンが未設定の場合に使用するタイムゾーン
+const DefaultTimezone = "Asia/Tokyo"
+
+// LoadLocation IANAタイムゾーン名からLocationを取得します（空文字はデフォルト）
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		name = DefaultTimezone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid timezone: %s", name)
+	}
+	return loc, nil
+}
+
+// StartOfDay 指定したタイムゾーンでのtの日付の0時を返します
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.In(loc).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// AddDays 指定したタイムゾーンの暦日でn日後の0時を返します（夏時間の切り替えでも24時間とは限らない）
+func AddDays(day time.Time, n int, loc *time.Location) time.Time {
+	y, m, d := day.In(loc).Date()
+	return time.Date(y, m, d+n, 0, 0, 0, 0, loc)
+}
@@ -0,0 +1,255 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// TodoTxtDateLayout todo.txtの日付の形式
+const TodoTxtDateLayout = "2006-01-02"
+
+// maxTodoTxtLineLength 1行の最大長
+const maxTodoTxtLineLength = 64 * 1024
+
+var (
+	todoTxtDatePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	todoTxtPriorityPattern = regexp.MustCompile(`^\(([A-Z])\)$`)
+	// key:value（キーと値にはコロンと空白を含まない。キーは英字で始まるものだけを扱い、「10:30」のような時刻は本文とする）
+	todoTxtExtraPattern = regexp.MustCompile(`^([A-Za-z][A-Za-z0-9_-]*):([^\s:]+)$`)
+)
+
+// TodoTxtExtra key:value形式の追加情報
+type TodoTxtExtra struct {
+	Key   string
+	Value string
+}
+
+// TodoTxtTask todo.txtの1行（1タスク）
+// Descriptionは+project・@context・key:valueを取り除いた本文です
+// 本文のうちタグや日付として読まれてしまう単語は、書き出し時に先頭へ「\」を付けてエスケープし、解析時に外します
+type TodoTxtTask struct {
+	Line           int
+	Completed      bool
+	Priority       string // A〜Z（なければ空）
+	CompletionDate *time.Time
+	CreationDate   *time.Time
+	Description    string
+	Projects       []string
+	Contexts       []string
+	Extras         []TodoTxtExtra
+}
+
+// Extra 指定したキーの最初の値を返します
+func (t *TodoTxtTask) Extra(key string) (string, bool) {
+	for _, extra := range t.Extras {
+		if extra.Key == key {
+			return extra.Value, true
+		}
+	}
+	return "", false
+}
+
+// ParseTodoTxt todo.txtのテキストを解析します（空行は読み飛ばす）
+// 解析できない行があれば、行番号付きのエラーをすべて返します
+func ParseTodoTxt(r io.Reader, loc *time.Location) ([]TodoTxtTask, []ErrorItem) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxTodoTxtLineLength)
+
+	tasks := []TodoTxtTask{}
+	var errs []ErrorItem
+	number := 0
+	for scanner.Scan() {
+		number++
+		text := strings.TrimSuffix(scanner.Text(), "\r")
+		if number == 1 {
+			text = strings.TrimPrefix(text, "\ufeff") // BOM
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		task, err := parseTodoTxtLine(text, loc)
+		if err != nil {
+			errs = append(errs, ErrorItem{Line: number, Message: err.Error()})
+			continue
+		}
+		task.Line = number
+		tasks = append(tasks, task)
+	}
+	if err := scanner.Err(); err != nil {
+		errs = append(errs, ErrorItem{Line: number + 1, Message: err.Error()})
+	}
+	return tasks, errs
+}
+
+// ParseTodoTxtDate YYYY-MM-DD形式の日付をlocの0時として解釈します
+func ParseTodoTxtDate(value string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation(TodoTxtDateLayout, value, loc)
+	if err != nil || !todoTxtDatePattern.MatchString(value) {
+		return time.Time{}, fmt.Errorf("invalid date: %s", value)
+	}
+	return t, nil
+}
+
+// parseTodoTxtLine 「x 完了日 作成日」または「(A) 作成日」で始まる1行を解析します
+func parseTodoTxtLine(line string, loc *time.Location) (TodoTxtTask, error) {
+	var task TodoTxtTask
+	fields := strings.Fields(line)
+
+	i := 0
+	if fields[0] == "x" {
+		task.Completed = true
+		i++
+	}
+	if i < len(fields) {
+		if m := todoTxtPriorityPattern.FindStringSubmatch(fields[i]); m != nil {
+			task.Priority = m[1]
+			i++
+		}
+	}
+	// 完了したタスクは完了日・作成日の順、未完了のタスクは作成日だけを持つ
+	var dates []*time.Time
+	for i < len(fields) && len(dates) < 2 && todoTxtDatePattern.MatchString(fields[i]) {
+		t, err := ParseTodoTxtDate(fields[i], loc)
+		if err != nil {
+			return task, err
+		}
+		dates = append(dates, &t)
+		i++
+		if !task.Completed {
+			break
+		}
+	}
+	switch {
+	case !task.Completed && len(dates) == 1:
+		task.CreationDate = dates[0]
+	case task.Completed && len(dates) >= 1:
+		task.CompletionDate = dates[0]
+		if len(dates) == 2 {
+			task.CreationDate = dates[1]
+		}
+	}
+
+	var words []string
+	for j, field := range fields[i:] {
+		switch {
+		case len(field) > 1 && field[0] == '+':
+			task.Projects = append(task.Projects, field[1:])
+		case len(field) > 1 && field[0] == '@':
+			task.Contexts = append(task.Contexts, field[1:])
+		default:
+			if m := todoTxtExtra(field); m != nil {
+				task.Extras = append(task.Extras, TodoTxtExtra{Key: m[1], Value: m[2]})
+			} else if isEscapedTodoTxtWord(field, j == 0) {
+				words = append(words, field[1:])
+			} else {
+				words = append(words, field)
+			}
+		}
+	}
+	task.Description = strings.Join(words, " ")
+	if task.Description == "" {
+		return task, fmt.Errorf("description is required")
+	}
+
+	// 完了したタスクの優先度はpri:Aとして残す慣習に従う
+	if pri, ok := task.Extra("pri"); ok && task.Priority == "" {
+		if m := todoTxtPriorityPattern.FindStringSubmatch("(" + pri + ")"); m != nil {
+			task.Priority = m[1]
+			task.removeExtra("pri")
+		}
+	}
+	return task, nil
+}
+
+// todoTxtExtra key:valueならキーと値を返します（「http://...」のようなURLは本文として扱う）
+func todoTxtExtra(field string) []string {
+	if m := todoTxtExtraPattern.FindStringSubmatch(field); m != nil && !strings.HasPrefix(m[2], "//") {
+		return m
+	}
+	return nil
+}
+
+// isTodoTxtToken 本文の単語が+project・@context・key:valueとして読まれるかどうか
+// 本文の先頭の単語は、完了の「x」・優先度・日付としても読まれます
+func isTodoTxtToken(word string, first bool) bool {
+	if len(word) > 1 && (word[0] == '+' || word[0] == '@') {
+		return true
+	}
+	if todoTxtExtra(word) != nil {
+		return true
+	}
+	return first && (word == "x" || todoTxtPriorityPattern.MatchString(word) || todoTxtDatePattern.MatchString(word))
+}
+
+// isEscapedTodoTxtWord エスケープした単語（「\+1」「\\+1」など）かどうか
+func isEscapedTodoTxtWord(word string, first bool) bool {
+	if !strings.HasPrefix(word, `\`) {
+		return false
+	}
+	rest := word[1:]
+	return isTodoTxtToken(rest, first) || isEscapedTodoTxtWord(rest, first)
+}
+
+// escapeTodoTxtDescription 本文の単語のうちタグや日付として読まれるものを「\」でエスケープします
+func escapeTodoTxtDescription(description string) string {
+	words := strings.Fields(description)
+	for i, word := range words {
+		if isTodoTxtToken(word, i == 0) || isEscapedTodoTxtWord(word, i == 0) {
+			words[i] = `\` + word
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// removeExtra 指定したキーの追加情報を取り除きます
+func (t *TodoTxtTask) removeExtra(key string) {
+	extras := t.Extras[:0]
+	for _, extra := range t.Extras {
+		if extra.Key != key {
+			extras = append(extras, extra)
+		}
+	}
+	t.Extras = extras
+}
+
+// FormatTodoTxt タスクをtodo.txtの1行にします（日付はlocで表示）
+func FormatTodoTxt(task TodoTxtTask, loc *time.Location) string {
+	var parts []string
+	if task.Completed {
+		parts = append(parts, "x")
+		// 作成日は完了日がある場合にしか書けない
+		if task.CompletionDate != nil {
+			parts = append(parts, task.CompletionDate.In(loc).Format(TodoTxtDateLayout))
+			if task.CreationDate != nil {
+				parts = append(parts, task.CreationDate.In(loc).Format(TodoTxtDateLayout))
+			}
+		}
+	} else {
+		if task.Priority != "" {
+			parts = append(parts, "("+task.Priority+")")
+		}
+		if task.CreationDate != nil {
+			parts = append(parts, task.CreationDate.In(loc).Format(TodoTxtDateLayout))
+		}
+	}
+
+	// 改行を含む本文は1行にまとめる
+	parts = append(parts, escapeTodoTxtDescription(task.Description))
+	for _, project := range task.Projects {
+		parts = append(parts, "+"+project)
+	}
+	for _, context := range task.Contexts {
+		parts = append(parts, "@"+context)
+	}
+	for _, extra := range task.Extras {
+		parts = append(parts, extra.Key+":"+extra.Value)
+	}
+	if task.Completed && task.Priority != "" {
+		parts = append(parts, "pri:"+task.Priority)
+	}
+	return strings.Join(parts, " ")
+}
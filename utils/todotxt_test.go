@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTodoTxt(t *testing.T) {
+	loc := time.UTC
+	date := func(year int, month time.Month, day int) *time.Time {
+		d := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		return &d
+	}
+
+	tests := []struct {
+		line string
+		want TodoTxtTask
+	}{
+		{
+			line: "(A) 2026-10-01 企画書を書く +仕事 @PC due:2026-10-20",
+			want: TodoTxtTask{
+				Priority:     "A",
+				CreationDate: date(2026, 10, 1),
+				Description:  "企画書を書く",
+				Projects:     []string{"仕事"},
+				Contexts:     []string{"PC"},
+				Extras:       []TodoTxtExtra{{Key: "due", Value: "2026-10-20"}},
+			},
+		},
+		{
+			line: "x 2026-10-05 2026-10-02 牛乳を買う pri:B",
+			want: TodoTxtTask{
+				Completed:      true,
+				Priority:       "B",
+				CompletionDate: date(2026, 10, 5),
+				CreationDate:   date(2026, 10, 2),
+				Description:    "牛乳を買う",
+				Extras:         []TodoTxtExtra{},
+			},
+		},
+		// URLと時刻は本文として扱う
+		{
+			line: "read http://example.com at 10:30",
+			want: TodoTxtTask{Description: "read http://example.com at 10:30"},
+		},
+		// エスケープした単語
+		{
+			line: `\x marks the spot`,
+			want: TodoTxtTask{Description: "x marks the spot"},
+		},
+		{
+			line: `\+1 the PR and email \@bob about \re:contract`,
+			want: TodoTxtTask{Description: "+1 the PR and email @bob about re:contract"},
+		},
+		// 先頭以外の「\x」はエスケープではない
+		{
+			line: `press \x to exit`,
+			want: TodoTxtTask{Description: `press \x to exit`},
+		},
+	}
+
+	for _, tt := range tests {
+		tasks, errs := ParseTodoTxt(strings.NewReader(tt.line), loc)
+		if len(errs) > 0 {
+			t.Errorf("ParseTodoTxt(%q) errors = %v", tt.line, errs)
+			continue
+		}
+		if len(tasks) != 1 {
+			t.Errorf("ParseTodoTxt(%q) = %d tasks, want 1", tt.line, len(tasks))
+			continue
+		}
+		got := tasks[0]
+		tt.want.Line = 1
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseTodoTxt(%q) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseTodoTxtErrors(t *testing.T) {
+	text := "ok\n(A) +project @context\n\nx 2026-13-01 bad date\n"
+	tasks, errs := ParseTodoTxt(strings.NewReader(text), time.UTC)
+	if len(tasks) != 1 {
+		t.Errorf("tasks = %d, want 1", len(tasks))
+	}
+	lines := make([]int, len(errs))
+	for i, err := range errs {
+		lines[i] = err.Line
+	}
+	if !reflect.DeepEqual(lines, []int{2, 4}) {
+		t.Errorf("error lines = %v, want [2 4]", lines)
+	}
+}
+
+// 書き出した行を読み直すと、本文・タグ・日付が元に戻ること
+func TestTodoTxtRoundTrip(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := time.Date(2026, 10, 2, 0, 0, 0, 0, loc)
+	completed := time.Date(2026, 10, 5, 0, 0, 0, 0, loc)
+
+	descriptions := []string{
+		"re:contract",
+		"+1 the PR",
+		"email @bob",
+		"x marks the spot",
+		"(B) is not a priority",
+		"2026-01-01 retrospective",
+		`\+1 literal backslash`,
+		`\\@double escaped`,
+		`C:\path and \x`,
+		"+ and @ alone",
+		"read http://example.com at 10:30",
+		"会議の資料 re:見積",
+	}
+
+	for _, description := range descriptions {
+		tasks := []TodoTxtTask{
+			{Description: description},
+			{Description: description, Priority: "A", CreationDate: &created, Projects: []string{"work"}, Contexts: []string{"pc"}},
+			{Description: description, Completed: true},
+			{Description: description, Completed: true, CompletionDate: &completed, CreationDate: &created, Priority: "C",
+				Extras: []TodoTxtExtra{{Key: "due", Value: "2026-10-20"}}},
+		}
+		for _, task := range tasks {
+			line := FormatTodoTxt(task, loc)
+			parsed, errs := ParseTodoTxt(strings.NewReader(line), loc)
+			if len(errs) > 0 || len(parsed) != 1 {
+				t.Errorf("ParseTodoTxt(%q) = %v, errors %v", line, parsed, errs)
+				continue
+			}
+			got := parsed[0]
+			if got.Description != description {
+				t.Errorf("round trip of %q via %q: description = %q", description, line, got.Description)
+			}
+			if got.Completed != task.Completed || got.Priority != task.Priority {
+				t.Errorf("round trip via %q: completed/priority = %v/%q, want %v/%q", line, got.Completed, got.Priority, task.Completed, task.Priority)
+			}
+			if !sameDate(got.CreationDate, task.CreationDate) || !sameDate(got.CompletionDate, task.CompletionDate) {
+				t.Errorf("round trip via %q: dates = %v/%v", line, got.CreationDate, got.CompletionDate)
+			}
+			if len(got.Projects) != len(task.Projects) || len(got.Contexts) != len(task.Contexts) || len(got.Extras) != len(task.Extras) {
+				t.Errorf("round trip via %q: projects %v, contexts %v, extras %v", line, got.Projects, got.Contexts, got.Extras)
+			}
+		}
+	}
+}
+
+func sameDate(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
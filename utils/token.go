@@ -54,9 +54,16 @@ func GenerateAccessToken(userID uint) (string, error) {
 
 // ValidateAccessToken アクセストークンを検証し、user_idを返します
 func ValidateAccessToken(tokenString string) (uint, error) {
+	userID, _, err := ParseAccessToken(tokenString)
+	return userID, err
+}
+
+// ParseAccessToken アクセストークンを検証し、user_idと有効期限を返します
+// WebSocketのように接続が続く場合は、有効期限までに新しいトークンで認証し直させるのに使います
+func ParseAccessToken(tokenString string) (uint, time.Time, error) {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		return 0, fmt.Errorf("JWT_SECRET is not set")
+		return 0, time.Time{}, fmt.Errorf("JWT_SECRET is not set")
 	}
 
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
@@ -67,14 +74,18 @@ func ValidateAccessToken(tokenString string) (uint, error) {
 	})
 
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, err
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		return claims.UserID, nil
+		var expiresAt time.Time
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+		return claims.UserID, expiresAt, nil
 	}
 
-	return 0, fmt.Errorf("invalid token")
+	return 0, time.Time{}, fmt.Errorf("invalid token")
 }
 
 // GenerateRefreshToken ランダムなリフレッシュトークンを生成します